@@ -0,0 +1,211 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/dokku/dokku-datastore/internal"
+	"github.com/dokku/dokku-datastore/internal/datastores"
+
+	"github.com/josegonzalez/cli-skeleton/command"
+	"github.com/posener/complete"
+	flag "github.com/spf13/pflag"
+)
+
+// ACLListCommand is the command for listing the users of the apps linked to a service
+type ACLListCommand struct {
+	// Meta is the command meta
+	command.Meta
+	// GlobalFlagCommand is the global flag command
+	GlobalFlagCommand
+}
+
+// Name returns the name of the command
+func (c *ACLListCommand) Name() string {
+	return "acl-list"
+}
+
+// Synopsis returns the synopsis of the command
+func (c *ACLListCommand) Synopsis() string {
+	return "Lists the users of the apps linked to a service"
+}
+
+// Help returns the help text for the command
+func (c *ACLListCommand) Help() string {
+	return command.CommandHelp(c)
+}
+
+// Examples returns the examples for the command
+func (c *ACLListCommand) Examples() map[string]string {
+	appName := os.Getenv("CLI_APP_NAME")
+	return map[string]string{
+		"Lists the app users of a redis service named test": fmt.Sprintf("%s %s redis test", appName, c.Name()),
+	}
+}
+
+// Arguments returns the arguments for the command
+func (c *ACLListCommand) Arguments() []command.Argument {
+	args := []command.Argument{}
+	args = append(args, command.Argument{
+		Name:        "datastore-type",
+		Description: "the type of datastore to list the app users of",
+		Optional:    false,
+		Type:        command.ArgumentString,
+	})
+	args = append(args, command.Argument{
+		Name:        "service-name",
+		Description: "the name of the service to list the app users of",
+		Optional:    false,
+		Type:        command.ArgumentString,
+	})
+	return args
+}
+
+// AutocompleteArgs returns the autocomplete arguments for the command
+func (c *ACLListCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictSet("redis")
+}
+
+// ParsedArguments parses the arguments for the command
+func (c *ACLListCommand) ParsedArguments(args []string) (map[string]command.Argument, error) {
+	return command.ParseArguments(args, c.Arguments())
+}
+
+// FlagSet returns the flag set for the command
+func (c *ACLListCommand) FlagSet() *flag.FlagSet {
+	f := c.Meta.FlagSet(c.Name(), command.FlagSetClient)
+	c.GlobalFlags(f)
+	return f
+}
+
+// AutocompleteFlags returns the autocomplete flags for the command
+func (c *ACLListCommand) AutocompleteFlags() complete.Flags {
+	return command.MergeAutocompleteFlags(
+		c.Meta.AutocompleteFlags(command.FlagSetClient),
+		c.AutocompleteGlobalFlags(),
+		complete.Flags{},
+	)
+}
+
+// Run runs the command
+func (c *ACLListCommand) Run(args []string) int {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGHUP,
+		syscall.SIGINT,
+		syscall.SIGQUIT,
+		syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	logger := internal.Ui{Ui: c.Ui, Command: c.Name(), Format: formatFromArgs(args)}
+	defer logger.Flush()
+	flags := c.FlagSet()
+	flags.Usage = func() {
+		logger.Help(c.Help()) //nolint:errcheck
+	}
+	if err := flags.Parse(args); err != nil {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   err,
+		})
+		return 1
+	}
+
+	logger = internal.Ui{
+		Ui:      c.Ui,
+		Command: c.Name(),
+		Format:  c.format,
+		Quiet:   c.quiet,
+		Trace:   c.trace,
+	}
+	ctx = c.outputContext(ctx)
+
+	arguments, err := c.ParsedArguments(flags.Args())
+	if err != nil {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   err,
+		})
+		return 1
+	}
+
+	datastoreType := arguments["datastore-type"].StringValue()
+	if datastoreType == "" {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   fmt.Errorf("datastore type is required"),
+		})
+		return 1
+	}
+
+	datastore, ok := datastores.Datastores[datastoreType]
+	if !ok {
+		logger.Error(internal.ErrorInput{
+			Error: fmt.Errorf("datastore type %s is not supported", datastoreType),
+		})
+		return 1
+	}
+
+	serviceName := arguments["service-name"].StringValue()
+	if serviceName == "" {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   fmt.Errorf("service name is required"),
+		})
+		return 1
+	}
+
+	if err := datastores.ValidateServiceName(serviceName); err != nil {
+		logger.Error(internal.ErrorInput{
+			Error: err,
+		})
+		return 1
+	}
+
+	if code := authorizeService(ctx, &logger, datastore, serviceName, c.trace); code != 0 {
+		return code
+	}
+
+	if !datastores.Exists(ctx, datastore, serviceName) {
+		logger.Error(internal.ErrorInput{
+			Error: fmt.Errorf("service %s does not exist", serviceName),
+		})
+		return 1
+	}
+
+	users, err := internal.ListAppUsers(datastore, serviceName)
+	if err != nil {
+		logger.Error(internal.ErrorInput{
+			Error: err,
+		})
+		return 1
+	}
+
+	if c.format == "json" {
+		logger.Result(users)
+		return 0
+	}
+
+	rows := []string{}
+	for _, user := range users {
+		state := "enabled"
+		if !user.Enabled {
+			state = "revoked"
+		}
+		rows = append(rows, fmt.Sprintf("%-30s  %-8s  %s", user.App, state, user.Rules))
+	}
+	if err := logger.Table(fmt.Sprintf("%s app users", serviceName), rows); err != nil {
+		logger.Error(internal.ErrorInput{
+			Error: err,
+		})
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,221 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/dokku/dokku-datastore/internal"
+	"github.com/dokku/dokku-datastore/internal/datastores"
+
+	"github.com/josegonzalez/cli-skeleton/command"
+	"github.com/posener/complete"
+	flag "github.com/spf13/pflag"
+)
+
+// ACLRevokeCommand is the command for revoking the user of a linked app
+type ACLRevokeCommand struct {
+	// Meta is the command meta
+	command.Meta
+	// GlobalFlagCommand is the global flag command
+	GlobalFlagCommand
+}
+
+// Name returns the name of the command
+func (c *ACLRevokeCommand) Name() string {
+	return "acl-revoke"
+}
+
+// Synopsis returns the synopsis of the command
+func (c *ACLRevokeCommand) Synopsis() string {
+	return "Revokes the user of an app linked to a service"
+}
+
+// Help returns the help text for the command
+func (c *ACLRevokeCommand) Help() string {
+	return command.CommandHelp(c)
+}
+
+// Examples returns the examples for the command
+func (c *ACLRevokeCommand) Examples() map[string]string {
+	appName := os.Getenv("CLI_APP_NAME")
+	return map[string]string{
+		"Stops accepting the credential of the app named my-app on a redis service named test": fmt.Sprintf("%s %s redis test my-app", appName, c.Name()),
+	}
+}
+
+// Arguments returns the arguments for the command
+func (c *ACLRevokeCommand) Arguments() []command.Argument {
+	args := []command.Argument{}
+	args = append(args, command.Argument{
+		Name:        "datastore-type",
+		Description: "the type of datastore the service belongs to",
+		Optional:    false,
+		Type:        command.ArgumentString,
+	})
+	args = append(args, command.Argument{
+		Name:        "service-name",
+		Description: "the name of the service the app is linked to",
+		Optional:    false,
+		Type:        command.ArgumentString,
+	})
+	args = append(args, command.Argument{
+		Name:        "app-name",
+		Description: "the name of the linked app to revoke the user of",
+		Optional:    false,
+		Type:        command.ArgumentString,
+	})
+	return args
+}
+
+// AutocompleteArgs returns the autocomplete arguments for the command
+func (c *ACLRevokeCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictSet("redis")
+}
+
+// ParsedArguments parses the arguments for the command
+func (c *ACLRevokeCommand) ParsedArguments(args []string) (map[string]command.Argument, error) {
+	return command.ParseArguments(args, c.Arguments())
+}
+
+// FlagSet returns the flag set for the command
+func (c *ACLRevokeCommand) FlagSet() *flag.FlagSet {
+	f := c.Meta.FlagSet(c.Name(), command.FlagSetClient)
+	c.GlobalFlags(f)
+	return f
+}
+
+// AutocompleteFlags returns the autocomplete flags for the command
+func (c *ACLRevokeCommand) AutocompleteFlags() complete.Flags {
+	return command.MergeAutocompleteFlags(
+		c.Meta.AutocompleteFlags(command.FlagSetClient),
+		c.AutocompleteGlobalFlags(),
+		complete.Flags{},
+	)
+}
+
+// Run runs the command
+func (c *ACLRevokeCommand) Run(args []string) (exitCode int) {
+	audit := newAuditRun(c)
+	defer audit.record(c.Ui, &exitCode)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGHUP,
+		syscall.SIGINT,
+		syscall.SIGQUIT,
+		syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	logger := internal.Ui{Ui: c.Ui, Command: c.Name(), Format: formatFromArgs(args)}
+	defer logger.Flush()
+	flags := c.FlagSet()
+	flags.Usage = func() {
+		logger.Help(c.Help()) //nolint:errcheck
+	}
+	if err := flags.Parse(args); err != nil {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   err,
+		})
+		return 1
+	}
+
+	logger = internal.Ui{
+		Ui:      c.Ui,
+		Command: c.Name(),
+		Format:  c.format,
+		Quiet:   c.quiet,
+		Trace:   c.trace,
+	}
+	ctx = c.outputContext(ctx)
+	audit.flags = flags
+
+	arguments, err := c.ParsedArguments(flags.Args())
+	if err != nil {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   err,
+		})
+		return 1
+	}
+
+	datastoreType := arguments["datastore-type"].StringValue()
+	if datastoreType == "" {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   fmt.Errorf("datastore type is required"),
+		})
+		return 1
+	}
+
+	datastore, ok := datastores.Datastores[datastoreType]
+	if !ok {
+		logger.Error(internal.ErrorInput{
+			Error: fmt.Errorf("datastore type %s is not supported", datastoreType),
+		})
+		return 1
+	}
+
+	serviceName := arguments["service-name"].StringValue()
+	if serviceName == "" {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   fmt.Errorf("service name is required"),
+		})
+		return 1
+	}
+
+	if err := datastores.ValidateServiceName(serviceName); err != nil {
+		logger.Error(internal.ErrorInput{
+			Error: err,
+		})
+		return 1
+	}
+
+	if code := authorizeService(ctx, &logger, datastore, serviceName, c.trace); code != 0 {
+		return code
+	}
+
+	if !datastores.Exists(ctx, datastore, serviceName) {
+		logger.Error(internal.ErrorInput{
+			Error: fmt.Errorf("service %s does not exist", serviceName),
+		})
+		return 1
+	}
+
+	appName := arguments["app-name"].StringValue()
+	if appName == "" {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   fmt.Errorf("app name is required"),
+		})
+		return 1
+	}
+
+	err = internal.RevokeAppUser(ctx, internal.RevokeAppUserInput{
+		AppName:     appName,
+		Datastore:   datastore,
+		ServiceName: serviceName,
+	})
+	if err != nil {
+		logger.Error(internal.ErrorInput{
+			Error: err,
+		})
+		return 1
+	}
+
+	logger.Header1(fmt.Sprintf("Service %s user for app %s revoked", serviceName, appName))
+	logger.Result(internal.ServiceResult{
+		Type:    datastoreType,
+		Service: serviceName,
+		App:     appName,
+	})
+
+	return 0
+}
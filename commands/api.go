@@ -0,0 +1,29 @@
+package commands
+
+import (
+	"os"
+
+	"github.com/josegonzalez/cli-skeleton/command"
+	"github.com/mitchellh/cli"
+)
+
+// ApiCommand is the parent command for the api subcommands
+type ApiCommand struct {
+	// Meta is the command meta
+	command.Meta
+}
+
+// Synopsis returns the synopsis of the command
+func (c *ApiCommand) Synopsis() string {
+	return "Manages the local json api"
+}
+
+// Help returns the help text for the command
+func (c *ApiCommand) Help() string {
+	return "Usage: " + os.Getenv("CLI_APP_NAME") + " api <subcommand> [options] [args]"
+}
+
+// Run runs the command
+func (c *ApiCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
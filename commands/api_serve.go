@@ -0,0 +1,164 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/dokku/dokku-datastore/internal"
+
+	"github.com/josegonzalez/cli-skeleton/command"
+	"github.com/posener/complete"
+	flag "github.com/spf13/pflag"
+)
+
+// ApiServeCommand is the command for serving the datastore json api
+type ApiServeCommand struct {
+	// Meta is the command meta
+	command.Meta
+	// GlobalFlagCommand is the global flag command
+	GlobalFlagCommand
+	// listen is the loopback address to listen on
+	listen string
+	// socket is the path of a unix socket to listen on
+	socket string
+	// token is the bearer token required on every request
+	token string
+}
+
+// Name returns the name of the command
+func (c *ApiServeCommand) Name() string {
+	return "api serve"
+}
+
+// Synopsis returns the synopsis of the command
+func (c *ApiServeCommand) Synopsis() string {
+	return "Serves a local json api for managing services"
+}
+
+// Help returns the help text for the command
+func (c *ApiServeCommand) Help() string {
+	return command.CommandHelp(c)
+}
+
+// Examples returns the examples for the command
+func (c *ApiServeCommand) Examples() map[string]string {
+	appName := os.Getenv("CLI_APP_NAME")
+	return map[string]string{
+		"Serves the api on a unix socket":  fmt.Sprintf("%s %s --socket /var/run/dokku-datastore.sock", appName, c.Name()),
+		"Serves the api on localhost:5050": fmt.Sprintf("%s %s --listen 127.0.0.1:5050", appName, c.Name()),
+	}
+}
+
+// Arguments returns the arguments for the command
+func (c *ApiServeCommand) Arguments() []command.Argument {
+	args := []command.Argument{}
+	return args
+}
+
+// AutocompleteArgs returns the autocomplete arguments for the command
+func (c *ApiServeCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+// ParsedArguments parses the arguments for the command
+func (c *ApiServeCommand) ParsedArguments(args []string) (map[string]command.Argument, error) {
+	return command.ParseArguments(args, c.Arguments())
+}
+
+// FlagSet returns the flag set for the command
+func (c *ApiServeCommand) FlagSet() *flag.FlagSet {
+	f := c.Meta.FlagSet(c.Name(), command.FlagSetClient)
+	c.GlobalFlags(f)
+	f.StringVar(&c.listen, "listen", "127.0.0.1:5050", "the loopback address to listen on")
+	f.StringVar(&c.socket, "socket", "", "the path of a unix socket to listen on instead of a tcp address")
+	f.StringVar(&c.token, "token", os.Getenv("DOKKU_DATASTORE_API_TOKEN"), "the bearer token required on every request (default: $DOKKU_DATASTORE_API_TOKEN)")
+	return f
+}
+
+// AutocompleteFlags returns the autocomplete flags for the command
+func (c *ApiServeCommand) AutocompleteFlags() complete.Flags {
+	return command.MergeAutocompleteFlags(
+		c.Meta.AutocompleteFlags(command.FlagSetClient),
+		c.AutocompleteGlobalFlags(),
+		complete.Flags{
+			"--listen": complete.PredictAnything,
+			"--socket": complete.PredictFiles("*"),
+			"--token":  complete.PredictAnything,
+		},
+	)
+}
+
+// Run runs the command
+func (c *ApiServeCommand) Run(args []string) int {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGHUP,
+		syscall.SIGINT,
+		syscall.SIGQUIT,
+		syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	logger := internal.Ui{Ui: c.Ui, Command: c.Name(), Format: formatFromArgs(args)}
+	defer logger.Flush()
+	flags := c.FlagSet()
+	flags.Usage = func() {
+		logger.Help(c.Help()) //nolint:errcheck
+	}
+	if err := flags.Parse(args); err != nil {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   err,
+		})
+		return 1
+	}
+
+	logger = internal.Ui{
+		Ui:      c.Ui,
+		Command: c.Name(),
+		Format:  c.format,
+		Quiet:   c.quiet,
+		Trace:   c.trace,
+	}
+	ctx = c.outputContext(ctx)
+
+	if _, err := c.ParsedArguments(flags.Args()); err != nil {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   err,
+		})
+		return 1
+	}
+
+	if c.token == "" {
+		logger.Error(internal.ErrorInput{
+			Error: fmt.Errorf("an api token is required, set --token or DOKKU_DATASTORE_API_TOKEN"),
+		})
+		return 1
+	}
+
+	address := c.listen
+	if c.socket != "" {
+		address = c.socket
+	}
+	logger.Header1(fmt.Sprintf("Serving api on %s", address)) //nolint:errcheck
+
+	err := internal.ServeAPI(ctx, internal.APIServerInput{
+		Listen: c.listen,
+		Socket: c.socket,
+		Token:  c.token,
+	})
+	if err != nil {
+		logger.Error(internal.ErrorInput{
+			Error: err,
+		})
+		return 1
+	}
+
+	return 0
+}
@@ -104,7 +104,8 @@ func (c *AppLinksCommand) Run(args []string) int {
 		cancel()
 	}()
 
-	logger := internal.Ui{Ui: c.Ui}
+	logger := internal.Ui{Ui: c.Ui, Command: c.Name(), Format: formatFromArgs(args)}
+	defer logger.Flush()
 	flags := c.FlagSet()
 	flags.Usage = func() {
 		logger.Help(c.Help()) //nolint:errcheck
@@ -118,11 +119,13 @@ func (c *AppLinksCommand) Run(args []string) int {
 	}
 
 	logger = internal.Ui{
-		Ui:     c.Ui,
-		Format: c.format,
-		Quiet:  c.quiet,
-		Trace:  c.trace,
+		Ui:      c.Ui,
+		Command: c.Name(),
+		Format:  c.format,
+		Quiet:   c.quiet,
+		Trace:   c.trace,
 	}
+	ctx = c.outputContext(ctx)
 
 	arguments, err := c.ParsedArguments(flags.Args())
 	if err != nil {
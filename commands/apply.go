@@ -0,0 +1,241 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/dokku/dokku-datastore/internal"
+	"github.com/dokku/dokku-datastore/internal/datastores"
+
+	"github.com/dokku/dokku/plugins/common"
+	"github.com/josegonzalez/cli-skeleton/command"
+	"github.com/posener/complete"
+	flag "github.com/spf13/pflag"
+)
+
+// ApplyCommand is the command for converging services to a manifest
+type ApplyCommand struct {
+	// Meta is the command meta
+	command.Meta
+	// GlobalFlagCommand is the global flag command
+	GlobalFlagCommand
+	// dryRun is whether to only print the plan
+	dryRun bool
+	// file is the path to the manifest
+	file string
+	// force is whether to skip confirmation when pruning services
+	force bool
+	// noRestart is whether to skip restarting apps when linking or unlinking
+	noRestart bool
+	// prune is whether to destroy services that are not in the manifest
+	prune bool
+}
+
+// Name returns the name of the command
+func (c *ApplyCommand) Name() string {
+	return "apply"
+}
+
+// Synopsis returns the synopsis of the command
+func (c *ApplyCommand) Synopsis() string {
+	return "Converges services to a yaml or toml manifest"
+}
+
+// Help returns the help text for the command
+func (c *ApplyCommand) Help() string {
+	return command.CommandHelp(c)
+}
+
+// Examples returns the examples for the command
+func (c *ApplyCommand) Examples() map[string]string {
+	appName := os.Getenv("CLI_APP_NAME")
+	return map[string]string{
+		"Shows the changes needed to converge to services.yml": fmt.Sprintf("%s %s -f services.yml --dry-run", appName, c.Name()),
+		"Converges to services.yml, destroying unlisted ones":  fmt.Sprintf("%s %s -f services.yml --prune", appName, c.Name()),
+	}
+}
+
+// Arguments returns the arguments for the command
+func (c *ApplyCommand) Arguments() []command.Argument {
+	args := []command.Argument{}
+	return args
+}
+
+// AutocompleteArgs returns the autocomplete arguments for the command
+func (c *ApplyCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+// ParsedArguments parses the arguments for the command
+func (c *ApplyCommand) ParsedArguments(args []string) (map[string]command.Argument, error) {
+	return command.ParseArguments(args, c.Arguments())
+}
+
+// FlagSet returns the flag set for the command
+func (c *ApplyCommand) FlagSet() *flag.FlagSet {
+	f := c.Meta.FlagSet(c.Name(), command.FlagSetClient)
+	c.GlobalFlags(f)
+	f.BoolVar(&c.dryRun, "dry-run", false, "print the plan without applying it")
+	f.StringVarP(&c.file, "file", "f", "", "the yaml or toml manifest describing the desired services")
+	f.BoolVar(&c.force, "force", false, "skip confirmation when pruning services")
+	f.BoolVar(&c.noRestart, "no-restart", false, "do not restart apps when linking or unlinking")
+	f.BoolVar(&c.prune, "prune", false, "destroy services that are not listed in the manifest")
+	return f
+}
+
+// AutocompleteFlags returns the autocomplete flags for the command
+func (c *ApplyCommand) AutocompleteFlags() complete.Flags {
+	return command.MergeAutocompleteFlags(
+		c.Meta.AutocompleteFlags(command.FlagSetClient),
+		c.AutocompleteGlobalFlags(),
+		complete.Flags{
+			"--dry-run":    complete.PredictNothing,
+			"--file":       complete.PredictFiles("*"),
+			"--force":      complete.PredictNothing,
+			"--no-restart": complete.PredictNothing,
+			"--prune":      complete.PredictNothing,
+		},
+	)
+}
+
+// Run runs the command
+func (c *ApplyCommand) Run(args []string) (exitCode int) {
+	audit := newAuditRun(c)
+	defer audit.record(c.Ui, &exitCode)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGHUP,
+		syscall.SIGINT,
+		syscall.SIGQUIT,
+		syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	logger := internal.Ui{Ui: c.Ui, Command: c.Name(), Format: formatFromArgs(args)}
+	defer logger.Flush()
+	flags := c.FlagSet()
+	flags.Usage = func() {
+		logger.Help(c.Help()) //nolint:errcheck
+	}
+	if err := flags.Parse(args); err != nil {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   err,
+		})
+		return 1
+	}
+
+	logger = internal.Ui{
+		Ui:      c.Ui,
+		Command: c.Name(),
+		Format:  c.format,
+		Quiet:   c.quiet,
+		Trace:   c.trace,
+	}
+	ctx = c.outputContext(ctx)
+	audit.flags = flags
+
+	if _, err := c.ParsedArguments(flags.Args()); err != nil {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   err,
+		})
+		return 1
+	}
+
+	if c.file == "" {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   fmt.Errorf("a manifest file is required"),
+		})
+		return 1
+	}
+
+	manifest, err := internal.LoadManifest(c.file)
+	if err != nil {
+		logger.Error(internal.ErrorInput{
+			Error: err,
+		})
+		return 1
+	}
+
+	actions, err := internal.PlanApply(ctx, internal.PlanApplyInput{
+		Manifest: manifest,
+		Prune:    c.prune,
+	})
+	if err != nil {
+		logger.Error(internal.ErrorInput{
+			Error: err,
+		})
+		if errors.Is(err, datastores.ErrAccessDenied) {
+			return ExitCodeAccessDenied
+		}
+		return 1
+	}
+
+	if c.format == "json" {
+		logger.Result(actions)
+	} else {
+		rows := []string{}
+		for _, action := range actions {
+			rows = append(rows, action.String())
+		}
+		if len(rows) == 0 {
+			rows = append(rows, "No changes")
+		}
+		if err := logger.Table("Plan", rows); err != nil {
+			logger.Error(internal.ErrorInput{
+				Error: err,
+			})
+			return 1
+		}
+	}
+
+	if c.dryRun || len(actions) == 0 {
+		return 0
+	}
+
+	if os.Getenv("DOKKU_APPS_FORCE_DELETE") == "1" {
+		c.force = true
+	}
+
+	if !c.force {
+		for _, action := range actions {
+			if action.Action != "destroy" {
+				continue
+			}
+
+			err := common.AskForDestructiveConfirmation(action.ServiceName, fmt.Sprintf("%s service", action.Type))
+			if err != nil {
+				logger.Error(internal.ErrorInput{
+					Error: err,
+				})
+				return 1
+			}
+		}
+	}
+
+	err = internal.Apply(ctx, internal.ApplyInput{
+		Actions:   actions,
+		NoRestart: c.noRestart,
+		Progress: func(action internal.ApplyAction) {
+			logger.Header2(action.String()) //nolint:errcheck
+		},
+	})
+	if err != nil {
+		logger.Error(internal.ErrorInput{
+			Error: err,
+		})
+		return 1
+	}
+
+	logger.Header1(fmt.Sprintf("Applied %d change(s)", len(actions))) //nolint:errcheck
+	return 0
+}
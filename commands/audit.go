@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dokku/dokku-datastore/internal"
+	"github.com/dokku/dokku-datastore/internal/datastores"
+
+	"github.com/josegonzalez/cli-skeleton/command"
+	"github.com/mitchellh/cli"
+	flag "github.com/spf13/pflag"
+)
+
+// auditRun is a run of a mutating command, recorded in the audit log once the command returns
+type auditRun struct {
+	// command is the command being run
+	command command.Command
+
+	// flags is the parsed flag set of the command, or nil when the flags could not be parsed
+	flags *flag.FlagSet
+
+	// started is when the command started
+	started time.Time
+}
+
+// newAuditRun starts recording a run of a mutating command
+func newAuditRun(c command.Command) *auditRun {
+	return &auditRun{command: c, started: time.Now()}
+}
+
+// record records the run in the audit log
+//
+// It is deferred at the start of Run so that the exit code and duration are known
+func (a *auditRun) record(ui cli.Ui, exitCode *int) {
+	args := auditArgs(a.command.Arguments(), a.flags)
+
+	var datastore datastores.Datastore
+	serviceName := ""
+	for _, arg := range args {
+		switch arg.Name {
+		case "datastore-type":
+			datastore = datastores.Datastores[arg.Value]
+		case "service-name":
+			serviceName = arg.Value
+		}
+	}
+
+	err := internal.RecordAudit(internal.RecordAuditInput{
+		Args:        args,
+		Command:     a.command.Name(),
+		Datastore:   datastore,
+		ExitCode:    *exitCode,
+		ServiceName: serviceName,
+		Source:      "cli",
+		Started:     a.started,
+	})
+	if err != nil {
+		ui.Warn(fmt.Sprintf("Unable to record audit log entry: %s", err.Error()))
+	}
+}
+
+// auditArgs names the positional arguments and set flags of a parsed flag set, where a trailing list argument
+// takes every remaining positional argument
+func auditArgs(arguments []command.Argument, flags *flag.FlagSet) []internal.AuditArg {
+	args := []internal.AuditArg{}
+	if flags == nil {
+		return args
+	}
+
+	for i, value := range flags.Args() {
+		name := "argument"
+		switch {
+		case i < len(arguments):
+			name = arguments[i].Name
+		case len(arguments) > 0 && arguments[len(arguments)-1].Type == command.ArgumentList:
+			name = arguments[len(arguments)-1].Name
+		}
+		args = append(args, internal.AuditArg{Name: name, Value: value})
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		args = append(args, internal.AuditArg{Flag: true, Name: f.Name, Value: f.Value.String()})
+	})
+	return args
+}
@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dokku/dokku-datastore/internal"
+
+	"github.com/josegonzalez/cli-skeleton/command"
+	flag "github.com/spf13/pflag"
+)
+
+func TestAuditArgs(t *testing.T) {
+	arguments := []command.Argument{
+		{Name: "datastore-type", Type: command.ArgumentString},
+		{Name: "service-name", Type: command.ArgumentString},
+		{Name: "value", Type: command.ArgumentList},
+	}
+
+	tests := []struct {
+		name      string
+		arguments []command.Argument
+		args      []string
+		want      []internal.AuditArg
+	}{
+		{
+			name:      "positional arguments",
+			arguments: arguments,
+			args:      []string{"redis", "lollipop"},
+			want: []internal.AuditArg{
+				{Name: "datastore-type", Value: "redis"},
+				{Name: "service-name", Value: "lollipop"},
+			},
+		},
+		{
+			name:      "list argument takes the rest",
+			arguments: arguments,
+			args:      []string{"redis", "lollipop", "a", "b"},
+			want: []internal.AuditArg{
+				{Name: "datastore-type", Value: "redis"},
+				{Name: "service-name", Value: "lollipop"},
+				{Name: "value", Value: "a"},
+				{Name: "value", Value: "b"},
+			},
+		},
+		{
+			name:      "extra arguments",
+			arguments: arguments[:1],
+			args:      []string{"redis", "lollipop"},
+			want: []internal.AuditArg{
+				{Name: "datastore-type", Value: "redis"},
+				{Name: "argument", Value: "lollipop"},
+			},
+		},
+		{
+			name:      "only set flags",
+			arguments: arguments[:2],
+			args:      []string{"--password", "hunter2", "redis", "lollipop"},
+			want: []internal.AuditArg{
+				{Name: "datastore-type", Value: "redis"},
+				{Name: "service-name", Value: "lollipop"},
+				{Flag: true, Name: "password", Value: "hunter2"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := flag.NewFlagSet("test", flag.ContinueOnError)
+			flags.String("password", "", "")
+			flags.Bool("force", false, "")
+			if err := flags.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := auditArgs(tt.arguments, flags); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("auditArgs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := auditArgs(arguments, nil); len(got) != 0 {
+		t.Errorf("auditArgs() without parsed flags = %v, want none", got)
+	}
+}
@@ -0,0 +1,34 @@
+package commands
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dokku/dokku-datastore/internal"
+	"github.com/dokku/dokku-datastore/internal/datastores"
+)
+
+// ExitCodeAccessDenied is the exit code when the user-auth-service trigger denies access to a service
+const ExitCodeAccessDenied = 77
+
+// authorizeService runs the shared authorization step for commands taking a service name
+//
+// It returns the exit code the command should exit with, which is zero when access is allowed
+func authorizeService(ctx context.Context, logger *internal.Ui, datastore datastores.Datastore, serviceName string, trace bool) int {
+	err := datastores.AuthorizeService(ctx, datastores.AuthorizeServiceInput{
+		Datastore:   datastore,
+		ServiceName: serviceName,
+		Trace:       trace,
+	})
+	if err == nil {
+		return 0
+	}
+
+	logger.Error(internal.ErrorInput{
+		Error: err,
+	})
+	if errors.Is(err, datastores.ErrAccessDenied) {
+		return ExitCodeAccessDenied
+	}
+	return 1
+}
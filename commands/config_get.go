@@ -0,0 +1,218 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/dokku/dokku-datastore/internal"
+	"github.com/dokku/dokku-datastore/internal/datastores"
+
+	"github.com/josegonzalez/cli-skeleton/command"
+	"github.com/posener/complete"
+	flag "github.com/spf13/pflag"
+)
+
+// ConfigGetCommand is the command for getting a directive from the configuration file of a service
+type ConfigGetCommand struct {
+	// Meta is the command meta
+	command.Meta
+	// GlobalFlagCommand is the global flag command
+	GlobalFlagCommand
+}
+
+// Name returns the name of the command
+func (c *ConfigGetCommand) Name() string {
+	return "config-get"
+}
+
+// Synopsis returns the synopsis of the command
+func (c *ConfigGetCommand) Synopsis() string {
+	return "Gets a directive from the configuration file of a service"
+}
+
+// Help returns the help text for the command
+func (c *ConfigGetCommand) Help() string {
+	return command.CommandHelp(c)
+}
+
+// Examples returns the examples for the command
+func (c *ConfigGetCommand) Examples() map[string]string {
+	appName := os.Getenv("CLI_APP_NAME")
+	return map[string]string{
+		"Gets the eviction policy of a redis service named test": fmt.Sprintf("%s %s redis test maxmemory-policy", appName, c.Name()),
+	}
+}
+
+// Arguments returns the arguments for the command
+func (c *ConfigGetCommand) Arguments() []command.Argument {
+	args := []command.Argument{}
+	args = append(args, command.Argument{
+		Name:        "datastore-type",
+		Description: "the type of datastore the service belongs to",
+		Optional:    false,
+		Type:        command.ArgumentString,
+	})
+	args = append(args, command.Argument{
+		Name:        "service-name",
+		Description: "the name of the service to get the directive of",
+		Optional:    false,
+		Type:        command.ArgumentString,
+	})
+	args = append(args, command.Argument{
+		Name:        "directive",
+		Description: "the name of the directive to get",
+		Optional:    false,
+		Type:        command.ArgumentString,
+	})
+	return args
+}
+
+// AutocompleteArgs returns the autocomplete arguments for the command
+func (c *ConfigGetCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictSet("redis")
+}
+
+// ParsedArguments parses the arguments for the command
+func (c *ConfigGetCommand) ParsedArguments(args []string) (map[string]command.Argument, error) {
+	return command.ParseArguments(args, c.Arguments())
+}
+
+// FlagSet returns the flag set for the command
+func (c *ConfigGetCommand) FlagSet() *flag.FlagSet {
+	f := c.Meta.FlagSet(c.Name(), command.FlagSetClient)
+	c.GlobalFlags(f)
+	return f
+}
+
+// AutocompleteFlags returns the autocomplete flags for the command
+func (c *ConfigGetCommand) AutocompleteFlags() complete.Flags {
+	return command.MergeAutocompleteFlags(
+		c.Meta.AutocompleteFlags(command.FlagSetClient),
+		c.AutocompleteGlobalFlags(),
+		complete.Flags{},
+	)
+}
+
+// Run runs the command
+func (c *ConfigGetCommand) Run(args []string) int {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGHUP,
+		syscall.SIGINT,
+		syscall.SIGQUIT,
+		syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	logger := internal.Ui{Ui: c.Ui, Command: c.Name(), Format: formatFromArgs(args)}
+	defer logger.Flush()
+	flags := c.FlagSet()
+	flags.Usage = func() {
+		logger.Help(c.Help()) //nolint:errcheck
+	}
+	if err := flags.Parse(args); err != nil {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   err,
+		})
+		return 1
+	}
+
+	logger = internal.Ui{
+		Ui:      c.Ui,
+		Command: c.Name(),
+		Format:  c.format,
+		Quiet:   c.quiet,
+		Trace:   c.trace,
+	}
+	ctx = c.outputContext(ctx)
+
+	arguments, err := c.ParsedArguments(flags.Args())
+	if err != nil {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   err,
+		})
+		return 1
+	}
+
+	datastoreType := arguments["datastore-type"].StringValue()
+	if datastoreType == "" {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   fmt.Errorf("datastore type is required"),
+		})
+		return 1
+	}
+
+	datastore, ok := datastores.Datastores[datastoreType]
+	if !ok {
+		logger.Error(internal.ErrorInput{
+			Error: fmt.Errorf("datastore type %s is not supported", datastoreType),
+		})
+		return 1
+	}
+
+	serviceName := arguments["service-name"].StringValue()
+	if serviceName == "" {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   fmt.Errorf("service name is required"),
+		})
+		return 1
+	}
+
+	if err := datastores.ValidateServiceName(serviceName); err != nil {
+		logger.Error(internal.ErrorInput{
+			Error: err,
+		})
+		return 1
+	}
+
+	if code := authorizeService(ctx, &logger, datastore, serviceName, c.trace); code != 0 {
+		return code
+	}
+
+	if !datastores.Exists(ctx, datastore, serviceName) {
+		logger.Error(internal.ErrorInput{
+			Error: fmt.Errorf("service %s does not exist", serviceName),
+		})
+		return 1
+	}
+
+	directive := arguments["directive"].StringValue()
+	if directive == "" {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   fmt.Errorf("directive is required"),
+		})
+		return 1
+	}
+
+	directives, err := internal.GetConfigDirective(datastore, serviceName, directive)
+	if err != nil {
+		logger.Error(internal.ErrorInput{
+			Error: err,
+		})
+		return 1
+	}
+
+	if c.format == "json" {
+		logger.Result(internal.ConfigResult{
+			Type:       datastoreType,
+			Service:    serviceName,
+			Directives: directives,
+		})
+		return 0
+	}
+
+	for _, directive := range directives {
+		c.Ui.Output(directive.Value)
+	}
+	return 0
+}
@@ -0,0 +1,247 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/dokku/dokku-datastore/internal"
+	"github.com/dokku/dokku-datastore/internal/datastores"
+
+	"github.com/josegonzalez/cli-skeleton/command"
+	"github.com/posener/complete"
+	flag "github.com/spf13/pflag"
+)
+
+// ConfigSetCommand is the command for setting a directive in the configuration file of a service
+type ConfigSetCommand struct {
+	// Meta is the command meta
+	command.Meta
+	// GlobalFlagCommand is the global flag command
+	GlobalFlagCommand
+}
+
+// Name returns the name of the command
+func (c *ConfigSetCommand) Name() string {
+	return "config-set"
+}
+
+// Synopsis returns the synopsis of the command
+func (c *ConfigSetCommand) Synopsis() string {
+	return "Sets a directive in the configuration file of a service"
+}
+
+// Help returns the help text for the command
+func (c *ConfigSetCommand) Help() string {
+	return command.CommandHelp(c)
+}
+
+// Examples returns the examples for the command
+func (c *ConfigSetCommand) Examples() map[string]string {
+	appName := os.Getenv("CLI_APP_NAME")
+	return map[string]string{
+		"Notifies subscribers of expired keys on a redis service named test":    fmt.Sprintf("%s %s redis test notify-keyspace-events Ex", appName, c.Name()),
+		"Snapshots a redis service named test every hour after a single change": fmt.Sprintf("%s %s redis test save 3600 1", appName, c.Name()),
+	}
+}
+
+// Arguments returns the arguments for the command
+func (c *ConfigSetCommand) Arguments() []command.Argument {
+	args := []command.Argument{}
+	args = append(args, command.Argument{
+		Name:        "datastore-type",
+		Description: "the type of datastore the service belongs to",
+		Optional:    false,
+		Type:        command.ArgumentString,
+	})
+	args = append(args, command.Argument{
+		Name:        "service-name",
+		Description: "the name of the service to set the directive of",
+		Optional:    false,
+		Type:        command.ArgumentString,
+	})
+	args = append(args, command.Argument{
+		Name:        "directive",
+		Description: "the name of the directive to set",
+		Optional:    false,
+		Type:        command.ArgumentString,
+	})
+	args = append(args, command.Argument{
+		Name:        "value",
+		Description: "the value of the directive",
+		Optional:    false,
+		Type:        command.ArgumentList,
+	})
+	return args
+}
+
+// AutocompleteArgs returns the autocomplete arguments for the command
+func (c *ConfigSetCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictSet("redis")
+}
+
+// ParsedArguments parses the arguments for the command
+func (c *ConfigSetCommand) ParsedArguments(args []string) (map[string]command.Argument, error) {
+	return command.ParseArguments(args, c.Arguments())
+}
+
+// FlagSet returns the flag set for the command
+func (c *ConfigSetCommand) FlagSet() *flag.FlagSet {
+	f := c.Meta.FlagSet(c.Name(), command.FlagSetClient)
+	c.GlobalFlags(f)
+	return f
+}
+
+// AutocompleteFlags returns the autocomplete flags for the command
+func (c *ConfigSetCommand) AutocompleteFlags() complete.Flags {
+	return command.MergeAutocompleteFlags(
+		c.Meta.AutocompleteFlags(command.FlagSetClient),
+		c.AutocompleteGlobalFlags(),
+		complete.Flags{},
+	)
+}
+
+// Run runs the command
+func (c *ConfigSetCommand) Run(args []string) (exitCode int) {
+	audit := newAuditRun(c)
+	defer audit.record(c.Ui, &exitCode)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGHUP,
+		syscall.SIGINT,
+		syscall.SIGQUIT,
+		syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	logger := internal.Ui{Ui: c.Ui, Command: c.Name(), Format: formatFromArgs(args)}
+	defer logger.Flush()
+	flags := c.FlagSet()
+	flags.Usage = func() {
+		logger.Help(c.Help()) //nolint:errcheck
+	}
+	if err := flags.Parse(args); err != nil {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   err,
+		})
+		return 1
+	}
+
+	logger = internal.Ui{
+		Ui:      c.Ui,
+		Command: c.Name(),
+		Format:  c.format,
+		Quiet:   c.quiet,
+		Trace:   c.trace,
+	}
+	ctx = c.outputContext(ctx)
+	audit.flags = flags
+
+	arguments, err := c.ParsedArguments(flags.Args())
+	if err != nil {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   err,
+		})
+		return 1
+	}
+
+	datastoreType := arguments["datastore-type"].StringValue()
+	if datastoreType == "" {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   fmt.Errorf("datastore type is required"),
+		})
+		return 1
+	}
+
+	datastore, ok := datastores.Datastores[datastoreType]
+	if !ok {
+		logger.Error(internal.ErrorInput{
+			Error: fmt.Errorf("datastore type %s is not supported", datastoreType),
+		})
+		return 1
+	}
+
+	serviceName := arguments["service-name"].StringValue()
+	if serviceName == "" {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   fmt.Errorf("service name is required"),
+		})
+		return 1
+	}
+
+	if err := datastores.ValidateServiceName(serviceName); err != nil {
+		logger.Error(internal.ErrorInput{
+			Error: err,
+		})
+		return 1
+	}
+
+	if code := authorizeService(ctx, &logger, datastore, serviceName, c.trace); code != 0 {
+		return code
+	}
+
+	if !datastores.Exists(ctx, datastore, serviceName) {
+		logger.Error(internal.ErrorInput{
+			Error: fmt.Errorf("service %s does not exist", serviceName),
+		})
+		return 1
+	}
+
+	directive := arguments["directive"].StringValue()
+	if directive == "" {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   fmt.Errorf("directive is required"),
+		})
+		return 1
+	}
+
+	value := strings.Join(arguments["value"].ListValue(), " ")
+	if value == "" {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   fmt.Errorf("value is required"),
+		})
+		return 1
+	}
+
+	change, err := internal.SetConfigDirective(ctx, internal.SetConfigDirectiveInput{
+		Datastore:   datastore,
+		Name:        directive,
+		ServiceName: serviceName,
+		Value:       value,
+	})
+	if err != nil {
+		logger.Error(internal.ErrorInput{
+			Error: err,
+		})
+		return 1
+	}
+
+	logger.Header1(fmt.Sprintf("Service %s directive %s set", serviceName, change.Name))
+	if change.Applied {
+		logger.Info("Applied to the running service")
+	}
+	if change.RestartRequired {
+		logger.Warn(internal.WarnInput{
+			Warning: fmt.Sprintf("Restart service %s to apply directive %s", serviceName, change.Name),
+		})
+	}
+	logger.Result(internal.ConfigChangeResult{
+		Type:         datastoreType,
+		Service:      serviceName,
+		ConfigChange: change,
+	})
+
+	return 0
+}
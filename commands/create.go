@@ -2,7 +2,6 @@ package commands
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"os/signal"
@@ -23,28 +22,50 @@ type CreateCommand struct {
 	command.Meta
 	// GlobalFlagCommand is the global flag command
 	GlobalFlagCommand
+	// ResourceFlagCommand is the resource limit flag command
+	ResourceFlagCommand
+	// cluster is whether to shard the service over many member containers
+	cluster bool
+	// clusterReplicas is the number of replicas of each shard of a cluster service
+	clusterReplicas int
+	// clusterShards is the number of shards of a cluster service
+	clusterShards int
 	// configOptions is the configuration options to use for the service
 	configOptions string
 	// customEnv is the custom environment variables to use for the service
 	customEnv string
+	// ha is whether to run the service as a primary with replicas failed over by monitors
+	ha bool
+	// haReplicas is the number of replica services of a high availability service
+	haReplicas int
 	// image is the image to use for the service
 	image string
 	// imageVersion is the image version to use for the service
 	imageVersion string
+	// maxmemoryPercent is the share of the memory limit the datastore may use
+	maxmemoryPercent int
+	// maxmemoryPolicy is the eviction policy once the datastore uses its share of the memory limit
+	maxmemoryPolicy string
 	// memory is the memory limit to use for the service
 	memory int
 	// initialNetwork is the initial network to use for the service
 	initialNetwork string
 	// password is the password to use for the service
 	password string
+	// persistence is how the service persists its data
+	persistence datastores.PersistenceSettings
 	// postCreateNetwork is the networks to attach the service container to after service creation
 	postCreateNetwork []string
+	// replicaOf is the name of the primary service to replicate from
+	replicaOf string
 	// rootPassword is the root password to use for the service
 	rootPassword string
 	// postStartNetwork is the networks to attach the service container to after service start
 	postStartNetwork []string
 	// shmSize is the shared memory size to use for the service
 	shmSize string
+	// tls is whether the service only accepts tls connections
+	tls bool
 }
 
 // Name returns the name of the command
@@ -66,7 +87,9 @@ func (c *CreateCommand) Help() string {
 func (c *CreateCommand) Examples() map[string]string {
 	appName := os.Getenv("CLI_APP_NAME")
 	return map[string]string{
-		"Creates a new redis service named test": fmt.Sprintf("%s %s redis test", appName, c.Name()),
+		"Creates a new redis service named test":                               fmt.Sprintf("%s %s redis test", appName, c.Name()),
+		"Creates a cache-only redis service named cache that never persists":   fmt.Sprintf("%s %s redis cache --persistence none", appName, c.Name()),
+		"Creates a redis service named queue that flushes writes every second": fmt.Sprintf("%s %s redis queue --persistence aof --appendfsync everysec", appName, c.Name()),
 	}
 }
 
@@ -102,17 +125,30 @@ func (c *CreateCommand) ParsedArguments(args []string) (map[string]command.Argum
 func (c *CreateCommand) FlagSet() *flag.FlagSet {
 	f := c.Meta.FlagSet(c.Name(), command.FlagSetClient)
 	c.GlobalFlags(f)
+	c.ResourceFlags(f)
+	f.BoolVar(&c.cluster, "cluster", false, "shard the service over many member containers")
 	f.StringVar(&c.configOptions, "config-options", "", "extra arguments to pass to the container create command")
 	f.StringVar(&c.customEnv, "custom-env", "", "semi-colon delimited environment variables to start the service with")
+	f.BoolVar(&c.ha, "ha", false, "run the service as a primary with replica services, failed over by monitors")
+	f.IntVar(&c.haReplicas, "ha-replicas", internal.DefaultHAReplicas, "the number of replica services of a high availability service")
 	f.StringVar(&c.image, "image", "", "the image name to start the service with")
 	f.StringVar(&c.imageVersion, "image-version", "", "the image version to start the service with")
+	f.IntVar(&c.maxmemoryPercent, "maxmemory-percent", 0, fmt.Sprintf("the share of the memory limit the datastore may use (default: %d)", datastores.DefaultMaxmemoryPercent))
+	f.StringVar(&c.maxmemoryPolicy, "maxmemory-policy", "", "the eviction policy once the datastore uses its share of the memory limit, such as allkeys-lru")
 	f.IntVar(&c.memory, "memory", 0, "container memory limit in megabytes (default: unlimited)")
 	f.StringVar(&c.initialNetwork, "initial-network", "", "the initial network to attach the service to")
 	f.StringVar(&c.password, "password", "", "override the user-level service password")
+	f.StringVar(&c.persistence.Mode, "persistence", "", "how the service persists its data: rdb, aof, both or none (default: the datastore default)")
+	f.StringVar(&c.persistence.Save, "save", "", "the snapshot schedule as pairs of seconds and changes, such as \"3600 1 300 100\"")
+	f.StringVar(&c.persistence.Appendfsync, "appendfsync", "", "how often the append only file is flushed to disk: always, everysec or no (default: everysec)")
+	f.IntVar(&c.clusterReplicas, "replicas", 0, "the number of replicas of each shard of a cluster service")
 	f.StringSliceVar(&c.postCreateNetwork, "post-create-network", []string{}, "a comma-separated list of networks to attach the service container to after service creation")
+	f.StringVar(&c.replicaOf, "replica-of", "", "create the service as a read-only replica of an existing primary service")
 	f.StringVar(&c.rootPassword, "root-password", "", "override the root-level service password")
 	f.StringSliceVar(&c.postStartNetwork, "post-start-network", []string{}, "a comma-separated list of networks to attach the service container to after service start")
+	f.IntVar(&c.clusterShards, "shards", internal.DefaultClusterShards, "the number of shards of a cluster service")
 	f.StringVar(&c.shmSize, "shm-size", "", "override shared memory size for $PLUGIN_COMMAND_PREFIX docker container")
+	f.BoolVar(&c.tls, "tls", false, "only accept tls connections, using a certificate signed by the host certificate authority")
 	return f
 }
 
@@ -121,24 +157,40 @@ func (c *CreateCommand) AutocompleteFlags() complete.Flags {
 	return command.MergeAutocompleteFlags(
 		c.Meta.AutocompleteFlags(command.FlagSetClient),
 		c.AutocompleteGlobalFlags(),
+		c.AutocompleteResourceFlags(),
 		complete.Flags{
+			"--appendfsync":         complete.PredictSet("always", "everysec", "no"),
+			"--cluster":             complete.PredictNothing,
 			"--config-options":      complete.PredictAnything,
 			"--custom-env":          complete.PredictAnything,
+			"--ha":                  complete.PredictNothing,
+			"--ha-replicas":         complete.PredictAnything,
 			"--image":               complete.PredictAnything,
 			"--image-version":       complete.PredictAnything,
+			"--maxmemory-percent":   complete.PredictAnything,
+			"--maxmemory-policy":    complete.PredictAnything,
 			"--memory":              complete.PredictAnything,
 			"--initial-network":     complete.PredictAnything,
 			"--password":            complete.PredictAnything,
+			"--persistence":         complete.PredictSet("rdb", "aof", "both", "none"),
+			"--tls":                 complete.PredictNothing,
 			"--post-create-network": complete.PredictAnything,
+			"--replica-of":          complete.PredictAnything,
+			"--replicas":            complete.PredictAnything,
 			"--root-password":       complete.PredictAnything,
+			"--save":                complete.PredictAnything,
 			"--post-start-network":  complete.PredictAnything,
+			"--shards":              complete.PredictAnything,
 			"--shm-size":            complete.PredictAnything,
 		},
 	)
 }
 
 // Run runs the command
-func (c *CreateCommand) Run(args []string) int {
+func (c *CreateCommand) Run(args []string) (exitCode int) {
+	audit := newAuditRun(c)
+	defer audit.record(c.Ui, &exitCode)
+
 	ctx, cancel := context.WithCancel(context.Background())
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, os.Interrupt, syscall.SIGHUP,
@@ -150,7 +202,8 @@ func (c *CreateCommand) Run(args []string) int {
 		cancel()
 	}()
 
-	logger := internal.Ui{Ui: c.Ui}
+	logger := internal.Ui{Ui: c.Ui, Command: c.Name(), Format: formatFromArgs(args)}
+	defer logger.Flush()
 	flags := c.FlagSet()
 	flags.Usage = func() {
 		logger.Help(c.Help()) //nolint:errcheck
@@ -164,11 +217,14 @@ func (c *CreateCommand) Run(args []string) int {
 	}
 
 	logger = internal.Ui{
-		Ui:     c.Ui,
-		Format: c.format,
-		Quiet:  c.quiet,
-		Trace:  c.trace,
+		Ui:      c.Ui,
+		Command: c.Name(),
+		Format:  c.format,
+		Quiet:   c.quiet,
+		Trace:   c.trace,
 	}
+	ctx = c.outputContext(ctx)
+	audit.flags = flags
 
 	arguments, err := c.ParsedArguments(flags.Args())
 	if err != nil {
@@ -205,6 +261,16 @@ func (c *CreateCommand) Run(args []string) int {
 		return 1
 	}
 
+	if code := authorizeService(ctx, &logger, datastore, serviceName, c.trace); code != 0 {
+		return code
+	}
+
+	if c.replicaOf != "" {
+		if code := authorizeService(ctx, &logger, datastore, c.replicaOf, c.trace); code != 0 {
+			return code
+		}
+	}
+
 	updatedFlags, err := internal.UpdateFlagFromEnv(internal.UpdateFlagFromEnvInput{
 		ConfigOptions: c.configOptions,
 		CustomEnv:     c.customEnv,
@@ -220,18 +286,29 @@ func (c *CreateCommand) Run(args []string) int {
 	}
 
 	err = internal.CreateService(ctx, internal.CreateServiceInput{
+		Cluster:            c.cluster,
+		ClusterReplicas:    c.clusterReplicas,
+		ClusterShards:      c.clusterShards,
 		ConfigOptions:      updatedFlags.ConfigOptions,
 		CustomEnv:          updatedFlags.CustomEnv,
 		Datastore:          datastore,
+		HA:                 c.ha,
+		HAReplicas:         c.haReplicas,
 		Image:              updatedFlags.Image,
 		ImageVersion:       updatedFlags.ImageVersion,
 		InitialNetwork:     c.initialNetwork,
+		MaxmemoryPercent:   c.maxmemoryPercent,
+		MaxmemoryPolicy:    c.maxmemoryPolicy,
 		Memory:             c.memory,
 		Password:           c.password,
+		Persistence:        c.persistence,
 		PostCreateNetworks: c.postCreateNetwork,
 		PostStartNetworks:  c.postStartNetwork,
+		ReplicaOf:          c.replicaOf,
+		Resources:          c.resources,
 		ServiceName:        serviceName,
 		ShmSize:            c.shmSize,
+		TLS:                c.tls,
 	})
 	if err != nil {
 		logger.Error(internal.ErrorInput{
@@ -240,30 +317,10 @@ func (c *CreateCommand) Run(args []string) int {
 		return 1
 	}
 
-	serviceProperties := datastore.Properties()
-	waitPort := serviceProperties.WaitPort
-	initialNetwork := datastores.InitialNetwork(datastore, serviceName)
-	networkAlias := datastores.DNSHostname(datastore, serviceName)
-	containerName := datastores.ContainerName(datastore, serviceName)
-
-	linkContainerDockerArgs := []string{
-		"container",
-		"run",
-		"--rm",
-		"--link=" + containerName + ":" + networkAlias,
-	}
-
-	if initialNetwork != "" {
-		linkContainerDockerArgs = append(linkContainerDockerArgs, "--network="+initialNetwork)
-	}
-
-	linkContainerDockerArgs = append(linkContainerDockerArgs, datastores.PluginWaitImage)
-	linkContainerDockerArgs = append(linkContainerDockerArgs, "-c", fmt.Sprintf("%s:%d", networkAlias, waitPort))
-
 	logger.Header1(fmt.Sprintf("Waiting for %s container to be ready", serviceName)) //nolint:errcheck
-	_, err = datastores.CallExecCommandWithContext(ctx, common.ExecCommandInput{
-		Command: common.DockerBin(),
-		Args:    linkContainerDockerArgs,
+	err = internal.WaitForService(ctx, internal.WaitForServiceInput{
+		Datastore:   datastore,
+		ServiceName: serviceName,
 	})
 	if err != nil {
 		logger.Error(internal.ErrorInput{
@@ -284,12 +341,7 @@ func (c *CreateCommand) Run(args []string) int {
 		ServiceName: serviceName,
 	})
 	if c.format == "json" {
-		if err := json.NewEncoder(os.Stdout).Encode(info); err != nil {
-			logger.Error(internal.ErrorInput{
-				Error: err,
-			})
-			return 1
-		}
+		logger.Result(info)
 	} else {
 		logger.Header2(fmt.Sprintf("%s container created: %s", datastore.Title(), serviceName)) //nolint:errcheck
 		flagKeys := []string{}
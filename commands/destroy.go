@@ -99,7 +99,10 @@ func (c *DestroyCommand) AutocompleteFlags() complete.Flags {
 }
 
 // Run runs the command
-func (c *DestroyCommand) Run(args []string) int {
+func (c *DestroyCommand) Run(args []string) (exitCode int) {
+	audit := newAuditRun(c)
+	defer audit.record(c.Ui, &exitCode)
+
 	ctx, cancel := context.WithCancel(context.Background())
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, os.Interrupt, syscall.SIGHUP,
@@ -111,7 +114,8 @@ func (c *DestroyCommand) Run(args []string) int {
 		cancel()
 	}()
 
-	logger := internal.Ui{Ui: c.Ui}
+	logger := internal.Ui{Ui: c.Ui, Command: c.Name(), Format: formatFromArgs(args)}
+	defer logger.Flush()
 	flags := c.FlagSet()
 	flags.Usage = func() {
 		logger.Help(c.Help()) //nolint:errcheck
@@ -125,11 +129,14 @@ func (c *DestroyCommand) Run(args []string) int {
 	}
 
 	logger = internal.Ui{
-		Ui:     c.Ui,
-		Format: c.format,
-		Quiet:  c.quiet,
-		Trace:  c.trace,
+		Ui:      c.Ui,
+		Command: c.Name(),
+		Format:  c.format,
+		Quiet:   c.quiet,
+		Trace:   c.trace,
 	}
+	ctx = c.outputContext(ctx)
+	audit.flags = flags
 
 	arguments, err := c.ParsedArguments(flags.Args())
 	if err != nil {
@@ -166,6 +173,10 @@ func (c *DestroyCommand) Run(args []string) int {
 		return 1
 	}
 
+	if code := authorizeService(ctx, &logger, datastore, serviceName, c.trace); code != 0 {
+		return code
+	}
+
 	// check if the service exists
 	if !datastores.Exists(ctx, datastore, serviceName) {
 		logger.Error(internal.ErrorInput{
@@ -213,6 +224,11 @@ func (c *DestroyCommand) Run(args []string) int {
 		return 1
 	}
 	logger.Header2(fmt.Sprintf("%s container deleted: %s", datastore.Title(), serviceName)) //nolint:errcheck
+	logger.Result(internal.ServiceResult{
+		Type:    datastoreType,
+		Service: serviceName,
+		Status:  "destroyed",
+	})
 
 	return 0
 }
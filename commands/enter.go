@@ -103,7 +103,8 @@ func (c *EnterCommand) Run(args []string) int {
 		cancel()
 	}()
 
-	logger := internal.Ui{Ui: c.Ui}
+	logger := internal.Ui{Ui: c.Ui, Command: c.Name(), Format: formatFromArgs(args)}
+	defer logger.Flush()
 	flags := c.FlagSet()
 	flags.Usage = func() {
 		logger.Help(c.Help()) //nolint:errcheck
@@ -117,11 +118,13 @@ func (c *EnterCommand) Run(args []string) int {
 	}
 
 	logger = internal.Ui{
-		Ui:     c.Ui,
-		Format: c.format,
-		Quiet:  c.quiet,
-		Trace:  c.trace,
+		Ui:      c.Ui,
+		Command: c.Name(),
+		Format:  c.format,
+		Quiet:   c.quiet,
+		Trace:   c.trace,
 	}
+	ctx = c.outputContext(ctx)
 
 	arguments, err := c.ParsedArguments(flags.Args())
 	if err != nil {
@@ -165,6 +168,10 @@ func (c *EnterCommand) Run(args []string) int {
 		return 1
 	}
 
+	if code := authorizeService(ctx, &logger, datastore, serviceName, c.trace); code != 0 {
+		return code
+	}
+
 	if !datastores.Exists(ctx, datastore, serviceName) {
 		logger.Error(internal.ErrorInput{
 			Error: fmt.Errorf("service %s does not exist", serviceName),
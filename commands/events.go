@@ -0,0 +1,210 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/dokku/dokku-datastore/internal"
+	"github.com/dokku/dokku-datastore/internal/datastores"
+
+	"github.com/josegonzalez/cli-skeleton/command"
+	"github.com/posener/complete"
+	flag "github.com/spf13/pflag"
+)
+
+// EventsCommand is the command for streaming lifecycle events of service containers
+type EventsCommand struct {
+	// Meta is the command meta
+	command.Meta
+	// GlobalFlagCommand is the global flag command
+	GlobalFlagCommand
+	// datastoreType is the type of datastore to stream events for
+	datastoreType string
+	// serviceName is the name of the service to stream events for
+	serviceName string
+	// since is the timestamp or duration to replay events from
+	since string
+}
+
+// Name returns the name of the command
+func (c *EventsCommand) Name() string {
+	return "events"
+}
+
+// Synopsis returns the synopsis of the command
+func (c *EventsCommand) Synopsis() string {
+	return "Streams lifecycle events of service containers"
+}
+
+// Help returns the help text for the command
+func (c *EventsCommand) Help() string {
+	return command.CommandHelp(c)
+}
+
+// Examples returns the examples for the command
+func (c *EventsCommand) Examples() map[string]string {
+	appName := os.Getenv("CLI_APP_NAME")
+	return map[string]string{
+		"Streams events for every service on the host":                     fmt.Sprintf("%s %s", appName, c.Name()),
+		"Streams events for a redis service named test as json lines":      fmt.Sprintf("%s %s --type redis --service test --format json", appName, c.Name()),
+		"Streams redis events, starting with those from the last 24 hours": fmt.Sprintf("%s %s --type redis --since 24h", appName, c.Name()),
+	}
+}
+
+// Arguments returns the arguments for the command
+func (c *EventsCommand) Arguments() []command.Argument {
+	args := []command.Argument{}
+	return args
+}
+
+// AutocompleteArgs returns the autocomplete arguments for the command
+func (c *EventsCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+// ParsedArguments parses the arguments for the command
+func (c *EventsCommand) ParsedArguments(args []string) (map[string]command.Argument, error) {
+	return command.ParseArguments(args, c.Arguments())
+}
+
+// FlagSet returns the flag set for the command
+func (c *EventsCommand) FlagSet() *flag.FlagSet {
+	f := c.Meta.FlagSet(c.Name(), command.FlagSetClient)
+	c.GlobalFlags(f)
+	f.StringVar(&c.datastoreType, "type", "", "the type of datastore to stream events for")
+	f.StringVar(&c.serviceName, "service", "", "the name of the service to stream events for, requires --type")
+	f.StringVar(&c.since, "since", "", "replay events since a timestamp or relative duration, such as 10m")
+	return f
+}
+
+// AutocompleteFlags returns the autocomplete flags for the command
+func (c *EventsCommand) AutocompleteFlags() complete.Flags {
+	return command.MergeAutocompleteFlags(
+		c.Meta.AutocompleteFlags(command.FlagSetClient),
+		c.AutocompleteGlobalFlags(),
+		complete.Flags{
+			"--type":    complete.PredictSet("redis"),
+			"--service": complete.PredictAnything,
+			"--since":   complete.PredictAnything,
+		},
+	)
+}
+
+// Run runs the command
+func (c *EventsCommand) Run(args []string) int {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGHUP,
+		syscall.SIGINT,
+		syscall.SIGQUIT,
+		syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	logger := internal.Ui{Ui: c.Ui, Command: c.Name(), Format: formatFromArgs(args)}
+	defer logger.Flush()
+	flags := c.FlagSet()
+	flags.Usage = func() {
+		logger.Help(c.Help()) //nolint:errcheck
+	}
+	if err := flags.Parse(args); err != nil {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   err,
+		})
+		return 1
+	}
+
+	logger = internal.Ui{
+		Ui:      c.Ui,
+		Command: c.Name(),
+		Format:  c.format,
+		Quiet:   c.quiet,
+		Trace:   c.trace,
+	}
+	ctx = c.outputContext(ctx)
+
+	if _, err := c.ParsedArguments(flags.Args()); err != nil {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   err,
+		})
+		return 1
+	}
+
+	eventsInput := internal.StreamEventsInput{
+		Since: c.since,
+		Trace: c.trace,
+	}
+
+	if c.datastoreType != "" {
+		datastore, ok := datastores.Datastores[c.datastoreType]
+		if !ok {
+			logger.Error(internal.ErrorInput{
+				Error: fmt.Errorf("datastore type %s is not supported", c.datastoreType),
+			})
+			return 1
+		}
+		eventsInput.Datastore = datastore
+	}
+
+	if c.serviceName != "" {
+		if eventsInput.Datastore == nil {
+			logger.Error(internal.ErrorInput{
+				Message: command.CommandErrorText(c),
+				Error:   fmt.Errorf("--service requires --type"),
+			})
+			return 1
+		}
+
+		if err := datastores.ValidateServiceName(c.serviceName); err != nil {
+			logger.Error(internal.ErrorInput{
+				Error: err,
+			})
+			return 1
+		}
+
+		if code := authorizeService(ctx, &logger, eventsInput.Datastore, c.serviceName, c.trace); code != 0 {
+			return code
+		}
+		eventsInput.ServiceName = c.serviceName
+	}
+
+	eventsInput.Handler = func(event internal.ServiceEvent) error {
+		if c.format == "json" {
+			return logger.Stream(event)
+		}
+
+		detail := ""
+		if event.Health != "" {
+			detail = " " + event.Health
+		}
+		if event.ExitCode != nil {
+			detail = fmt.Sprintf(" exit-code=%d", *event.ExitCode)
+		}
+		logger.Info(fmt.Sprintf("%s  %s/%s  %-10s  %s%s",
+			event.Time.Local().Format(time.RFC3339),
+			event.Type,
+			event.Service,
+			event.Role,
+			event.Action,
+			detail,
+		))
+		return nil
+	}
+
+	if err := internal.StreamEvents(ctx, eventsInput); err != nil {
+		logger.Error(internal.ErrorInput{
+			Error: err,
+		})
+		return 1
+	}
+
+	return 0
+}
@@ -21,6 +21,11 @@ type ExposeCommand struct {
 	command.Meta
 	// GlobalFlagCommand is the global flag command
 	GlobalFlagCommand
+
+	// allow is the networks allowed to connect to the exposed ports
+	allow []string
+	// bind is the host address to bind the exposed ports to
+	bind string
 }
 
 // Name returns the name of the command
@@ -42,7 +47,9 @@ func (c *ExposeCommand) Help() string {
 func (c *ExposeCommand) Examples() map[string]string {
 	appName := os.Getenv("CLI_APP_NAME")
 	return map[string]string{
-		"Exposes a redis service named test": fmt.Sprintf("%s %s redis test", appName, c.Name()),
+		"Exposes a redis service named test":                           fmt.Sprintf("%s %s redis test", appName, c.Name()),
+		"Exposes a redis service named test on localhost only":         fmt.Sprintf("%s %s redis test --bind 127.0.0.1", appName, c.Name()),
+		"Exposes a redis service named test to a private network only": fmt.Sprintf("%s %s redis test --bind 10.0.0.5 --allow 10.0.0.0/8", appName, c.Name()),
 	}
 }
 
@@ -84,6 +91,8 @@ func (c *ExposeCommand) ParsedArguments(args []string) (map[string]command.Argum
 func (c *ExposeCommand) FlagSet() *flag.FlagSet {
 	f := c.Meta.FlagSet(c.Name(), command.FlagSetClient)
 	c.GlobalFlags(f)
+	f.StringSliceVar(&c.allow, "allow", []string{}, "a comma-separated list of ipv4 networks allowed to connect to the exposed ports (default: all)")
+	f.StringVar(&c.bind, "bind", "", "the host address to bind the exposed ports to (default: all interfaces)")
 	return f
 }
 
@@ -92,12 +101,18 @@ func (c *ExposeCommand) AutocompleteFlags() complete.Flags {
 	return command.MergeAutocompleteFlags(
 		c.Meta.AutocompleteFlags(command.FlagSetClient),
 		c.AutocompleteGlobalFlags(),
-		complete.Flags{},
+		complete.Flags{
+			"--allow": complete.PredictAnything,
+			"--bind":  complete.PredictAnything,
+		},
 	)
 }
 
 // Run runs the command
-func (c *ExposeCommand) Run(args []string) int {
+func (c *ExposeCommand) Run(args []string) (exitCode int) {
+	audit := newAuditRun(c)
+	defer audit.record(c.Ui, &exitCode)
+
 	ctx, cancel := context.WithCancel(context.Background())
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, os.Interrupt, syscall.SIGHUP,
@@ -109,7 +124,8 @@ func (c *ExposeCommand) Run(args []string) int {
 		cancel()
 	}()
 
-	logger := internal.Ui{Ui: c.Ui}
+	logger := internal.Ui{Ui: c.Ui, Command: c.Name(), Format: formatFromArgs(args)}
+	defer logger.Flush()
 	flags := c.FlagSet()
 	flags.Usage = func() {
 		logger.Help(c.Help()) //nolint:errcheck
@@ -123,11 +139,14 @@ func (c *ExposeCommand) Run(args []string) int {
 	}
 
 	logger = internal.Ui{
-		Ui:     c.Ui,
-		Format: c.format,
-		Quiet:  c.quiet,
-		Trace:  c.trace,
+		Ui:      c.Ui,
+		Command: c.Name(),
+		Format:  c.format,
+		Quiet:   c.quiet,
+		Trace:   c.trace,
 	}
+	ctx = c.outputContext(ctx)
+	audit.flags = flags
 
 	arguments, err := c.ParsedArguments(flags.Args())
 	if err != nil {
@@ -171,6 +190,10 @@ func (c *ExposeCommand) Run(args []string) int {
 		return 1
 	}
 
+	if code := authorizeService(ctx, &logger, datastore, serviceName, c.trace); code != 0 {
+		return code
+	}
+
 	if !datastores.Exists(ctx, datastore, serviceName) {
 		logger.Error(internal.ErrorInput{
 			Error: fmt.Errorf("service %s does not exist", serviceName),
@@ -190,15 +213,20 @@ func (c *ExposeCommand) Run(args []string) int {
 			})
 			return 1
 		}
+		logger.Result(internal.ServiceResult{
+			Type:         datastoreType,
+			Service:      serviceName,
+			Status:       "exposed",
+			ExposedPorts: datastores.ExposedPorts(datastore, serviceName),
+		})
 		return 0
 	}
 
-	ambassadorContainerName := datastores.AmbassadorContainerName(datastore, serviceName)
-	if datastores.ContainerExists(ctx, ambassadorContainerName) {
+	if internal.HasExposeContainer(ctx, datastore, serviceName) {
 		logger.Warn(internal.WarnInput{
 			Warning: fmt.Sprintf("Service %s has an untracked expose container, removing", serviceName),
 		})
-		err = internal.RemoveAmbassadorContainer(ctx, datastore, serviceName)
+		err = internal.RemoveExposeContainers(ctx, datastore, serviceName)
 		if err != nil {
 			logger.Warn(internal.WarnInput{
 				Warning: err.Error(),
@@ -209,6 +237,8 @@ func (c *ExposeCommand) Run(args []string) int {
 	}
 
 	err = internal.ExposeService(ctx, internal.ExposeServiceInput{
+		Allow:       c.allow,
+		Bind:        c.bind,
 		Datastore:   datastore,
 		Ports:       arguments["ports"].ListValue(),
 		ServiceName: serviceName,
@@ -220,5 +250,11 @@ func (c *ExposeCommand) Run(args []string) int {
 		return 1
 	}
 	logger.Header2(fmt.Sprintf("Service %s exposed on port(s) [container->host]: %s", serviceName, datastores.ExposedPorts(datastore, serviceName))) //nolint:errcheck
+	logger.Result(internal.ServiceResult{
+		Type:         datastoreType,
+		Service:      serviceName,
+		Status:       "exposed",
+		ExposedPorts: datastores.ExposedPorts(datastore, serviceName),
+	})
 	return 0
 }
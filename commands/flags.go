@@ -1,6 +1,12 @@
 package commands
 
 import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/dokku/dokku-datastore/internal/datastores"
+
 	"github.com/posener/complete"
 	flag "github.com/spf13/pflag"
 )
@@ -23,6 +29,32 @@ func (c *GlobalFlagCommand) GlobalFlags(f *flag.FlagSet) {
 	f.BoolVar(&c.trace, "trace", false, "enable trace output")
 }
 
+// outputContext returns a context whose triggers and dokku commands stream their stdout to stderr for json
+// output, so stdout only carries the json envelope
+func (c *GlobalFlagCommand) outputContext(ctx context.Context) context.Context {
+	if c.format != "json" {
+		return ctx
+	}
+	return datastores.WithStdoutWriter(ctx, os.Stderr)
+}
+
+// formatFromArgs returns the output format requested in unparsed arguments, so that errors parsing them can be
+// reported in that format
+func formatFromArgs(args []string) string {
+	format := "text"
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if value, ok := strings.CutPrefix(arg, "--format="); ok {
+			format = value
+		} else if arg == "--format" && i+1 < len(args) {
+			format = args[i+1]
+		}
+	}
+	return format
+}
+
 // AutocompleteGlobalFlags returns the autocomplete global flags
 func (c *GlobalFlagCommand) AutocompleteGlobalFlags() complete.Flags {
 	return complete.Flags{
@@ -31,3 +63,35 @@ func (c *GlobalFlagCommand) AutocompleteGlobalFlags() complete.Flags {
 		"--trace":  complete.PredictNothing,
 	}
 }
+
+// ResourceFlagCommand is the resource limit flag command
+type ResourceFlagCommand struct {
+	// resources is the resource limits for the service container
+	resources datastores.ResourceLimits
+}
+
+// ResourceFlags adds the resource limit flags to the flag set
+func (c *ResourceFlagCommand) ResourceFlags(f *flag.FlagSet) {
+	f.IntVar(&c.resources.BlkioWeight, "blkio-weight", 0, "relative block io weight between 10 and 1000 (default: unset)")
+	f.StringVar(&c.resources.CPUs, "cpus", "", "number of cpus the container may use, such as 1.5 (default: unlimited)")
+	f.IntVar(&c.resources.CPUShares, "cpu-shares", 0, "relative cpu weight (default: unset)")
+	f.StringVar(&c.resources.CPUSetCPUs, "cpuset-cpus", "", "cpus the container may run on, such as 0-3 (default: all)")
+	f.StringVar(&c.resources.MemoryReservation, "memory-reservation", "", "soft memory limit, such as 256m (default: unset)")
+	f.StringVar(&c.resources.MemorySwap, "memory-swap", "", "memory plus swap limit, such as 1g, or -1 for unlimited swap (default: unset)")
+	f.IntVar(&c.resources.PidsLimit, "pids-limit", 0, "maximum number of processes (default: unlimited)")
+	f.StringSliceVar(&c.resources.Ulimits, "ulimit", []string{}, "a ulimit in the format <name>=<soft>[:<hard>], such as nofile=10032:10032")
+}
+
+// AutocompleteResourceFlags returns the autocomplete resource limit flags
+func (c *ResourceFlagCommand) AutocompleteResourceFlags() complete.Flags {
+	return complete.Flags{
+		"--blkio-weight":       complete.PredictAnything,
+		"--cpus":               complete.PredictAnything,
+		"--cpu-shares":         complete.PredictAnything,
+		"--cpuset-cpus":        complete.PredictAnything,
+		"--memory-reservation": complete.PredictAnything,
+		"--memory-swap":        complete.PredictAnything,
+		"--pids-limit":         complete.PredictAnything,
+		"--ulimit":             complete.PredictAnything,
+	}
+}
@@ -0,0 +1,27 @@
+package commands
+
+import "testing"
+
+func TestFormatFromArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{name: "no flags", args: []string{"redis", "lollipop"}, want: "text"},
+		{name: "separate value", args: []string{"--format", "json", "redis"}, want: "json"},
+		{name: "joined value", args: []string{"redis", "--format=json"}, want: "json"},
+		{name: "last wins", args: []string{"--format=json", "--format", "text"}, want: "text"},
+		{name: "missing value", args: []string{"redis", "--format"}, want: "text"},
+		{name: "after separator", args: []string{"redis", "--", "--format=json"}, want: "text"},
+		{name: "before an unknown flag", args: []string{"--format", "json", "--nosuch"}, want: "json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatFromArgs(tt.args); got != tt.want {
+				t.Errorf("formatFromArgs(%q) = %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/dokku/dokku-datastore/internal"
+	"github.com/dokku/dokku-datastore/internal/datastores"
+
+	"github.com/josegonzalez/cli-skeleton/command"
+	"github.com/posener/complete"
+	flag "github.com/spf13/pflag"
+)
+
+// HardeningCommand is the command for changing the container hardening settings of a service or datastore
+type HardeningCommand struct {
+	// Meta is the command meta
+	command.Meta
+	// GlobalFlagCommand is the global flag command
+	GlobalFlagCommand
+	// enabled is whether hardening is enabled, where empty falls back to the datastore default
+	enabled string
+	// user is the non-root user to run as
+	user string
+	// seccompProfile is the path to a custom seccomp profile
+	seccompProfile string
+	// apparmorProfile is the name of a custom apparmor profile
+	apparmorProfile string
+}
+
+// Name returns the name of the command
+func (c *HardeningCommand) Name() string {
+	return "hardening"
+}
+
+// Synopsis returns the synopsis of the command
+func (c *HardeningCommand) Synopsis() string {
+	return "Changes the container hardening settings of a service"
+}
+
+// Help returns the help text for the command
+func (c *HardeningCommand) Help() string {
+	return command.CommandHelp(c)
+}
+
+// Examples returns the examples for the command
+func (c *HardeningCommand) Examples() map[string]string {
+	appName := os.Getenv("CLI_APP_NAME")
+	return map[string]string{
+		"Hardens a redis service named test":                      fmt.Sprintf("%s %s redis test --enabled true", appName, c.Name()),
+		"Hardens every redis service that does not override it":   fmt.Sprintf("%s %s redis --enabled true", appName, c.Name()),
+		"Runs a redis service named test with a seccomp profile":  fmt.Sprintf("%s %s redis test --seccomp-profile /etc/docker/seccomp/redis.json", appName, c.Name()),
+		"Makes a redis service named test use the redis defaults": fmt.Sprintf("%s %s redis test --enabled ''", appName, c.Name()),
+	}
+}
+
+// Arguments returns the arguments for the command
+func (c *HardeningCommand) Arguments() []command.Argument {
+	args := []command.Argument{}
+	args = append(args, command.Argument{
+		Name:        "datastore-type",
+		Description: "the type of datastore to change the hardening settings of",
+		Optional:    false,
+		Type:        command.ArgumentString,
+	})
+	args = append(args, command.Argument{
+		Name:        "service-name",
+		Description: "the name of the service to change the hardening settings of, or none to change the datastore defaults",
+		Optional:    true,
+		Type:        command.ArgumentString,
+	})
+	return args
+}
+
+// AutocompleteArgs returns the autocomplete arguments for the command
+func (c *HardeningCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictSet("redis")
+}
+
+// ParsedArguments parses the arguments for the command
+func (c *HardeningCommand) ParsedArguments(args []string) (map[string]command.Argument, error) {
+	return command.ParseArguments(args, c.Arguments())
+}
+
+// FlagSet returns the flag set for the command
+func (c *HardeningCommand) FlagSet() *flag.FlagSet {
+	f := c.Meta.FlagSet(c.Name(), command.FlagSetClient)
+	c.GlobalFlags(f)
+	f.StringVar(&c.enabled, "enabled", "", "whether to run read-only, without capabilities and as a non-root user: true or false")
+	f.StringVar(&c.user, "user", "", "the non-root user to run as, such as 999:999 (default: the datastore user)")
+	f.StringVar(&c.seccompProfile, "seccomp-profile", "", "the path to a custom seccomp profile")
+	f.StringVar(&c.apparmorProfile, "apparmor-profile", "", "the name of a custom apparmor profile")
+	return f
+}
+
+// AutocompleteFlags returns the autocomplete flags for the command
+func (c *HardeningCommand) AutocompleteFlags() complete.Flags {
+	return command.MergeAutocompleteFlags(
+		c.Meta.AutocompleteFlags(command.FlagSetClient),
+		c.AutocompleteGlobalFlags(),
+		complete.Flags{
+			"--enabled":          complete.PredictSet("true", "false"),
+			"--user":             complete.PredictAnything,
+			"--seccomp-profile":  complete.PredictFiles("*.json"),
+			"--apparmor-profile": complete.PredictAnything,
+		},
+	)
+}
+
+// Run runs the command
+func (c *HardeningCommand) Run(args []string) (exitCode int) {
+	audit := newAuditRun(c)
+	defer audit.record(c.Ui, &exitCode)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGHUP,
+		syscall.SIGINT,
+		syscall.SIGQUIT,
+		syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	logger := internal.Ui{Ui: c.Ui, Command: c.Name(), Format: formatFromArgs(args)}
+	defer logger.Flush()
+	flags := c.FlagSet()
+	flags.Usage = func() {
+		logger.Help(c.Help()) //nolint:errcheck
+	}
+	if err := flags.Parse(args); err != nil {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   err,
+		})
+		return 1
+	}
+
+	logger = internal.Ui{
+		Ui:      c.Ui,
+		Command: c.Name(),
+		Format:  c.format,
+		Quiet:   c.quiet,
+		Trace:   c.trace,
+	}
+	ctx = c.outputContext(ctx)
+	audit.flags = flags
+
+	arguments, err := c.ParsedArguments(flags.Args())
+	if err != nil {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   err,
+		})
+		return 1
+	}
+
+	datastoreType := arguments["datastore-type"].StringValue()
+	if datastoreType == "" {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   fmt.Errorf("datastore type is required"),
+		})
+		return 1
+	}
+
+	datastore, ok := datastores.Datastores[datastoreType]
+	if !ok {
+		logger.Error(internal.ErrorInput{
+			Error: fmt.Errorf("datastore type %s is not supported", datastoreType),
+		})
+		return 1
+	}
+
+	values := map[string]string{}
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "enabled":
+			values["hardening"] = c.enabled
+		case "user":
+			values["hardening-user"] = c.user
+		case "seccomp-profile":
+			values["hardening-seccomp-profile"] = c.seccompProfile
+		case "apparmor-profile":
+			values["hardening-apparmor-profile"] = c.apparmorProfile
+		}
+	})
+	if len(values) == 0 {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   fmt.Errorf("at least one hardening flag is required"),
+		})
+		return 1
+	}
+
+	serviceName := arguments["service-name"].StringValue()
+	if serviceName == "" {
+		// the datastore defaults apply to services when their containers are next created
+		err = datastores.SetHardening(datastores.SetHardeningInput{
+			Datastore:   datastore,
+			ServiceName: datastores.HardeningGlobalScope,
+			Values:      values,
+		})
+		if err != nil {
+			logger.Error(internal.ErrorInput{
+				Error: err,
+			})
+			return 1
+		}
+
+		logger.Header1(fmt.Sprintf("Default hardening settings for %s services updated", datastoreType))
+		return 0
+	}
+
+	if err := datastores.ValidateServiceName(serviceName); err != nil {
+		logger.Error(internal.ErrorInput{
+			Error: err,
+		})
+		return 1
+	}
+
+	if code := authorizeService(ctx, &logger, datastore, serviceName, c.trace); code != 0 {
+		return code
+	}
+
+	if !datastores.Exists(ctx, datastore, serviceName) {
+		logger.Error(internal.ErrorInput{
+			Error: fmt.Errorf("service %s does not exist", serviceName),
+		})
+		return 1
+	}
+
+	err = datastores.SetHardening(datastores.SetHardeningInput{
+		Datastore:   datastore,
+		ServiceName: serviceName,
+		Values:      values,
+	})
+	if err != nil {
+		logger.Error(internal.ErrorInput{
+			Error: err,
+		})
+		return 1
+	}
+
+	// hardening flags are fixed at container creation, so the container is recreated to apply them
+	err = datastores.RecreateServiceContainer(ctx, datastores.RecreateServiceContainerInput{
+		Datastore:   datastore,
+		ServiceName: serviceName,
+	})
+	if err != nil {
+		logger.Error(internal.ErrorInput{
+			Error: err,
+		})
+		return 1
+	}
+
+	logger.Header1(fmt.Sprintf("Service %s hardening settings updated", serviceName))
+	logger.Result(internal.ServiceResult{
+		Type:    datastoreType,
+		Service: serviceName,
+		Status:  datastores.Status(ctx, datastores.StatusInput{Datastore: datastore, ServiceName: serviceName}),
+	})
+
+	return 0
+}
@@ -0,0 +1,210 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dokku/dokku-datastore/internal"
+	"github.com/dokku/dokku-datastore/internal/datastores"
+
+	"github.com/josegonzalez/cli-skeleton/command"
+	"github.com/posener/complete"
+	flag "github.com/spf13/pflag"
+)
+
+// HistoryCommand is the command for showing the audit log of a service
+type HistoryCommand struct {
+	// Meta is the command meta
+	command.Meta
+	// GlobalFlagCommand is the global flag command
+	GlobalFlagCommand
+
+	// num is the number of entries to display
+	num int
+}
+
+// Name returns the name of the command
+func (c *HistoryCommand) Name() string {
+	return "history"
+}
+
+// Synopsis returns the synopsis of the command
+func (c *HistoryCommand) Synopsis() string {
+	return "Shows the audit log of a service"
+}
+
+// Help returns the help text for the command
+func (c *HistoryCommand) Help() string {
+	return command.CommandHelp(c)
+}
+
+// Examples returns the examples for the command
+func (c *HistoryCommand) Examples() map[string]string {
+	appName := os.Getenv("CLI_APP_NAME")
+	return map[string]string{
+		"Shows the audit log of a redis service named test":                fmt.Sprintf("%s %s redis test", appName, c.Name()),
+		"Shows the last 5 audit log entries of a redis service named test": fmt.Sprintf("%s %s redis test --num 5", appName, c.Name()),
+	}
+}
+
+// Arguments returns the arguments for the command
+func (c *HistoryCommand) Arguments() []command.Argument {
+	args := []command.Argument{}
+	args = append(args, command.Argument{
+		Name:        "datastore-type",
+		Description: "the type of datastore to show the audit log of",
+		Optional:    false,
+		Type:        command.ArgumentString,
+	})
+	args = append(args, command.Argument{
+		Name:        "service-name",
+		Description: "the name of the service to show the audit log of",
+		Optional:    false,
+		Type:        command.ArgumentString,
+	})
+	return args
+}
+
+// AutocompleteArgs returns the autocomplete arguments for the command
+func (c *HistoryCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictSet("redis")
+}
+
+// ParsedArguments parses the arguments for the command
+func (c *HistoryCommand) ParsedArguments(args []string) (map[string]command.Argument, error) {
+	return command.ParseArguments(args, c.Arguments())
+}
+
+// FlagSet returns the flag set for the command
+func (c *HistoryCommand) FlagSet() *flag.FlagSet {
+	f := c.Meta.FlagSet(c.Name(), command.FlagSetClient)
+	c.GlobalFlags(f)
+	f.IntVar(&c.num, "num", 0, "the number of most recent entries to display (default: all)")
+	return f
+}
+
+// AutocompleteFlags returns the autocomplete flags for the command
+func (c *HistoryCommand) AutocompleteFlags() complete.Flags {
+	return command.MergeAutocompleteFlags(
+		c.Meta.AutocompleteFlags(command.FlagSetClient),
+		c.AutocompleteGlobalFlags(),
+		complete.Flags{
+			"--num": complete.PredictAnything,
+		},
+	)
+}
+
+// Run runs the command
+func (c *HistoryCommand) Run(args []string) int {
+	logger := internal.Ui{Ui: c.Ui, Command: c.Name(), Format: formatFromArgs(args)}
+	defer logger.Flush()
+	flags := c.FlagSet()
+	flags.Usage = func() {
+		logger.Help(c.Help()) //nolint:errcheck
+	}
+	if err := flags.Parse(args); err != nil {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   err,
+		})
+		return 1
+	}
+
+	logger = internal.Ui{
+		Ui:      c.Ui,
+		Command: c.Name(),
+		Format:  c.format,
+		Quiet:   c.quiet,
+		Trace:   c.trace,
+	}
+
+	arguments, err := c.ParsedArguments(flags.Args())
+	if err != nil {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   err,
+		})
+		return 1
+	}
+
+	datastoreType := arguments["datastore-type"].StringValue()
+	if datastoreType == "" {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   fmt.Errorf("datastore type is required"),
+		})
+		return 1
+	}
+
+	serviceName := arguments["service-name"].StringValue()
+	if serviceName == "" {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   fmt.Errorf("service name is required"),
+		})
+		return 1
+	}
+
+	datastore, ok := datastores.Datastores[datastoreType]
+	if !ok {
+		logger.Error(internal.ErrorInput{
+			Error: fmt.Errorf("datastore type %s is not supported", datastoreType),
+		})
+		return 1
+	}
+
+	// destroyed services are not required to exist, as their history is kept in the global log
+	if err := datastores.ValidateServiceName(serviceName); err != nil {
+		logger.Error(internal.ErrorInput{
+			Error: err,
+		})
+		return 1
+	}
+
+	if code := authorizeService(context.Background(), &logger, datastore, serviceName, c.trace); code != 0 {
+		return code
+	}
+
+	entries, err := internal.ReadAuditLog(internal.ReadAuditLogInput{
+		Datastore:   datastore,
+		Num:         c.num,
+		ServiceName: serviceName,
+	})
+	if err != nil {
+		logger.Error(internal.ErrorInput{
+			Error: err,
+		})
+		return 1
+	}
+
+	if c.format == "json" {
+		logger.Result(entries)
+		return 0
+	}
+
+	rows := []string{}
+	for _, entry := range entries {
+		duration := time.Duration(entry.DurationMs) * time.Millisecond
+		rows = append(rows, fmt.Sprintf("%s  %s (%s)  %-5s  %s %s  %s (%s)",
+			entry.Timestamp.Format(time.RFC3339),
+			entry.SSHUser,
+			entry.SSHName,
+			entry.Source,
+			entry.Command,
+			strings.Join(entry.Args, " "),
+			entry.Result,
+			duration,
+		))
+	}
+	if err := logger.Table(fmt.Sprintf("%s service %s history", datastoreType, serviceName), rows); err != nil {
+		logger.Error(internal.ErrorInput{
+			Error: err,
+		})
+		return 1
+	}
+
+	return 0
+}
@@ -2,10 +2,10 @@ package commands
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/dokku/dokku-datastore/internal"
@@ -23,6 +23,10 @@ type InfoCommand struct {
 	command.Meta
 	// GlobalFlagCommand is the global flag command
 	GlobalFlagCommand
+	// aofRewrite is the state of the latest append only file rewrite of the service
+	aofRewrite bool
+	// clusterMembers is the cluster members of a service besides the service container
+	clusterMembers bool
 	// configDir is the configuration directory for the service
 	configDir bool
 	// dataDir is the data directory for the service
@@ -31,22 +35,40 @@ type InfoCommand struct {
 	dsn bool
 	// exposedPorts is the exposed ports for the service
 	exposedPorts bool
+	// exposeMode is the expose mode for the service
+	exposeMode bool
+	// haMembers is the replica services of a high availability service
+	haMembers bool
+	// haPrimary is the current primary of a high availability service
+	haPrimary bool
+	// hardening is the hardening settings for the service
+	hardening bool
 	// id is the ID for the service
 	id bool
 	// internalIp is the internal IP for the service
 	internalIp bool
 	// initialNetwork is the initial network for the service
 	initialNetwork bool
+	// lastSave is when the service last saved a snapshot
+	lastSave bool
 	// links is the links for the service
 	links bool
+	// persistence is the persistence mode of the service
+	persistence bool
 	// postCreateNetwork is the post create network for the service
 	postCreateNetwork bool
 	// postStartNetwork is the post start network for the service
 	postStartNetwork bool
+	// replicaOf is the primary the service replicates from
+	replicaOf bool
+	// resourceLimits is the resource limits for the service
+	resourceLimits bool
 	// serviceRoot is the service root for the service
 	serviceRoot bool
 	// status is the status for the service
 	status bool
+	// tls is the tls state for the service
+	tls bool
 	// version is the version for the service
 	version bool
 }
@@ -106,18 +128,29 @@ func (c *InfoCommand) ParsedArguments(args []string) (map[string]command.Argumen
 func (c *InfoCommand) FlagSet() *flag.FlagSet {
 	f := c.Meta.FlagSet(c.Name(), command.FlagSetClient)
 	c.GlobalFlags(f)
+	f.BoolVar(&c.aofRewrite, "aof-rewrite", false, "the state of the latest append only file rewrite of the service")
+	f.BoolVar(&c.clusterMembers, "cluster-members", false, "the cluster members of a service besides the service container")
 	f.BoolVar(&c.configDir, "config-dir", false, "the configuration directory for the service")
 	f.BoolVar(&c.dataDir, "data-dir", false, "the data directory for the service")
 	f.BoolVar(&c.dsn, "dsn", false, "the data source name for the service")
 	f.BoolVar(&c.exposedPorts, "exposed-ports", false, "the exposed ports for the service")
+	f.BoolVar(&c.exposeMode, "expose-mode", false, "the expose mode for the service")
+	f.BoolVar(&c.haMembers, "ha-members", false, "the replica services of a high availability service")
+	f.BoolVar(&c.haPrimary, "ha-primary", false, "the current primary of a high availability service")
+	f.BoolVar(&c.hardening, "hardening", false, "the hardening settings for the service")
 	f.BoolVar(&c.id, "id", false, "the ID for the service")
 	f.BoolVar(&c.internalIp, "internal-ip", false, "the internal IP for the service")
 	f.BoolVar(&c.initialNetwork, "initial-network", false, "the initial network for the service")
+	f.BoolVar(&c.lastSave, "last-save", false, "when the service last saved a snapshot")
 	f.BoolVar(&c.links, "links", false, "the links for the service")
+	f.BoolVar(&c.persistence, "persistence", false, "the persistence mode of the service")
 	f.BoolVar(&c.postCreateNetwork, "post-create-network", false, "the post create network for the service")
 	f.BoolVar(&c.postStartNetwork, "post-start-network", false, "the post start network for the service")
+	f.BoolVar(&c.replicaOf, "replica-of", false, "the primary the service replicates from")
+	f.BoolVar(&c.resourceLimits, "resource-limits", false, "the resource limits for the service")
 	f.BoolVar(&c.serviceRoot, "service-root", false, "the service root for the service")
 	f.BoolVar(&c.status, "status", false, "the status for the service")
+	f.BoolVar(&c.tls, "tls", false, "the tls state of the service")
 	f.BoolVar(&c.version, "version", false, "the version for the service")
 	return f
 }
@@ -128,18 +161,29 @@ func (c *InfoCommand) AutocompleteFlags() complete.Flags {
 		c.Meta.AutocompleteFlags(command.FlagSetClient),
 		c.AutocompleteGlobalFlags(),
 		complete.Flags{
+			"aof-rewrite":         complete.PredictNothing,
+			"cluster-members":     complete.PredictNothing,
 			"config-dir":          complete.PredictNothing,
 			"data-dir":            complete.PredictNothing,
 			"dsn":                 complete.PredictNothing,
 			"exposed-ports":       complete.PredictNothing,
+			"expose-mode":         complete.PredictNothing,
+			"ha-members":          complete.PredictNothing,
+			"ha-primary":          complete.PredictNothing,
+			"hardening":           complete.PredictNothing,
 			"id":                  complete.PredictNothing,
 			"internal-ip":         complete.PredictNothing,
 			"initial-network":     complete.PredictNothing,
+			"last-save":           complete.PredictNothing,
 			"links":               complete.PredictNothing,
+			"persistence":         complete.PredictNothing,
 			"post-create-network": complete.PredictNothing,
 			"post-start-network":  complete.PredictNothing,
+			"replica-of":          complete.PredictNothing,
+			"resource-limits":     complete.PredictNothing,
 			"service-root":        complete.PredictNothing,
 			"status":              complete.PredictNothing,
+			"tls":                 complete.PredictNothing,
 			"version":             complete.PredictNothing,
 		},
 	)
@@ -158,7 +202,8 @@ func (c *InfoCommand) Run(args []string) int {
 		cancel()
 	}()
 
-	logger := internal.Ui{Ui: c.Ui}
+	logger := internal.Ui{Ui: c.Ui, Command: c.Name(), Format: formatFromArgs(args)}
+	defer logger.Flush()
 	flags := c.FlagSet()
 	flags.Usage = func() {
 		logger.Help(c.Help()) //nolint:errcheck
@@ -172,11 +217,13 @@ func (c *InfoCommand) Run(args []string) int {
 	}
 
 	logger = internal.Ui{
-		Ui:     c.Ui,
-		Format: c.format,
-		Quiet:  c.quiet,
-		Trace:  c.trace,
+		Ui:      c.Ui,
+		Command: c.Name(),
+		Format:  c.format,
+		Quiet:   c.quiet,
+		Trace:   c.trace,
 	}
+	ctx = c.outputContext(ctx)
 
 	arguments, err := c.ParsedArguments(flags.Args())
 	if err != nil {
@@ -220,6 +267,10 @@ func (c *InfoCommand) Run(args []string) int {
 		return 1
 	}
 
+	if code := authorizeService(ctx, &logger, datastore, serviceName, c.trace); code != 0 {
+		return code
+	}
+
 	if !datastores.Exists(ctx, datastore, serviceName) {
 		logger.Error(internal.ErrorInput{
 			Error: fmt.Errorf("service %s does not exist", serviceName),
@@ -228,6 +279,12 @@ func (c *InfoCommand) Run(args []string) int {
 	}
 
 	infoFlag := ""
+	if c.aofRewrite {
+		infoFlag = "--aof-rewrite"
+	}
+	if c.clusterMembers {
+		infoFlag = "--cluster-members"
+	}
 	if c.configDir {
 		infoFlag = "--config-dir"
 	}
@@ -240,6 +297,18 @@ func (c *InfoCommand) Run(args []string) int {
 	if c.exposedPorts {
 		infoFlag = "--exposed-ports"
 	}
+	if c.exposeMode {
+		infoFlag = "--expose-mode"
+	}
+	if c.haMembers {
+		infoFlag = "--ha-members"
+	}
+	if c.haPrimary {
+		infoFlag = "--ha-primary"
+	}
+	if c.hardening {
+		infoFlag = "--hardening"
+	}
 	if c.id {
 		infoFlag = "--id"
 	}
@@ -249,36 +318,47 @@ func (c *InfoCommand) Run(args []string) int {
 	if c.initialNetwork {
 		infoFlag = "--initial-network"
 	}
+	if c.lastSave {
+		infoFlag = "--last-save"
+	}
 	if c.links {
 		infoFlag = "--links"
 	}
+	if c.persistence {
+		infoFlag = "--persistence"
+	}
 	if c.postCreateNetwork {
 		infoFlag = "--post-create-network"
 	}
 	if c.postStartNetwork {
 		infoFlag = "--post-start-network"
 	}
+	if c.replicaOf {
+		infoFlag = "--replica-of"
+	}
+	if c.resourceLimits {
+		infoFlag = "--resource-limits"
+	}
 	if c.serviceRoot {
 		infoFlag = "--service-root"
 	}
 	if c.status {
 		infoFlag = "--status"
 	}
+	if c.tls {
+		infoFlag = "--tls"
+	}
 	if c.version {
 		infoFlag = "--version"
 	}
 
 	info := datastores.Info(ctx, datastores.InfoInput{
 		Datastore:   datastore,
+		Field:       strings.TrimPrefix(infoFlag, "--"),
 		ServiceName: serviceName,
 	})
 	if c.format == "json" {
-		if err := json.NewEncoder(os.Stdout).Encode(info); err != nil {
-			logger.Error(internal.ErrorInput{
-				Error: err,
-			})
-
-		}
+		logger.Result(info)
 	} else {
 		flagKeys := []string{}
 
@@ -111,7 +111,8 @@ func (c *LinkedCommand) Run(args []string) int {
 		cancel()
 	}()
 
-	logger := internal.Ui{Ui: c.Ui}
+	logger := internal.Ui{Ui: c.Ui, Command: c.Name(), Format: formatFromArgs(args)}
+	defer logger.Flush()
 	flags := c.FlagSet()
 	flags.Usage = func() {
 		logger.Help(c.Help()) //nolint:errcheck
@@ -125,11 +126,13 @@ func (c *LinkedCommand) Run(args []string) int {
 	}
 
 	logger = internal.Ui{
-		Ui:     c.Ui,
-		Format: c.format,
-		Quiet:  c.quiet,
-		Trace:  c.trace,
+		Ui:      c.Ui,
+		Command: c.Name(),
+		Format:  c.format,
+		Quiet:   c.quiet,
+		Trace:   c.trace,
 	}
+	ctx = c.outputContext(ctx)
 
 	arguments, err := c.ParsedArguments(flags.Args())
 	if err != nil {
@@ -189,6 +192,10 @@ func (c *LinkedCommand) Run(args []string) int {
 		return 1
 	}
 
+	if code := authorizeService(ctx, &logger, datastore, serviceName, c.trace); code != 0 {
+		return code
+	}
+
 	if !datastores.Exists(ctx, datastore, serviceName) {
 		logger.Error(internal.ErrorInput{
 			Error: fmt.Errorf("service %s does not exist", serviceName),
@@ -209,6 +216,12 @@ func (c *LinkedCommand) Run(args []string) int {
 
 	if slices.Contains(linkedServices, serviceName) {
 		logger.Info(fmt.Sprintf("Service %s is linked to app %s", serviceName, appName))
+		logger.Result(internal.ServiceResult{
+			Type:    datastoreType,
+			Service: serviceName,
+			Status:  "linked",
+			App:     appName,
+		})
 	} else {
 		logger.Error(internal.ErrorInput{
 			Error: fmt.Errorf("service %s is not linked to app %s", serviceName, appName),
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"regexp"
 	"syscall"
 
 	"github.com/dokku/dokku-datastore/internal"
@@ -22,10 +23,22 @@ type LogsCommand struct {
 	// GlobalFlagCommand is the global flag command
 	GlobalFlagCommand
 
+	// ambassador is whether to get the logs of the expose container
+	ambassador bool
+	// grep is a regular expression lines must match
+	grep string
+	// member is the cluster member to get the logs of
+	member string
 	// tail is whether to tail the logs
 	tail bool
 	// num is the number of lines to display
 	num int
+	// since is the timestamp or relative duration to show logs since
+	since string
+	// timestamps is whether to show timestamps
+	timestamps bool
+	// until is the timestamp or relative duration to show logs until
+	until string
 }
 
 // Name returns the name of the command
@@ -47,7 +60,11 @@ func (c *LogsCommand) Help() string {
 func (c *LogsCommand) Examples() map[string]string {
 	appName := os.Getenv("CLI_APP_NAME")
 	return map[string]string{
-		"Gets the logs of a redis service named test": fmt.Sprintf("%s %s redis test", appName, c.Name()),
+		"Gets the logs of a redis service named test":                      fmt.Sprintf("%s %s redis test", appName, c.Name()),
+		"Gets the last hour of warnings from a redis service named test":   fmt.Sprintf("%s %s redis test --since 1h --grep '#'", appName, c.Name()),
+		"Tails the logs of a redis service named test as json lines":       fmt.Sprintf("%s %s redis test --tail --format json", appName, c.Name()),
+		"Gets the logs of the ambassador of a redis service named test":    fmt.Sprintf("%s %s redis test --ambassador", appName, c.Name()),
+		"Gets the logs of the second member of a redis cluster named test": fmt.Sprintf("%s %s redis test --member node-1", appName, c.Name()),
 	}
 }
 
@@ -83,8 +100,14 @@ func (c *LogsCommand) ParsedArguments(args []string) (map[string]command.Argumen
 func (c *LogsCommand) FlagSet() *flag.FlagSet {
 	f := c.Meta.FlagSet(c.Name(), command.FlagSetClient)
 	c.GlobalFlags(f)
+	f.BoolVar(&c.ambassador, "ambassador", false, "get the logs of the ambassador or proxy container instead of the service")
+	f.StringVar(&c.grep, "grep", "", "only display lines matching a regular expression")
+	f.StringVar(&c.member, "member", "", "only get the logs of a cluster member, such as node-0")
 	f.BoolVar(&c.tail, "tail", false, "tail the logs")
 	f.IntVar(&c.num, "num", 100, "the number of lines to display")
+	f.StringVar(&c.since, "since", "", "show logs since a timestamp or relative duration, such as 42m")
+	f.BoolVar(&c.timestamps, "timestamps", false, "show timestamps")
+	f.StringVar(&c.until, "until", "", "show logs before a timestamp or relative duration, such as 42m")
 	return f
 }
 
@@ -93,7 +116,16 @@ func (c *LogsCommand) AutocompleteFlags() complete.Flags {
 	return command.MergeAutocompleteFlags(
 		c.Meta.AutocompleteFlags(command.FlagSetClient),
 		c.AutocompleteGlobalFlags(),
-		complete.Flags{},
+		complete.Flags{
+			"--ambassador": complete.PredictNothing,
+			"--grep":       complete.PredictAnything,
+			"--member":     complete.PredictAnything,
+			"--num":        complete.PredictAnything,
+			"--since":      complete.PredictAnything,
+			"--tail":       complete.PredictNothing,
+			"--timestamps": complete.PredictNothing,
+			"--until":      complete.PredictAnything,
+		},
 	)
 }
 
@@ -110,7 +142,8 @@ func (c *LogsCommand) Run(args []string) int {
 		cancel()
 	}()
 
-	logger := internal.Ui{Ui: c.Ui}
+	logger := internal.Ui{Ui: c.Ui, Command: c.Name(), Format: formatFromArgs(args)}
+	defer logger.Flush()
 	flags := c.FlagSet()
 	flags.Usage = func() {
 		logger.Help(c.Help()) //nolint:errcheck
@@ -124,11 +157,13 @@ func (c *LogsCommand) Run(args []string) int {
 	}
 
 	logger = internal.Ui{
-		Ui:     c.Ui,
-		Format: c.format,
-		Quiet:  c.quiet,
-		Trace:  c.trace,
+		Ui:      c.Ui,
+		Command: c.Name(),
+		Format:  c.format,
+		Quiet:   c.quiet,
+		Trace:   c.trace,
 	}
+	ctx = c.outputContext(ctx)
 
 	arguments, err := c.ParsedArguments(flags.Args())
 	if err != nil {
@@ -172,6 +207,10 @@ func (c *LogsCommand) Run(args []string) int {
 		return 1
 	}
 
+	if code := authorizeService(ctx, &logger, datastore, serviceName, c.trace); code != 0 {
+		return code
+	}
+
 	if !datastores.Exists(ctx, datastore, serviceName) {
 		logger.Error(internal.ErrorInput{
 			Error: fmt.Errorf("service %s does not exist", serviceName),
@@ -179,23 +218,36 @@ func (c *LogsCommand) Run(args []string) int {
 		return 1
 	}
 
-	containerID := datastores.LiveContainerID(ctx, datastores.LiveContainerIDInput{
-		Datastore:   datastore,
-		ServiceName: serviceName,
-	})
-	if containerID == "" {
-		logger.Error(internal.ErrorInput{
-			Error: fmt.Errorf("container %s does not exist", serviceName),
-		})
-		return 1
+	var grep *regexp.Regexp
+	if c.grep != "" {
+		grep, err = regexp.Compile(c.grep)
+		if err != nil {
+			logger.Error(internal.ErrorInput{
+				Error: fmt.Errorf("invalid --grep expression: %w", err),
+			})
+			return 1
+		}
 	}
 
-	err = internal.Logs(ctx, internal.LogsInput{
+	logsInput := internal.LogsInput{
+		Ambassador:  c.ambassador,
 		Datastore:   datastore,
+		Grep:        grep,
+		Member:      c.member,
 		ServiceName: serviceName,
 		Num:         c.num,
+		Since:       c.since,
 		Tail:        c.tail,
-	})
+		Timestamps:  c.timestamps,
+		Until:       c.until,
+	}
+	if c.format == "json" {
+		logsInput.Handler = func(line internal.LogLine) error {
+			return logger.Stream(line)
+		}
+	}
+
+	err = internal.Logs(ctx, logsInput)
 	if err != nil {
 		logger.Error(internal.ErrorInput{
 			Error: err,
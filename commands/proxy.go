@@ -0,0 +1,156 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/dokku/dokku-datastore/internal"
+
+	"github.com/josegonzalez/cli-skeleton/command"
+	"github.com/posener/complete"
+	flag "github.com/spf13/pflag"
+)
+
+// ProxyCommand is the command run inside the proxy sidecar of a service exposed in proxy mode
+type ProxyCommand struct {
+	// Meta is the command meta
+	command.Meta
+	// GlobalFlagCommand is the global flag command
+	GlobalFlagCommand
+	// forwards is the ports to forward, in the format <listen-port>=<host>:<port>
+	forwards []string
+}
+
+// Name returns the name of the command
+func (c *ProxyCommand) Name() string {
+	return "proxy"
+}
+
+// Synopsis returns the synopsis of the command
+func (c *ProxyCommand) Synopsis() string {
+	return "Forwards tcp ports to a service"
+}
+
+// Help returns the help text for the command
+func (c *ProxyCommand) Help() string {
+	return command.CommandHelp(c)
+}
+
+// Examples returns the examples for the command
+func (c *ProxyCommand) Examples() map[string]string {
+	appName := os.Getenv("CLI_APP_NAME")
+	return map[string]string{
+		"Forwards port 6379 to a redis service named test": fmt.Sprintf("%s %s --forward 6379=dokku.redis.test:6379", appName, c.Name()),
+	}
+}
+
+// Arguments returns the arguments for the command
+func (c *ProxyCommand) Arguments() []command.Argument {
+	args := []command.Argument{}
+	return args
+}
+
+// AutocompleteArgs returns the autocomplete arguments for the command
+func (c *ProxyCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+// ParsedArguments parses the arguments for the command
+func (c *ProxyCommand) ParsedArguments(args []string) (map[string]command.Argument, error) {
+	return command.ParseArguments(args, c.Arguments())
+}
+
+// FlagSet returns the flag set for the command
+func (c *ProxyCommand) FlagSet() *flag.FlagSet {
+	f := c.Meta.FlagSet(c.Name(), command.FlagSetClient)
+	c.GlobalFlags(f)
+	f.StringSliceVar(&c.forwards, "forward", []string{}, "a port to forward, in the format <listen-port>=<host>:<port>")
+	return f
+}
+
+// AutocompleteFlags returns the autocomplete flags for the command
+func (c *ProxyCommand) AutocompleteFlags() complete.Flags {
+	return command.MergeAutocompleteFlags(
+		c.Meta.AutocompleteFlags(command.FlagSetClient),
+		c.AutocompleteGlobalFlags(),
+		complete.Flags{
+			"--forward": complete.PredictAnything,
+		},
+	)
+}
+
+// Run runs the command
+func (c *ProxyCommand) Run(args []string) int {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGHUP,
+		syscall.SIGINT,
+		syscall.SIGQUIT,
+		syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	logger := internal.Ui{Ui: c.Ui, Command: c.Name(), Format: formatFromArgs(args)}
+	defer logger.Flush()
+	flags := c.FlagSet()
+	flags.Usage = func() {
+		logger.Help(c.Help()) //nolint:errcheck
+	}
+	if err := flags.Parse(args); err != nil {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   err,
+		})
+		return 1
+	}
+
+	logger = internal.Ui{
+		Ui:      c.Ui,
+		Command: c.Name(),
+		Format:  c.format,
+		Quiet:   c.quiet,
+		Trace:   c.trace,
+	}
+	ctx = c.outputContext(ctx)
+
+	if _, err := c.ParsedArguments(flags.Args()); err != nil {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   err,
+		})
+		return 1
+	}
+
+	forwards := []internal.ProxyForward{}
+	for _, value := range c.forwards {
+		forward, err := internal.ParseProxyForward(value)
+		if err != nil {
+			logger.Error(internal.ErrorInput{
+				Error: err,
+			})
+			return 1
+		}
+		forwards = append(forwards, forward)
+	}
+
+	for _, forward := range forwards {
+		logger.Info(fmt.Sprintf("Forwarding port %d to %s", forward.ListenPort, forward.Target))
+	}
+
+	err := internal.ServeProxy(ctx, internal.ProxyInput{
+		Forwards: forwards,
+	})
+	if err != nil {
+		logger.Error(internal.ErrorInput{
+			Error: err,
+		})
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,288 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/dokku/dokku-datastore/internal"
+	"github.com/dokku/dokku-datastore/internal/datastores"
+	"github.com/dokku/dokku/plugins/common"
+
+	"github.com/josegonzalez/cli-skeleton/command"
+	"github.com/posener/complete"
+	flag "github.com/spf13/pflag"
+)
+
+// ResourcesCommand is the command for changing the resource limits of a service
+type ResourcesCommand struct {
+	// Meta is the command meta
+	command.Meta
+	// GlobalFlagCommand is the global flag command
+	GlobalFlagCommand
+	// ResourceFlagCommand is the resource limit flag command
+	ResourceFlagCommand
+	// maxmemoryPercent is the share of the memory limit the datastore may use
+	maxmemoryPercent int
+	// maxmemoryPolicy is the eviction policy once the datastore uses its share of the memory limit
+	maxmemoryPolicy string
+	// memory is the memory limit in megabytes
+	memory int
+}
+
+// Name returns the name of the command
+func (c *ResourcesCommand) Name() string {
+	return "resources"
+}
+
+// Synopsis returns the synopsis of the command
+func (c *ResourcesCommand) Synopsis() string {
+	return "Changes the resource limits of a service"
+}
+
+// Help returns the help text for the command
+func (c *ResourcesCommand) Help() string {
+	return command.CommandHelp(c)
+}
+
+// Examples returns the examples for the command
+func (c *ResourcesCommand) Examples() map[string]string {
+	appName := os.Getenv("CLI_APP_NAME")
+	return map[string]string{
+		"Limits a redis service named test to one and a half cpus":                                               fmt.Sprintf("%s %s redis test --cpus 1.5", appName, c.Name()),
+		"Raises the open file limit of a redis service named test":                                               fmt.Sprintf("%s %s redis test --ulimit nofile=10032:10032", appName, c.Name()),
+		"Removes the process limit of a redis service named test":                                                fmt.Sprintf("%s %s redis test --pids-limit 0", appName, c.Name()),
+		"Evicts the least recently used keys of a redis service named test once it uses 80% of its memory limit": fmt.Sprintf("%s %s redis test --maxmemory-percent 80 --maxmemory-policy allkeys-lru", appName, c.Name()),
+	}
+}
+
+// Arguments returns the arguments for the command
+func (c *ResourcesCommand) Arguments() []command.Argument {
+	args := []command.Argument{}
+	args = append(args, command.Argument{
+		Name:        "datastore-type",
+		Description: "the type of datastore to change the resource limits of",
+		Optional:    false,
+		Type:        command.ArgumentString,
+	})
+	args = append(args, command.Argument{
+		Name:        "service-name",
+		Description: "the name of the service to change the resource limits of",
+		Optional:    false,
+		Type:        command.ArgumentString,
+	})
+	return args
+}
+
+// AutocompleteArgs returns the autocomplete arguments for the command
+func (c *ResourcesCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictSet("redis")
+}
+
+// ParsedArguments parses the arguments for the command
+func (c *ResourcesCommand) ParsedArguments(args []string) (map[string]command.Argument, error) {
+	return command.ParseArguments(args, c.Arguments())
+}
+
+// FlagSet returns the flag set for the command
+func (c *ResourcesCommand) FlagSet() *flag.FlagSet {
+	f := c.Meta.FlagSet(c.Name(), command.FlagSetClient)
+	c.GlobalFlags(f)
+	c.ResourceFlags(f)
+	f.IntVar(&c.maxmemoryPercent, "maxmemory-percent", 0, fmt.Sprintf("the share of the memory limit the datastore may use, where 0 is the default of %d", datastores.DefaultMaxmemoryPercent))
+	f.StringVar(&c.maxmemoryPolicy, "maxmemory-policy", "", "the eviction policy once the datastore uses its share of the memory limit, where empty is the datastore default")
+	f.IntVar(&c.memory, "memory", 0, "container memory limit in megabytes, where 0 is unlimited")
+	return f
+}
+
+// AutocompleteFlags returns the autocomplete flags for the command
+func (c *ResourcesCommand) AutocompleteFlags() complete.Flags {
+	return command.MergeAutocompleteFlags(
+		c.Meta.AutocompleteFlags(command.FlagSetClient),
+		c.AutocompleteGlobalFlags(),
+		c.AutocompleteResourceFlags(),
+		complete.Flags{
+			"--maxmemory-percent": complete.PredictAnything,
+			"--maxmemory-policy":  complete.PredictAnything,
+			"--memory":            complete.PredictAnything,
+		},
+	)
+}
+
+// Run runs the command
+func (c *ResourcesCommand) Run(args []string) (exitCode int) {
+	audit := newAuditRun(c)
+	defer audit.record(c.Ui, &exitCode)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGHUP,
+		syscall.SIGINT,
+		syscall.SIGQUIT,
+		syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	logger := internal.Ui{Ui: c.Ui, Command: c.Name(), Format: formatFromArgs(args)}
+	defer logger.Flush()
+	flags := c.FlagSet()
+	flags.Usage = func() {
+		logger.Help(c.Help()) //nolint:errcheck
+	}
+	if err := flags.Parse(args); err != nil {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   err,
+		})
+		return 1
+	}
+
+	logger = internal.Ui{
+		Ui:      c.Ui,
+		Command: c.Name(),
+		Format:  c.format,
+		Quiet:   c.quiet,
+		Trace:   c.trace,
+	}
+	ctx = c.outputContext(ctx)
+	audit.flags = flags
+
+	arguments, err := c.ParsedArguments(flags.Args())
+	if err != nil {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   err,
+		})
+		return 1
+	}
+
+	datastoreType := arguments["datastore-type"].StringValue()
+	if datastoreType == "" {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   fmt.Errorf("datastore type is required"),
+		})
+		return 1
+	}
+
+	datastore, ok := datastores.Datastores[datastoreType]
+	if !ok {
+		logger.Error(internal.ErrorInput{
+			Error: fmt.Errorf("datastore type %s is not supported", datastoreType),
+		})
+		return 1
+	}
+
+	serviceName := arguments["service-name"].StringValue()
+	if serviceName == "" {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   fmt.Errorf("service name is required"),
+		})
+		return 1
+	}
+
+	if err := datastores.ValidateServiceName(serviceName); err != nil {
+		logger.Error(internal.ErrorInput{
+			Error: err,
+		})
+		return 1
+	}
+
+	if code := authorizeService(ctx, &logger, datastore, serviceName, c.trace); code != 0 {
+		return code
+	}
+
+	if !datastores.Exists(ctx, datastore, serviceName) {
+		logger.Error(internal.ErrorInput{
+			Error: fmt.Errorf("service %s does not exist", serviceName),
+		})
+		return 1
+	}
+
+	// only the limits passed as flags are changed, the rest keep their current values
+	serviceFiles := datastores.Files(datastore, serviceName)
+	memory, _ := strconv.Atoi(common.ReadFirstLine(serviceFiles.Memory))
+	limits := datastores.ReadResourceLimits(datastore, serviceName)
+	changed := false
+	maxmemoryChanged := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "blkio-weight":
+			limits.BlkioWeight = c.resources.BlkioWeight
+		case "cpus":
+			limits.CPUs = c.resources.CPUs
+		case "cpu-shares":
+			limits.CPUShares = c.resources.CPUShares
+		case "cpuset-cpus":
+			limits.CPUSetCPUs = c.resources.CPUSetCPUs
+		case "maxmemory-percent", "maxmemory-policy":
+			maxmemoryChanged[f.Name] = true
+		case "memory":
+			memory = c.memory
+		case "memory-reservation":
+			limits.MemoryReservation = c.resources.MemoryReservation
+		case "memory-swap":
+			limits.MemorySwap = c.resources.MemorySwap
+		case "pids-limit":
+			limits.PidsLimit = c.resources.PidsLimit
+		case "ulimit":
+			limits.Ulimits = c.resources.Ulimits
+		default:
+			return
+		}
+		changed = true
+	})
+	if !changed {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   fmt.Errorf("at least one resource limit flag is required"),
+		})
+		return 1
+	}
+
+	// the maxmemory settings are derived from the memory limit, so they are stored before it is applied
+	if maxmemoryChanged["maxmemory-percent"] {
+		if err := datastores.SetMaxmemoryPercent(datastore, serviceName, c.maxmemoryPercent); err != nil {
+			logger.Error(internal.ErrorInput{
+				Error: err,
+			})
+			return 1
+		}
+	}
+	if maxmemoryChanged["maxmemory-policy"] {
+		if err := datastores.SetMaxmemoryPolicy(datastore, serviceName, c.maxmemoryPolicy); err != nil {
+			logger.Error(internal.ErrorInput{
+				Error: err,
+			})
+			return 1
+		}
+	}
+
+	err = datastores.UpdateResourceLimits(ctx, datastores.UpdateResourceLimitsInput{
+		Datastore:   datastore,
+		Limits:      limits,
+		Memory:      memory,
+		ServiceName: serviceName,
+	})
+	if err != nil {
+		logger.Error(internal.ErrorInput{
+			Error: err,
+		})
+		return 1
+	}
+
+	logger.Header1(fmt.Sprintf("Service %s resource limits updated", serviceName))
+	logger.Result(internal.ServiceResult{
+		Type:    datastoreType,
+		Service: serviceName,
+		Status:  datastores.Status(ctx, datastores.StatusInput{Datastore: datastore, ServiceName: serviceName}),
+	})
+
+	return 0
+}
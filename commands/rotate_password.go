@@ -0,0 +1,255 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/dokku/dokku-datastore/internal"
+	"github.com/dokku/dokku-datastore/internal/datastores"
+
+	"github.com/josegonzalez/cli-skeleton/command"
+	"github.com/posener/complete"
+	flag "github.com/spf13/pflag"
+)
+
+// RotatePasswordCommand is the command for rotating the password of a service
+type RotatePasswordCommand struct {
+	// Meta is the command meta
+	command.Meta
+	// GlobalFlagCommand is the global flag command
+	GlobalFlagCommand
+	// keepPrevious is whether the current password stays valid until it is revoked
+	keepPrevious bool
+	// noRestart is whether to skip restarting linked apps after their dsn changes
+	noRestart bool
+	// password is the new password
+	password string
+	// revokePrevious is whether to revoke the password kept valid by the last rotation
+	revokePrevious bool
+}
+
+// Name returns the name of the command
+func (c *RotatePasswordCommand) Name() string {
+	return "rotate-password"
+}
+
+// Synopsis returns the synopsis of the command
+func (c *RotatePasswordCommand) Synopsis() string {
+	return "Rotates the password of a service"
+}
+
+// Help returns the help text for the command
+func (c *RotatePasswordCommand) Help() string {
+	return command.CommandHelp(c)
+}
+
+// Examples returns the examples for the command
+func (c *RotatePasswordCommand) Examples() map[string]string {
+	appName := os.Getenv("CLI_APP_NAME")
+	return map[string]string{
+		"Rotates the password of a redis service named test":                      fmt.Sprintf("%s %s redis test", appName, c.Name()),
+		"Rotates the password while the current one stays valid for running apps": fmt.Sprintf("%s %s redis test --keep-previous", appName, c.Name()),
+		"Stops accepting the password kept valid by the last rotation":            fmt.Sprintf("%s %s redis test --revoke-previous", appName, c.Name()),
+		"Sets the password of a redis service named test to a specific value":     fmt.Sprintf("%s %s redis test --password secret", appName, c.Name()),
+	}
+}
+
+// Arguments returns the arguments for the command
+func (c *RotatePasswordCommand) Arguments() []command.Argument {
+	args := []command.Argument{}
+	args = append(args, command.Argument{
+		Name:        "datastore-type",
+		Description: "the type of datastore to rotate the password of",
+		Optional:    false,
+		Type:        command.ArgumentString,
+	})
+	args = append(args, command.Argument{
+		Name:        "service-name",
+		Description: "the name of the service to rotate the password of",
+		Optional:    false,
+		Type:        command.ArgumentString,
+	})
+	return args
+}
+
+// AutocompleteArgs returns the autocomplete arguments for the command
+func (c *RotatePasswordCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictSet("redis")
+}
+
+// ParsedArguments parses the arguments for the command
+func (c *RotatePasswordCommand) ParsedArguments(args []string) (map[string]command.Argument, error) {
+	return command.ParseArguments(args, c.Arguments())
+}
+
+// FlagSet returns the flag set for the command
+func (c *RotatePasswordCommand) FlagSet() *flag.FlagSet {
+	f := c.Meta.FlagSet(c.Name(), command.FlagSetClient)
+	c.GlobalFlags(f)
+	f.BoolVar(&c.keepPrevious, "keep-previous", false, "keep the current password valid until it is revoked with --revoke-previous")
+	f.BoolVar(&c.noRestart, "no-restart", false, "do not restart linked apps after updating their dsn")
+	f.StringVar(&c.password, "password", "", "the new password, generated when empty")
+	f.BoolVar(&c.revokePrevious, "revoke-previous", false, "revoke the password kept valid by the last rotation")
+	return f
+}
+
+// AutocompleteFlags returns the autocomplete flags for the command
+func (c *RotatePasswordCommand) AutocompleteFlags() complete.Flags {
+	return command.MergeAutocompleteFlags(
+		c.Meta.AutocompleteFlags(command.FlagSetClient),
+		c.AutocompleteGlobalFlags(),
+		complete.Flags{
+			"--keep-previous":   complete.PredictNothing,
+			"--no-restart":      complete.PredictNothing,
+			"--password":        complete.PredictAnything,
+			"--revoke-previous": complete.PredictNothing,
+		},
+	)
+}
+
+// Run runs the command
+func (c *RotatePasswordCommand) Run(args []string) (exitCode int) {
+	audit := newAuditRun(c)
+	defer audit.record(c.Ui, &exitCode)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGHUP,
+		syscall.SIGINT,
+		syscall.SIGQUIT,
+		syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	logger := internal.Ui{Ui: c.Ui, Command: c.Name(), Format: formatFromArgs(args)}
+	defer logger.Flush()
+	flags := c.FlagSet()
+	flags.Usage = func() {
+		logger.Help(c.Help()) //nolint:errcheck
+	}
+	if err := flags.Parse(args); err != nil {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   err,
+		})
+		return 1
+	}
+
+	logger = internal.Ui{
+		Ui:      c.Ui,
+		Command: c.Name(),
+		Format:  c.format,
+		Quiet:   c.quiet,
+		Trace:   c.trace,
+	}
+	ctx = c.outputContext(ctx)
+	audit.flags = flags
+
+	arguments, err := c.ParsedArguments(flags.Args())
+	if err != nil {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   err,
+		})
+		return 1
+	}
+
+	datastoreType := arguments["datastore-type"].StringValue()
+	if datastoreType == "" {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   fmt.Errorf("datastore type is required"),
+		})
+		return 1
+	}
+
+	datastore, ok := datastores.Datastores[datastoreType]
+	if !ok {
+		logger.Error(internal.ErrorInput{
+			Error: fmt.Errorf("datastore type %s is not supported", datastoreType),
+		})
+		return 1
+	}
+
+	serviceName := arguments["service-name"].StringValue()
+	if serviceName == "" {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   fmt.Errorf("service name is required"),
+		})
+		return 1
+	}
+
+	if err := datastores.ValidateServiceName(serviceName); err != nil {
+		logger.Error(internal.ErrorInput{
+			Error: err,
+		})
+		return 1
+	}
+
+	if code := authorizeService(ctx, &logger, datastore, serviceName, c.trace); code != 0 {
+		return code
+	}
+
+	if !datastores.Exists(ctx, datastore, serviceName) {
+		logger.Error(internal.ErrorInput{
+			Error: fmt.Errorf("service %s does not exist", serviceName),
+		})
+		return 1
+	}
+
+	if c.revokePrevious {
+		if c.keepPrevious || c.password != "" {
+			logger.Error(internal.ErrorInput{
+				Error: fmt.Errorf("--revoke-previous cannot be combined with --keep-previous or --password"),
+			})
+			return 1
+		}
+
+		err = datastores.RevokePreviousPassword(ctx, datastores.RevokePreviousPasswordInput{
+			Datastore:   datastore,
+			ServiceName: serviceName,
+		})
+		if err != nil {
+			logger.Error(internal.ErrorInput{
+				Error: err,
+			})
+			return 1
+		}
+
+		logger.Header1(fmt.Sprintf("Service %s previous password revoked", serviceName))
+		logger.Result(internal.ServiceResult{
+			Type:    datastoreType,
+			Service: serviceName,
+		})
+		return 0
+	}
+
+	err = internal.RotateServicePassword(ctx, internal.RotateServicePasswordInput{
+		Datastore:    datastore,
+		KeepPrevious: c.keepPrevious,
+		NoRestart:    c.noRestart,
+		Password:     c.password,
+		ServiceName:  serviceName,
+	})
+	if err != nil {
+		logger.Error(internal.ErrorInput{
+			Error: err,
+		})
+		return 1
+	}
+
+	logger.Header1(fmt.Sprintf("Service %s password rotated", serviceName))
+	logger.Result(internal.ServiceResult{
+		Type:    datastoreType,
+		Service: serviceName,
+		Status:  datastores.Status(ctx, datastores.StatusInput{Datastore: datastore, ServiceName: serviceName}),
+	})
+
+	return 0
+}
@@ -0,0 +1,236 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/dokku/dokku-datastore/internal"
+	"github.com/dokku/dokku-datastore/internal/datastores"
+
+	"github.com/josegonzalez/cli-skeleton/command"
+	"github.com/posener/complete"
+	flag "github.com/spf13/pflag"
+)
+
+// statsRefreshInterval is how long the refreshing view waits between snapshots
+const statsRefreshInterval = 1 * time.Second
+
+// StatsCommand is the command for showing the resource usage of services
+type StatsCommand struct {
+	// Meta is the command meta
+	command.Meta
+	// GlobalFlagCommand is the global flag command
+	GlobalFlagCommand
+	// noStream is whether to show a single snapshot instead of a refreshing view
+	noStream bool
+}
+
+// Name returns the name of the command
+func (c *StatsCommand) Name() string {
+	return "stats"
+}
+
+// Synopsis returns the synopsis of the command
+func (c *StatsCommand) Synopsis() string {
+	return "Shows the resource usage of services"
+}
+
+// Help returns the help text for the command
+func (c *StatsCommand) Help() string {
+	return command.CommandHelp(c)
+}
+
+// Examples returns the examples for the command
+func (c *StatsCommand) Examples() map[string]string {
+	appName := os.Getenv("CLI_APP_NAME")
+	return map[string]string{
+		"Shows a refreshing view of every service on the host": fmt.Sprintf("%s %s", appName, c.Name()),
+		"Shows the resource usage of every redis service":      fmt.Sprintf("%s %s redis --no-stream", appName, c.Name()),
+		"Shows the resource usage of a redis service as json":  fmt.Sprintf("%s %s redis test --format json", appName, c.Name()),
+	}
+}
+
+// Arguments returns the arguments for the command
+func (c *StatsCommand) Arguments() []command.Argument {
+	args := []command.Argument{}
+	args = append(args, command.Argument{
+		Name:        "datastore-type",
+		Description: "the type of datastore to show the resource usage of",
+		Optional:    true,
+		Type:        command.ArgumentString,
+	})
+	args = append(args, command.Argument{
+		Name:        "service-name",
+		Description: "the name of the service to show the resource usage of",
+		Optional:    true,
+		Type:        command.ArgumentString,
+	})
+	return args
+}
+
+// AutocompleteArgs returns the autocomplete arguments for the command
+func (c *StatsCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictSet("redis")
+}
+
+// ParsedArguments parses the arguments for the command
+func (c *StatsCommand) ParsedArguments(args []string) (map[string]command.Argument, error) {
+	return command.ParseArguments(args, c.Arguments())
+}
+
+// FlagSet returns the flag set for the command
+func (c *StatsCommand) FlagSet() *flag.FlagSet {
+	f := c.Meta.FlagSet(c.Name(), command.FlagSetClient)
+	c.GlobalFlags(f)
+	f.BoolVar(&c.noStream, "no-stream", false, "show a single snapshot instead of a refreshing view")
+	return f
+}
+
+// AutocompleteFlags returns the autocomplete flags for the command
+func (c *StatsCommand) AutocompleteFlags() complete.Flags {
+	return command.MergeAutocompleteFlags(
+		c.Meta.AutocompleteFlags(command.FlagSetClient),
+		c.AutocompleteGlobalFlags(),
+		complete.Flags{
+			"--no-stream": complete.PredictNothing,
+		},
+	)
+}
+
+// Run runs the command
+func (c *StatsCommand) Run(args []string) int {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGHUP,
+		syscall.SIGINT,
+		syscall.SIGQUIT,
+		syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	logger := internal.Ui{Ui: c.Ui, Command: c.Name(), Format: formatFromArgs(args)}
+	defer logger.Flush()
+	flags := c.FlagSet()
+	flags.Usage = func() {
+		logger.Help(c.Help()) //nolint:errcheck
+	}
+	if err := flags.Parse(args); err != nil {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   err,
+		})
+		return 1
+	}
+
+	logger = internal.Ui{
+		Ui:      c.Ui,
+		Command: c.Name(),
+		Format:  c.format,
+		Quiet:   c.quiet,
+		Trace:   c.trace,
+	}
+	ctx = c.outputContext(ctx)
+
+	arguments, err := c.ParsedArguments(flags.Args())
+	if err != nil {
+		logger.Error(internal.ErrorInput{
+			Message: command.CommandErrorText(c),
+			Error:   err,
+		})
+		return 1
+	}
+
+	statsInput := internal.ServiceStatsInput{
+		Trace: c.trace,
+	}
+
+	datastoreType := arguments["datastore-type"].StringValue()
+	if datastoreType != "" {
+		datastore, ok := datastores.Datastores[datastoreType]
+		if !ok {
+			logger.Error(internal.ErrorInput{
+				Error: fmt.Errorf("datastore type %s is not supported", datastoreType),
+			})
+			return 1
+		}
+		statsInput.Datastore = datastore
+	}
+
+	serviceName := arguments["service-name"].StringValue()
+	if serviceName != "" {
+		if err := datastores.ValidateServiceName(serviceName); err != nil {
+			logger.Error(internal.ErrorInput{
+				Error: err,
+			})
+			return 1
+		}
+
+		if code := authorizeService(ctx, &logger, statsInput.Datastore, serviceName, c.trace); code != 0 {
+			return code
+		}
+
+		if !datastores.Exists(ctx, statsInput.Datastore, serviceName) {
+			logger.Error(internal.ErrorInput{
+				Error: fmt.Errorf("service %s does not exist", serviceName),
+			})
+			return 1
+		}
+		statsInput.ServiceName = serviceName
+	}
+
+	// the json envelope is emitted once, so json output is always a single snapshot
+	if c.noStream || c.format == "json" {
+		stats, err := internal.CollectServiceStats(ctx, statsInput)
+		if err != nil {
+			logger.Error(internal.ErrorInput{
+				Error: err,
+			})
+			return 1
+		}
+
+		if c.format == "json" {
+			logger.Result(stats)
+			return 0
+		}
+
+		if err := logger.Table("service stats", internal.FormatServiceStats(stats)); err != nil {
+			logger.Error(internal.ErrorInput{
+				Error: err,
+			})
+			return 1
+		}
+		return 0
+	}
+
+	for {
+		stats, err := internal.CollectServiceStats(ctx, statsInput)
+		if ctx.Err() != nil {
+			return 0
+		}
+		if err != nil {
+			logger.Error(internal.ErrorInput{
+				Error: err,
+			})
+			return 1
+		}
+
+		// clear the screen and move the cursor home before redrawing
+		fmt.Fprint(os.Stdout, "\033[H\033[2J")
+		fmt.Fprintf(os.Stdout, "%s\n\n", time.Now().Format(time.RFC3339))
+		for _, row := range internal.FormatServiceStats(stats) {
+			fmt.Fprintln(os.Stdout, row)
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0
+		case <-time.After(statsRefreshInterval):
+		}
+	}
+}
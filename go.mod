@@ -3,11 +3,13 @@ module github.com/dokku/dokku-datastore
 go 1.25.1
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/dokku/dokku/plugins/common v0.0.0-20251203045541-cc46c5ae9cb6
 	github.com/josegonzalez/cli-skeleton v0.25.0
 	github.com/mitchellh/cli v1.1.5
 	github.com/posener/complete v1.2.3
 	github.com/spf13/pflag v1.0.10
+	gopkg.in/yaml.v3 v3.0.1
 	mvdan.cc/sh/v3 v3.13.1
 )
 
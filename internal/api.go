@@ -0,0 +1,639 @@
+package internal
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dokku/dokku-datastore/internal/datastores"
+)
+
+// APIServerInput is the input for the ServeAPI function
+type APIServerInput struct {
+	// Listen is the loopback address to listen on
+	Listen string
+
+	// Socket is the path of a unix socket to listen on instead of a tcp address
+	Socket string
+
+	// Token is the bearer token required on every request
+	Token string
+}
+
+// apiServer serves the datastore json api
+type apiServer struct {
+	// ctx is the context of the server, which mutating operations run on so that a client disconnecting does
+	// not cancel them halfway through
+	ctx context.Context
+
+	// token is the bearer token required on every request
+	token string
+
+	// locks serializes mutating operations per service
+	locks sync.Map
+}
+
+// apiEvent is a single line in a streamed api response
+type apiEvent struct {
+	// Type is one of progress, result or error
+	Type string `json:"type"`
+
+	// Message is the progress message
+	Message string `json:"message,omitempty"`
+
+	// Result is the result of the operation
+	Result interface{} `json:"result,omitempty"`
+
+	// Error is the error message
+	Error string `json:"error,omitempty"`
+}
+
+// APICreateServiceRequest is the request body for creating a service
+type APICreateServiceRequest struct {
+	// Cluster is whether to shard the service over many member containers
+	Cluster bool `json:"cluster"`
+
+	// ClusterReplicas is the number of replicas of each shard of a cluster service
+	ClusterReplicas int `json:"cluster-replicas"`
+
+	// ClusterShards is the number of shards of a cluster service
+	ClusterShards int `json:"cluster-shards"`
+
+	// ConfigOptions is the configuration options to use for the service
+	ConfigOptions string `json:"config-options"`
+
+	// CustomEnv is the custom environment variables to use for the service
+	CustomEnv string `json:"custom-env"`
+
+	// HA is whether to run the service as a primary with replicas failed over by monitors
+	HA bool `json:"ha"`
+
+	// HAReplicas is the number of replica services of a high availability service
+	HAReplicas int `json:"ha-replicas"`
+
+	// Image is the image to use for the service
+	Image string `json:"image"`
+
+	// ImageVersion is the image version to use for the service
+	ImageVersion string `json:"image-version"`
+
+	// InitialNetwork is the initial network to use for the service
+	InitialNetwork string `json:"initial-network"`
+
+	// MaxmemoryPercent is the share of the memory limit the datastore may use
+	MaxmemoryPercent int `json:"maxmemory-percent"`
+
+	// MaxmemoryPolicy is the eviction policy once the datastore uses its share of the memory limit
+	MaxmemoryPolicy string `json:"maxmemory-policy"`
+
+	// Memory is the memory limit to use for the service
+	Memory int `json:"memory"`
+
+	// Password is the password to use for the service
+	Password string `json:"password"`
+
+	// Persistence is how the service persists its data
+	Persistence datastores.PersistenceSettings `json:"persistence"`
+
+	// PostCreateNetworks is the networks to attach the service container to after service creation
+	PostCreateNetworks []string `json:"post-create-networks"`
+
+	// PostStartNetworks is the networks to attach the service container to after service start
+	PostStartNetworks []string `json:"post-start-networks"`
+
+	// ReplicaOf is the name of the primary service to replicate from
+	ReplicaOf string `json:"replica-of"`
+
+	// Resources is the resource limits to use for the service
+	Resources datastores.ResourceLimits `json:"resources"`
+
+	// ShmSize is the shared memory size to use for the service
+	ShmSize string `json:"shm-size"`
+
+	// TLS is whether the service only accepts tls connections
+	TLS bool `json:"tls"`
+}
+
+// APIExposeServiceRequest is the request body for exposing a service
+type APIExposeServiceRequest struct {
+	// Allow is the networks allowed to connect to the exposed ports
+	Allow []string `json:"allow"`
+
+	// Bind is the host address to bind the exposed ports to
+	Bind string `json:"bind"`
+
+	// Ports is the ports to expose
+	Ports []string `json:"ports"`
+}
+
+// ServeAPI serves the datastore json api until the context is cancelled
+func ServeAPI(ctx context.Context, input APIServerInput) error {
+	if input.Token == "" {
+		return errors.New("an api token is required")
+	}
+
+	listener, err := apiListener(input)
+	if err != nil {
+		return err
+	}
+
+	server := &apiServer{ctx: ctx, token: input.Token}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/{type}/services", server.handleList)
+	mux.HandleFunc("GET /v1/{type}/services/{name}", server.handleInfo)
+	mux.HandleFunc("POST /v1/{type}/services/{name}", server.handleCreate)
+	mux.HandleFunc("DELETE /v1/{type}/services/{name}", server.handleDestroy)
+	mux.HandleFunc("POST /v1/{type}/services/{name}/start", server.handleStart)
+	mux.HandleFunc("POST /v1/{type}/services/{name}/stop", server.handleStop)
+	mux.HandleFunc("POST /v1/{type}/services/{name}/expose", server.handleExpose)
+	mux.HandleFunc("POST /v1/{type}/services/{name}/unexpose", server.handleUnexpose)
+	mux.HandleFunc("GET /v1/{type}/services/{name}/links", server.handleLinks)
+
+	httpServer := &http.Server{
+		Handler:           server.authenticate(mux),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx) //nolint:errcheck
+	}()
+
+	if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// apiListener opens the unix socket or loopback tcp listener for the api
+func apiListener(input APIServerInput) (net.Listener, error) {
+	if input.Socket != "" {
+		if err := os.RemoveAll(input.Socket); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket %s: %w", input.Socket, err)
+		}
+
+		listener, err := net.Listen("unix", input.Socket)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", input.Socket, err)
+		}
+		if err := os.Chmod(input.Socket, 0660); err != nil {
+			listener.Close() //nolint:errcheck
+			return nil, fmt.Errorf("failed to set permissions on %s: %w", input.Socket, err)
+		}
+		return listener, nil
+	}
+
+	host, _, err := net.SplitHostPort(input.Listen)
+	if err != nil {
+		return nil, fmt.Errorf("invalid listen address %s: %w", input.Listen, err)
+	}
+	if host != "localhost" {
+		ip := net.ParseIP(host)
+		if ip == nil || !ip.IsLoopback() {
+			return nil, fmt.Errorf("listen address %s is not a loopback address", input.Listen)
+		}
+	}
+
+	listener, err := net.Listen("tcp", input.Listen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", input.Listen, err)
+	}
+	return listener, nil
+}
+
+// authenticate rejects requests without a valid bearer token
+func (s *apiServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			writeAPIError(w, http.StatusUnauthorized, errors.New("invalid or missing api token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// lock serializes mutating operations against a single service
+func (s *apiServer) lock(datastoreType string, serviceName string) func() {
+	value, _ := s.locks.LoadOrStore(datastoreType+"/"+serviceName, &sync.Mutex{})
+	mutex := value.(*sync.Mutex)
+	mutex.Lock()
+	return mutex.Unlock
+}
+
+// lockService locks a service and checks it still exists, as another request may have destroyed it while waiting
+func (s *apiServer) lockService(w http.ResponseWriter, r *http.Request, datastore datastores.Datastore, serviceName string) (func(), bool) {
+	unlock := s.lock(datastore.ServiceType(), serviceName)
+	if !datastores.Exists(r.Context(), datastore, serviceName) {
+		unlock()
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("service %s does not exist", serviceName))
+		return nil, false
+	}
+	return unlock, true
+}
+
+// datastore resolves the datastore type from the request path
+func (s *apiServer) datastore(w http.ResponseWriter, r *http.Request) (datastores.Datastore, bool) {
+	datastoreType := r.PathValue("type")
+	datastore, ok := datastores.Datastores[datastoreType]
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("datastore type %s is not supported", datastoreType))
+		return nil, false
+	}
+	return datastore, true
+}
+
+// authorize checks that the user-auth-service trigger allows access to a service
+func (s *apiServer) authorize(w http.ResponseWriter, r *http.Request, datastore datastores.Datastore, serviceName string) bool {
+	err := datastores.AuthorizeService(r.Context(), datastores.AuthorizeServiceInput{
+		Datastore:   datastore,
+		ServiceName: serviceName,
+	})
+	if err == nil {
+		return true
+	}
+
+	if errors.Is(err, datastores.ErrAccessDenied) {
+		writeAPIError(w, http.StatusForbidden, err)
+	} else {
+		writeAPIError(w, http.StatusInternalServerError, err)
+	}
+	return false
+}
+
+// service resolves the datastore and an existing service from the request path
+func (s *apiServer) service(w http.ResponseWriter, r *http.Request) (datastores.Datastore, string, bool) {
+	datastore, ok := s.datastore(w, r)
+	if !ok {
+		return nil, "", false
+	}
+
+	serviceName := r.PathValue("name")
+	if err := datastores.ValidateServiceName(serviceName); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return nil, "", false
+	}
+
+	if !s.authorize(w, r, datastore, serviceName) {
+		return nil, "", false
+	}
+
+	if !datastores.Exists(r.Context(), datastore, serviceName) {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("service %s does not exist", serviceName))
+		return nil, "", false
+	}
+
+	return datastore, serviceName, true
+}
+
+// handleList lists all services of a datastore type
+func (s *apiServer) handleList(w http.ResponseWriter, r *http.Request) {
+	datastore, ok := s.datastore(w, r)
+	if !ok {
+		return
+	}
+
+	services, err := ListServices(r.Context(), ListServicesInput{
+		Datastore: datastore,
+	})
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeAPIResult(w, http.StatusOK, services)
+}
+
+// handleInfo returns information about a service
+func (s *apiServer) handleInfo(w http.ResponseWriter, r *http.Request) {
+	datastore, serviceName, ok := s.service(w, r)
+	if !ok {
+		return
+	}
+
+	writeAPIResult(w, http.StatusOK, datastores.Info(r.Context(), datastores.InfoInput{
+		Datastore:   datastore,
+		ServiceName: serviceName,
+	}))
+}
+
+// handleLinks lists the apps linked to a service
+func (s *apiServer) handleLinks(w http.ResponseWriter, r *http.Request) {
+	datastore, serviceName, ok := s.service(w, r)
+	if !ok {
+		return
+	}
+
+	writeAPIResult(w, http.StatusOK, datastores.LinkedApps(r.Context(), datastores.LinkedAppsInput{
+		Datastore:   datastore,
+		ServiceName: serviceName,
+	}))
+}
+
+// handleCreate creates a service and waits for it to be ready
+func (s *apiServer) handleCreate(w http.ResponseWriter, r *http.Request) {
+	datastore, ok := s.datastore(w, r)
+	if !ok {
+		return
+	}
+
+	serviceName := r.PathValue("name")
+	if err := datastores.ValidateServiceName(serviceName); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !s.authorize(w, r, datastore, serviceName) {
+		return
+	}
+
+	request := APICreateServiceRequest{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+			return
+		}
+	}
+
+	updatedFlags, err := UpdateFlagFromEnv(UpdateFlagFromEnvInput{
+		ConfigOptions: request.ConfigOptions,
+		CustomEnv:     request.CustomEnv,
+		Datastore:     datastore,
+		Image:         request.Image,
+		ImageVersion:  request.ImageVersion,
+	})
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	unlock := s.lock(datastore.ServiceType(), serviceName)
+	defer unlock()
+
+	if datastores.Exists(r.Context(), datastore, serviceName) {
+		writeAPIError(w, http.StatusConflict, fmt.Errorf("service %s already exists", serviceName))
+		return
+	}
+
+	streamAPIOperation(s.ctx, w, auditAPIOperation(r, "create", datastore, serviceName, func(ctx context.Context, progress func(string)) (interface{}, error) {
+		progress(fmt.Sprintf("Creating %s service %s", datastore.ServiceType(), serviceName))
+		err := CreateService(ctx, CreateServiceInput{
+			Cluster:            request.Cluster,
+			ClusterReplicas:    request.ClusterReplicas,
+			ClusterShards:      request.ClusterShards,
+			ConfigOptions:      updatedFlags.ConfigOptions,
+			CustomEnv:          updatedFlags.CustomEnv,
+			Datastore:          datastore,
+			HA:                 request.HA,
+			HAReplicas:         request.HAReplicas,
+			Image:              updatedFlags.Image,
+			ImageVersion:       updatedFlags.ImageVersion,
+			InitialNetwork:     request.InitialNetwork,
+			MaxmemoryPercent:   request.MaxmemoryPercent,
+			MaxmemoryPolicy:    request.MaxmemoryPolicy,
+			Memory:             request.Memory,
+			Password:           request.Password,
+			Persistence:        request.Persistence,
+			PostCreateNetworks: request.PostCreateNetworks,
+			PostStartNetworks:  request.PostStartNetworks,
+			ReplicaOf:          request.ReplicaOf,
+			Resources:          request.Resources,
+			ServiceName:        serviceName,
+			ShmSize:            request.ShmSize,
+			TLS:                request.TLS,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		progress(fmt.Sprintf("Waiting for %s container to be ready", serviceName))
+		if err := WaitForService(ctx, WaitForServiceInput{
+			Datastore:   datastore,
+			ServiceName: serviceName,
+		}); err != nil {
+			return nil, err
+		}
+
+		return datastores.Info(ctx, datastores.InfoInput{
+			Datastore:   datastore,
+			ServiceName: serviceName,
+		}), nil
+	}))
+}
+
+// handleDestroy destroys a service that has no linked apps
+func (s *apiServer) handleDestroy(w http.ResponseWriter, r *http.Request) {
+	datastore, serviceName, ok := s.service(w, r)
+	if !ok {
+		return
+	}
+
+	unlock, ok := s.lockService(w, r, datastore, serviceName)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	err := CheckDestroyService(r.Context(), DestroyServiceInput{
+		Datastore:   datastore,
+		ServiceName: serviceName,
+	})
+	if err != nil {
+		writeAPIError(w, http.StatusConflict, err)
+		return
+	}
+
+	streamAPIOperation(s.ctx, w, auditAPIOperation(r, "destroy", datastore, serviceName, func(ctx context.Context, progress func(string)) (interface{}, error) {
+		progress(fmt.Sprintf("Destroying %s service %s", datastore.ServiceType(), serviceName))
+		err := DestroyService(ctx, DestroyServiceInput{
+			Datastore:   datastore,
+			ServiceName: serviceName,
+		})
+		return nil, err
+	}))
+}
+
+// handleStart starts a service
+func (s *apiServer) handleStart(w http.ResponseWriter, r *http.Request) {
+	datastore, serviceName, ok := s.service(w, r)
+	if !ok {
+		return
+	}
+
+	unlock, ok := s.lockService(w, r, datastore, serviceName)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	streamAPIOperation(s.ctx, w, auditAPIOperation(r, "start", datastore, serviceName, func(ctx context.Context, progress func(string)) (interface{}, error) {
+		progress(fmt.Sprintf("Starting service %s", serviceName))
+		err := datastores.Start(ctx, datastores.StartInput{
+			Datastore:   datastore,
+			ServiceName: serviceName,
+		})
+		return nil, err
+	}))
+}
+
+// handleStop stops a service and removes the container
+func (s *apiServer) handleStop(w http.ResponseWriter, r *http.Request) {
+	datastore, serviceName, ok := s.service(w, r)
+	if !ok {
+		return
+	}
+
+	unlock, ok := s.lockService(w, r, datastore, serviceName)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	streamAPIOperation(s.ctx, w, auditAPIOperation(r, "stop", datastore, serviceName, func(ctx context.Context, progress func(string)) (interface{}, error) {
+		progress(fmt.Sprintf("Stopping service %s", serviceName))
+		err := datastores.RemoveServiceContainer(ctx, datastores.RemoveServiceContainerInput{
+			Datastore:   datastore,
+			ServiceName: serviceName,
+		})
+		return nil, err
+	}))
+}
+
+// handleExpose exposes a service
+func (s *apiServer) handleExpose(w http.ResponseWriter, r *http.Request) {
+	datastore, serviceName, ok := s.service(w, r)
+	if !ok {
+		return
+	}
+
+	request := APIExposeServiceRequest{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+			return
+		}
+	}
+
+	unlock, ok := s.lockService(w, r, datastore, serviceName)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	if IsExposed(datastore, serviceName) {
+		writeAPIError(w, http.StatusConflict, fmt.Errorf("service %s is already exposed", serviceName))
+		return
+	}
+
+	streamAPIOperation(s.ctx, w, auditAPIOperation(r, "expose", datastore, serviceName, func(ctx context.Context, progress func(string)) (interface{}, error) {
+		progress(fmt.Sprintf("Exposing service %s", serviceName))
+		err := ExposeService(ctx, ExposeServiceInput{
+			Allow:       request.Allow,
+			Bind:        request.Bind,
+			Datastore:   datastore,
+			Ports:       request.Ports,
+			ServiceName: serviceName,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{"exposed-ports": datastores.ExposedPorts(datastore, serviceName)}, nil
+	}))
+}
+
+// handleUnexpose unexposes a service
+func (s *apiServer) handleUnexpose(w http.ResponseWriter, r *http.Request) {
+	datastore, serviceName, ok := s.service(w, r)
+	if !ok {
+		return
+	}
+
+	unlock, ok := s.lockService(w, r, datastore, serviceName)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	streamAPIOperation(s.ctx, w, auditAPIOperation(r, "unexpose", datastore, serviceName, func(ctx context.Context, progress func(string)) (interface{}, error) {
+		progress(fmt.Sprintf("Unexposing service %s", serviceName))
+		err := UnexposeService(ctx, UnexposeServiceInput{
+			Datastore:   datastore,
+			ServiceName: serviceName,
+		})
+		return nil, err
+	}))
+}
+
+// apiOperation is a long-running operation that reports progress messages
+type apiOperation func(ctx context.Context, progress func(string)) (interface{}, error)
+
+// auditAPIOperation wraps an operation so that it is recorded in the audit log
+func auditAPIOperation(r *http.Request, command string, datastore datastores.Datastore, serviceName string, operation apiOperation) apiOperation {
+	return func(ctx context.Context, progress func(string)) (interface{}, error) {
+		started := time.Now()
+		result, err := operation(ctx, progress)
+		RecordAudit(RecordAuditInput{ //nolint:errcheck
+			Args:        []AuditArg{{Name: "method", Value: r.Method}, {Name: "path", Value: r.URL.Path}},
+			Command:     command,
+			Datastore:   datastore,
+			Error:       err,
+			ServiceName: serviceName,
+			Source:      "api",
+			Started:     started,
+		})
+		return result, err
+	}
+}
+
+// streamAPIOperation runs a long-running operation, streaming newline-delimited json events
+//
+// The 200 status is sent before the operation starts, so handlers reject requests they can tell will fail with a
+// real status code first, and a failure of the operation itself is only reported by the trailing error event
+func streamAPIOperation(ctx context.Context, w http.ResponseWriter, operation apiOperation) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	emit := func(event apiEvent) {
+		encoder.Encode(event) //nolint:errcheck
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	result, err := operation(ctx, func(message string) {
+		emit(apiEvent{Type: "progress", Message: message})
+	})
+	if err != nil {
+		emit(apiEvent{Type: "error", Error: err.Error()})
+		return
+	}
+	emit(apiEvent{Type: "result", Result: result})
+}
+
+// writeAPIResult writes a json result
+func writeAPIResult(w http.ResponseWriter, status int, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"result": result}) //nolint:errcheck
+}
+
+// writeAPIError writes a json error
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()}) //nolint:errcheck
+}
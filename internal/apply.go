@@ -0,0 +1,617 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/dokku/dokku-datastore/internal/datastores"
+	"github.com/dokku/dokku/plugins/common"
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest is a declarative list of services
+type Manifest struct {
+	// Services is the list of services to converge
+	Services []ManifestService `yaml:"services" toml:"services"`
+}
+
+// ManifestService is the desired state of a single service
+type ManifestService struct {
+	// Name is the name of the service
+	Name string `yaml:"name" toml:"name"`
+
+	// Type is the datastore type of the service
+	Type string `yaml:"type" toml:"type"`
+
+	// Image is the image to use for the service
+	Image string `yaml:"image" toml:"image"`
+
+	// ImageVersion is the image version to use for the service
+	ImageVersion string `yaml:"image-version" toml:"image-version"`
+
+	// Memory is the memory limit in megabytes
+	Memory int `yaml:"memory" toml:"memory"`
+
+	// ShmSize is the shared memory size
+	ShmSize string `yaml:"shm-size" toml:"shm-size"`
+
+	// Resources is the container resource limits beyond memory
+	Resources datastores.ResourceLimits `yaml:"resources" toml:"resources"`
+
+	// ConfigOptions is the extra arguments to pass to the container create command
+	ConfigOptions string `yaml:"config-options" toml:"config-options"`
+
+	// CustomEnv is the custom environment variables to start the service with
+	CustomEnv map[string]string `yaml:"custom-env" toml:"custom-env"`
+
+	// InitialNetwork is the initial network to attach the service to
+	InitialNetwork string `yaml:"initial-network" toml:"initial-network"`
+
+	// PostCreateNetworks is the networks to attach the service container to after service creation
+	PostCreateNetworks []string `yaml:"post-create-networks" toml:"post-create-networks"`
+
+	// PostStartNetworks is the networks to attach the service container to after service start
+	PostStartNetworks []string `yaml:"post-start-networks" toml:"post-start-networks"`
+
+	// ExposedPorts is the host ports to expose the service on, in datastore port order
+	ExposedPorts []string `yaml:"exposed-ports" toml:"exposed-ports"`
+
+	// ExposeAllow is the networks allowed to connect to the exposed ports
+	ExposeAllow []string `yaml:"expose-allow" toml:"expose-allow"`
+
+	// ExposeBind is the host address to bind the exposed ports to
+	ExposeBind string `yaml:"expose-bind" toml:"expose-bind"`
+
+	// Links is the apps to link the service to, where nil leaves the links unchanged and an empty list unlinks every app
+	Links *[]string `yaml:"links" toml:"links"`
+}
+
+// LoadManifest reads a yaml or toml manifest from disk
+func LoadManifest(path string) (Manifest, error) {
+	manifest := Manifest{}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return manifest, fmt.Errorf("failed to read manifest %s: %w", path, err)
+	}
+
+	if strings.ToLower(filepath.Ext(path)) == ".toml" {
+		if err := toml.Unmarshal(content, &manifest); err != nil {
+			return manifest, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+		}
+	} else {
+		if err := yaml.Unmarshal(content, &manifest); err != nil {
+			return manifest, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, service := range manifest.Services {
+		if _, ok := datastores.Datastores[service.Type]; !ok {
+			return manifest, fmt.Errorf("service %s: datastore type %s is not supported", service.Name, service.Type)
+		}
+		if err := datastores.ValidateServiceName(service.Name); err != nil {
+			return manifest, fmt.Errorf("service %s: %w", service.Name, err)
+		}
+
+		key := service.Type + "/" + service.Name
+		if seen[key] {
+			return manifest, fmt.Errorf("%s service %s is defined more than once", service.Type, service.Name)
+		}
+		seen[key] = true
+	}
+
+	return manifest, nil
+}
+
+// ApplyAction is a single step needed to converge a service
+type ApplyAction struct {
+	// Action is one of create, update, expose, unexpose, link, unlink or destroy
+	Action string `json:"action"`
+
+	// AppName is the app to link or unlink
+	AppName string `json:"app,omitempty"`
+
+	// Datastore is the datastore of the service
+	Datastore datastores.Datastore `json:"-"`
+
+	// Reason describes why the action is needed
+	Reason string `json:"reason,omitempty"`
+
+	// Service is the desired state of the service
+	Service ManifestService `json:"-"`
+
+	// ServiceName is the name of the service
+	ServiceName string `json:"service"`
+
+	// Type is the datastore type of the service
+	Type string `json:"type"`
+}
+
+// String returns a human readable description of the action
+func (a ApplyAction) String() string {
+	description := fmt.Sprintf("%-8s %s %s", a.Action, a.Type, a.ServiceName)
+	if a.AppName != "" {
+		description = fmt.Sprintf("%s -> %s", description, a.AppName)
+	}
+	if a.Reason != "" {
+		description = fmt.Sprintf("%s (%s)", description, a.Reason)
+	}
+	return description
+}
+
+// PlanApplyInput is the input for the PlanApply function
+type PlanApplyInput struct {
+	// Manifest is the desired state
+	Manifest Manifest
+
+	// Prune is whether to destroy services that are not in the manifest
+	Prune bool
+}
+
+// PlanApply computes the actions needed to converge the host to the manifest
+func PlanApply(ctx context.Context, input PlanApplyInput) ([]ApplyAction, error) {
+	actions := []ApplyAction{}
+	for _, service := range input.Manifest.Services {
+		datastore := datastores.Datastores[service.Type]
+		err := datastores.AuthorizeService(ctx, datastores.AuthorizeServiceInput{
+			Datastore:   datastore,
+			ServiceName: service.Name,
+		})
+		if err != nil {
+			return actions, err
+		}
+
+		base := ApplyAction{
+			Datastore:   datastore,
+			Service:     service,
+			ServiceName: service.Name,
+			Type:        service.Type,
+		}
+
+		exists := datastores.Exists(ctx, datastore, service.Name)
+		if !exists {
+			action := base
+			action.Action = "create"
+			actions = append(actions, action)
+		} else if changes := serviceConfigChanges(datastore, service); len(changes) > 0 {
+			action := base
+			action.Action = "update"
+			action.Reason = strings.Join(changes, ", ")
+			actions = append(actions, action)
+		}
+
+		currentPorts := []string{}
+		if exists {
+			currentPorts = strings.Fields(common.ReadFirstLine(datastores.Files(datastore, service.Name).Port))
+		}
+		if len(service.ExposedPorts) == 0 && len(currentPorts) > 0 {
+			action := base
+			action.Action = "unexpose"
+			actions = append(actions, action)
+		} else if len(service.ExposedPorts) > 0 && !slices.Equal(service.ExposedPorts, currentPorts) {
+			action := base
+			action.Action = "expose"
+			action.Reason = "ports " + strings.Join(service.ExposedPorts, ",")
+			actions = append(actions, action)
+		} else if len(service.ExposedPorts) > 0 && exposeSettingsChanged(datastore, service) {
+			action := base
+			action.Action = "expose"
+			action.Reason = "bind or allowed networks"
+			actions = append(actions, action)
+		}
+
+		if service.Links == nil {
+			continue
+		}
+
+		currentLinks := []string{}
+		if exists {
+			currentLinks = datastores.LinkedApps(ctx, datastores.LinkedAppsInput{
+				Datastore:   datastore,
+				ServiceName: service.Name,
+			})
+		}
+		for _, appName := range *service.Links {
+			if !slices.Contains(currentLinks, appName) {
+				action := base
+				action.Action = "link"
+				action.AppName = appName
+				actions = append(actions, action)
+			}
+		}
+		for _, appName := range currentLinks {
+			if !slices.Contains(*service.Links, appName) {
+				action := base
+				action.Action = "unlink"
+				action.AppName = appName
+				actions = append(actions, action)
+			}
+		}
+	}
+
+	if !input.Prune {
+		return actions, nil
+	}
+
+	datastoreTypes := []string{}
+	for datastoreType := range datastores.Datastores {
+		datastoreTypes = append(datastoreTypes, datastoreType)
+	}
+	sort.Strings(datastoreTypes)
+
+	for _, datastoreType := range datastoreTypes {
+		datastore := datastores.Datastores[datastoreType]
+		services, err := ListServices(ctx, ListServicesInput{
+			Datastore: datastore,
+		})
+		if err != nil {
+			return actions, err
+		}
+
+		existing := []prunableService{}
+		for _, serviceName := range services {
+			existing = append(existing, prunableService{
+				HAMemberOf: datastores.HAMemberOf(datastore, serviceName),
+				Name:       serviceName,
+				ReplicaOf:  datastores.ReplicaOf(datastore, serviceName),
+			})
+		}
+
+		pruned, err := planPrune(input.Manifest, datastore, existing)
+		if err != nil {
+			return actions, err
+		}
+		actions = append(actions, pruned...)
+	}
+
+	return actions, nil
+}
+
+// prunableService is a service on the host that prune may destroy
+type prunableService struct {
+	// HAMemberOf is the high availability service the service is a member of
+	HAMemberOf string
+
+	// Name is the name of the service
+	Name string
+
+	// ReplicaOf is the primary the service replicates from
+	ReplicaOf string
+}
+
+// planPrune returns the destroy actions for services of a datastore that are not in the manifest
+//
+// High availability members are destroyed along with the service they belong to, and replicas of a primary in
+// the manifest are left alone, so neither is pruned. Replicas are destroyed before their primaries, which cannot
+// be destroyed while they have replicas.
+func planPrune(manifest Manifest, datastore datastores.Datastore, services []prunableService) ([]ApplyAction, error) {
+	datastoreType := datastore.ServiceType()
+	listed := func(serviceName string) bool {
+		return slices.ContainsFunc(manifest.Services, func(service ManifestService) bool {
+			return service.Type == datastoreType && service.Name == serviceName
+		})
+	}
+
+	replicas := []ApplyAction{}
+	primaries := []ApplyAction{}
+	for _, service := range services {
+		if listed(service.Name) || service.HAMemberOf != "" {
+			continue
+		}
+		if service.ReplicaOf != "" && listed(service.ReplicaOf) {
+			continue
+		}
+
+		for _, replica := range services {
+			if replica.ReplicaOf == service.Name && replica.HAMemberOf == "" && listed(replica.Name) {
+				return nil, fmt.Errorf("cannot prune %s service %s, its replica %s is in the manifest", datastoreType, service.Name, replica.Name)
+			}
+		}
+
+		action := ApplyAction{
+			Action:      "destroy",
+			Datastore:   datastore,
+			Reason:      "not in manifest",
+			ServiceName: service.Name,
+			Type:        datastoreType,
+		}
+		if service.ReplicaOf != "" {
+			replicas = append(replicas, action)
+		} else {
+			primaries = append(primaries, action)
+		}
+	}
+
+	return append(replicas, primaries...), nil
+}
+
+// exposeSettingsChanged checks if the bind address or allowlist on disk differ from the manifest
+func exposeSettingsChanged(s datastores.Datastore, service ManifestService) bool {
+	if datastores.ExposeBind(s, service.Name) != service.ExposeBind {
+		return true
+	}
+
+	allowList := []string{}
+	for _, network := range service.ExposeAllow {
+		normalized, err := datastores.NormalizeAllowedNetwork(network)
+		if err != nil {
+			return true
+		}
+		allowList = append(allowList, normalized)
+	}
+	return !slices.Equal(allowList, datastores.ExposeAllowList(s, service.Name))
+}
+
+// serviceConfigChanges returns the settings that differ between a service on disk and the manifest
+func serviceConfigChanges(s datastores.Datastore, service ManifestService) []string {
+	properties := s.Properties()
+	serviceFiles := datastores.Files(s, service.Name)
+	changes := []string{}
+
+	currentImage := common.ReadFirstLine(serviceFiles.Image)
+	if currentImage == "" {
+		currentImage = properties.DefaultImage
+	}
+	if desired := manifestImage(s, service); desired != currentImage {
+		changes = append(changes, fmt.Sprintf("image %s -> %s", currentImage, desired))
+	}
+
+	currentImageVersion := common.ReadFirstLine(serviceFiles.ImageVersion)
+	if currentImageVersion == "" {
+		currentImageVersion = properties.DefaultImageVersion
+	}
+	if desired := manifestImageVersion(s, service); desired != currentImageVersion {
+		changes = append(changes, fmt.Sprintf("image-version %s -> %s", currentImageVersion, desired))
+	}
+
+	currentMemory, _ := strconv.Atoi(common.ReadFirstLine(serviceFiles.Memory))
+	if currentMemory != service.Memory {
+		changes = append(changes, fmt.Sprintf("memory %d -> %d", currentMemory, service.Memory))
+	}
+
+	if current := common.ReadFirstLine(serviceFiles.ShmSize); current != service.ShmSize {
+		changes = append(changes, "shm-size")
+	}
+
+	if current := datastores.ReadResourceLimits(s, service.Name).String(); current != service.Resources.String() {
+		changes = append(changes, fmt.Sprintf("resources %q -> %q", current, service.Resources.String()))
+	}
+
+	if current := common.ReadFirstLine(serviceFiles.ConfigOptions); current != service.ConfigOptions {
+		changes = append(changes, "config-options")
+	}
+
+	currentEnv, _ := common.FileToSlice(serviceFiles.Env)
+	sort.Strings(currentEnv)
+	if strings.Join(currentEnv, ";") != manifestCustomEnv(service) {
+		changes = append(changes, "custom-env")
+	}
+
+	if current := datastores.InitialNetwork(s, service.Name); current != service.InitialNetwork {
+		changes = append(changes, "initial-network")
+	}
+
+	if current := datastores.PostCreateNetwork(s, service.Name); current != strings.Join(service.PostCreateNetworks, ",") {
+		changes = append(changes, "post-create-networks")
+	}
+
+	if current := datastores.PostStartNetwork(s, service.Name); current != strings.Join(service.PostStartNetworks, ",") {
+		changes = append(changes, "post-start-networks")
+	}
+
+	return changes
+}
+
+// manifestImage returns the desired image for a service
+func manifestImage(s datastores.Datastore, service ManifestService) string {
+	if service.Image != "" {
+		return service.Image
+	}
+	return s.Properties().DefaultImage
+}
+
+// manifestImageVersion returns the desired image version for a service
+func manifestImageVersion(s datastores.Datastore, service ManifestService) string {
+	if service.ImageVersion != "" {
+		return service.ImageVersion
+	}
+	return s.Properties().DefaultImageVersion
+}
+
+// manifestCustomEnv returns the custom env for a service in the semi-colon delimited on-disk format
+func manifestCustomEnv(service ManifestService) string {
+	env := []string{}
+	for key, value := range service.CustomEnv {
+		env = append(env, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(env)
+	return strings.Join(env, ";")
+}
+
+// ApplyInput is the input for the Apply function
+type ApplyInput struct {
+	// Actions is the planned actions to execute
+	Actions []ApplyAction
+
+	// NoRestart is whether to skip restarting apps when linking or unlinking
+	NoRestart bool
+
+	// Progress is called before each action is executed
+	Progress func(action ApplyAction)
+}
+
+// Apply executes planned actions in order, stopping at the first failure
+func Apply(ctx context.Context, input ApplyInput) error {
+	for _, action := range input.Actions {
+		if input.Progress != nil {
+			input.Progress(action)
+		}
+
+		started := time.Now()
+		err := applyAction(ctx, action, input.NoRestart)
+
+		args := []AuditArg{
+			{Name: "datastore-type", Value: action.Type},
+			{Name: "service-name", Value: action.ServiceName},
+		}
+		if action.AppName != "" {
+			args = append(args, AuditArg{Name: "app-name", Value: action.AppName})
+		}
+		RecordAudit(RecordAuditInput{ //nolint:errcheck
+			Args:        args,
+			Command:     action.Action,
+			Datastore:   action.Datastore,
+			Error:       err,
+			ServiceName: action.ServiceName,
+			Source:      "apply",
+			Started:     started,
+		})
+
+		if err != nil {
+			return fmt.Errorf("failed to %s %s service %s: %w", action.Action, action.Type, action.ServiceName, err)
+		}
+	}
+
+	return nil
+}
+
+// applyAction executes a single planned action
+func applyAction(ctx context.Context, action ApplyAction, noRestart bool) error {
+	datastore := action.Datastore
+	service := action.Service
+	switch action.Action {
+	case "create":
+		err := CreateService(ctx, CreateServiceInput{
+			ConfigOptions:      service.ConfigOptions,
+			CustomEnv:          manifestCustomEnv(service),
+			Datastore:          datastore,
+			Image:              manifestImage(datastore, service),
+			ImageVersion:       manifestImageVersion(datastore, service),
+			InitialNetwork:     service.InitialNetwork,
+			Memory:             service.Memory,
+			PostCreateNetworks: service.PostCreateNetworks,
+			PostStartNetworks:  service.PostStartNetworks,
+			Resources:          service.Resources,
+			ServiceName:        service.Name,
+			ShmSize:            service.ShmSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		return WaitForService(ctx, WaitForServiceInput{
+			Datastore:   datastore,
+			ServiceName: service.Name,
+		})
+	case "update":
+		return updateService(ctx, datastore, service)
+	case "expose":
+		if IsExposed(datastore, service.Name) {
+			if err := UnexposeService(ctx, UnexposeServiceInput{
+				Datastore:   datastore,
+				ServiceName: service.Name,
+			}); err != nil {
+				return err
+			}
+		}
+
+		return ExposeService(ctx, ExposeServiceInput{
+			Allow:       service.ExposeAllow,
+			Bind:        service.ExposeBind,
+			Datastore:   datastore,
+			Ports:       service.ExposedPorts,
+			ServiceName: service.Name,
+		})
+	case "unexpose":
+		return UnexposeService(ctx, UnexposeServiceInput{
+			Datastore:   datastore,
+			ServiceName: service.Name,
+		})
+	case "link":
+		return LinkService(ctx, LinkServiceInput{
+			AppName:     action.AppName,
+			Datastore:   datastore,
+			NoRestart:   noRestart,
+			ServiceName: service.Name,
+		})
+	case "unlink":
+		return UnlinkService(ctx, UnlinkServiceInput{
+			AppName:     action.AppName,
+			Datastore:   datastore,
+			NoRestart:   noRestart,
+			ServiceName: service.Name,
+		})
+	case "destroy":
+		return DestroyService(ctx, DestroyServiceInput{
+			Datastore:   datastore,
+			ServiceName: action.ServiceName,
+		})
+	}
+
+	return fmt.Errorf("unknown action %s", action.Action)
+}
+
+// updateService rewrites the service config and recreates the service container
+func updateService(ctx context.Context, datastore datastores.Datastore, service ManifestService) error {
+	image := manifestImage(datastore, service)
+	imageVersion := manifestImageVersion(datastore, service)
+	if err := EnsureTaggedImage(ctx, EnsureTaggedImageInput{
+		Datastore:   datastore,
+		ServiceName: service.Name,
+		TaggedImage: fmt.Sprintf("%s:%s", image, imageVersion),
+	}); err != nil {
+		return err
+	}
+
+	err := datastores.CommitServiceConfig(datastores.CommitServiceConfigInput{
+		ConfigOptions:      service.ConfigOptions,
+		CustomEnv:          manifestCustomEnv(service),
+		Datastore:          datastore,
+		Image:              image,
+		ImageVersion:       imageVersion,
+		InitialNetwork:     service.InitialNetwork,
+		Memory:             service.Memory,
+		PostCreateNetworks: service.PostCreateNetworks,
+		PostStartNetworks:  service.PostStartNetworks,
+		Resources:          service.Resources,
+		ServiceName:        service.Name,
+		ShmSize:            service.ShmSize,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to commit service config: %w", err)
+	}
+
+	// maxmemory follows the committed MEMORY limit so the recreated container is not capped below the datastore
+	if err := datastores.ConfigureMaxmemory(ctx, datastore, service.Name); err != nil {
+		return err
+	}
+
+	err = datastores.RemoveServiceContainer(ctx, datastores.RemoveServiceContainerInput{
+		Datastore:   datastore,
+		ServiceName: service.Name,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = datastores.Start(ctx, datastores.StartInput{
+		Datastore:   datastore,
+		ServiceName: service.Name,
+	})
+	if err != nil {
+		return err
+	}
+
+	return WaitForService(ctx, WaitForServiceInput{
+		Datastore:   datastore,
+		ServiceName: service.Name,
+	})
+}
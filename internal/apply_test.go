@@ -0,0 +1,191 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dokku/dokku-datastore/internal/datastores"
+)
+
+func TestLoadManifest(t *testing.T) {
+	tests := []struct {
+		name      string
+		filename  string
+		content   string
+		wantErr   bool
+		wantLinks []*[]string
+	}{
+		{
+			name:      "yaml without links",
+			filename:  "services.yml",
+			content:   "services:\n  - name: cache\n    type: redis\n",
+			wantLinks: []*[]string{nil},
+		},
+		{
+			name:      "yaml with empty links",
+			filename:  "services.yml",
+			content:   "services:\n  - name: cache\n    type: redis\n    links: []\n",
+			wantLinks: []*[]string{{}},
+		},
+		{
+			name:      "yaml with links",
+			filename:  "services.yaml",
+			content:   "services:\n  - name: cache\n    type: redis\n    links: [web]\n",
+			wantLinks: []*[]string{{"web"}},
+		},
+		{
+			name:      "toml without links",
+			filename:  "services.toml",
+			content:   "[[services]]\nname = \"cache\"\ntype = \"redis\"\n",
+			wantLinks: []*[]string{nil},
+		},
+		{
+			name:      "toml with empty links",
+			filename:  "services.TOML",
+			content:   "[[services]]\nname = \"cache\"\ntype = \"redis\"\nlinks = []\n",
+			wantLinks: []*[]string{{}},
+		},
+		{
+			name:     "unsupported type",
+			filename: "services.yml",
+			content:  "services:\n  - name: cache\n    type: nosuch\n",
+			wantErr:  true,
+		},
+		{
+			name:     "invalid service name",
+			filename: "services.yml",
+			content:  "services:\n  - name: cache.1\n    type: redis\n",
+			wantErr:  true,
+		},
+		{
+			name:     "duplicate service",
+			filename: "services.yml",
+			content:  "services:\n  - name: cache\n    type: redis\n  - name: cache\n    type: redis\n",
+			wantErr:  true,
+		},
+		{
+			name:     "invalid yaml",
+			filename: "services.yml",
+			content:  "services: [\n",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.filename)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			manifest, err := LoadManifest(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("LoadManifest() returned no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadManifest() returned %v", err)
+			}
+
+			links := []*[]string{}
+			for _, service := range manifest.Services {
+				links = append(links, service.Links)
+			}
+			if !reflect.DeepEqual(links, tt.wantLinks) {
+				t.Errorf("LoadManifest() links = %v, want %v", links, tt.wantLinks)
+			}
+		})
+	}
+}
+
+func TestPlanPrune(t *testing.T) {
+	datastore := datastores.Datastores["redis"]
+	manifest := Manifest{Services: []ManifestService{
+		{Name: "cache", Type: "redis"},
+		{Name: "sessions", Type: "redis"},
+		{Name: "kept", Type: "memcached"},
+	}}
+
+	tests := []struct {
+		name     string
+		services []prunableService
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "listed services are kept",
+			services: []prunableService{{Name: "cache"}, {Name: "sessions"}},
+			want:     []string{},
+		},
+		{
+			name:     "unlisted services are destroyed",
+			services: []prunableService{{Name: "cache"}, {Name: "old"}, {Name: "kept"}},
+			want:     []string{"old", "kept"},
+		},
+		{
+			name: "high availability members are skipped",
+			services: []prunableService{
+				{Name: "cache"},
+				{Name: "cache-replica-1", HAMemberOf: "cache", ReplicaOf: "cache"},
+				{Name: "old"},
+				{Name: "old-replica-1", HAMemberOf: "old", ReplicaOf: "old"},
+			},
+			want: []string{"old"},
+		},
+		{
+			name: "replicas of a listed primary are skipped",
+			services: []prunableService{
+				{Name: "cache"},
+				{Name: "cache-read", ReplicaOf: "cache"},
+			},
+			want: []string{},
+		},
+		{
+			name: "replicas are destroyed before their primary",
+			services: []prunableService{
+				{Name: "cache"},
+				{Name: "old"},
+				{Name: "old-read", ReplicaOf: "old"},
+			},
+			want: []string{"old-read", "old"},
+		},
+		{
+			name: "primary of a listed replica",
+			services: []prunableService{
+				{Name: "old"},
+				{Name: "sessions", ReplicaOf: "old"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actions, err := planPrune(manifest, datastore, tt.services)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("planPrune() returned no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("planPrune() returned %v", err)
+			}
+
+			got := []string{}
+			for _, action := range actions {
+				if action.Action != "destroy" || action.Type != "redis" {
+					t.Errorf("planPrune() planned %s of %s service %s", action.Action, action.Type, action.ServiceName)
+				}
+				got = append(got, action.ServiceName)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("planPrune() destroys %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
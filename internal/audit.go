@@ -0,0 +1,307 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dokku/dokku-datastore/internal/datastores"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// AuditEntry is a single entry in the audit log
+type AuditEntry struct {
+	// Timestamp is when the operation started
+	Timestamp time.Time `json:"timestamp"`
+
+	// SSHUser is the ssh user that ran the operation
+	SSHUser string `json:"ssh-user"`
+
+	// SSHName is the name of the ssh key that ran the operation
+	SSHName string `json:"ssh-name"`
+
+	// Source is where the operation was run from, one of cli, api or apply
+	Source string `json:"source"`
+
+	// Command is the operation that was run
+	Command string `json:"command"`
+
+	// Args is the arguments the operation was run with, with secrets redacted
+	Args []string `json:"args"`
+
+	// Type is the datastore type of the service
+	Type string `json:"type,omitempty"`
+
+	// Service is the name of the service
+	Service string `json:"service,omitempty"`
+
+	// DurationMs is how long the operation took in milliseconds
+	DurationMs int64 `json:"duration-ms"`
+
+	// Result is one of success or failure
+	Result string `json:"result"`
+
+	// ExitCode is the exit code of the operation
+	ExitCode int `json:"exit-code"`
+
+	// Error is the error the operation failed with, if known
+	Error string `json:"error,omitempty"`
+}
+
+// RecordAuditInput is the input for the RecordAudit function
+type RecordAuditInput struct {
+	// Args is the named arguments and flags the operation was run with
+	Args []AuditArg
+
+	// Command is the operation that was run
+	Command string
+
+	// Datastore is the datastore of the service, if any
+	Datastore datastores.Datastore
+
+	// Error is the error the operation failed with
+	Error error
+
+	// ExitCode is the exit code of the operation
+	ExitCode int
+
+	// ServiceName is the name of the service, if any
+	ServiceName string
+
+	// Source is where the operation was run from
+	Source string
+
+	// Started is when the operation started
+	Started time.Time
+}
+
+// AuditArg is a named argument or flag an operation was run with
+type AuditArg struct {
+	// Flag is whether the value was given as a flag rather than a positional argument
+	Flag bool
+
+	// Name is the name of the argument or flag
+	Name string
+
+	// Value is the value of the argument or flag
+	Value string
+}
+
+// auditLoggableArgs is the names of the arguments and flags whose values are written to the audit log as given,
+// every other value is redacted so a secret passed in an unforeseen argument never reaches the log
+var auditLoggableArgs = map[string]bool{
+	"acl":                 true,
+	"allow":               true,
+	"app-name":            true,
+	"appendfsync":         true,
+	"apparmor-profile":    true,
+	"bind":                true,
+	"blkio-weight":        true,
+	"cluster":             true,
+	"cpu-shares":          true,
+	"cpus":                true,
+	"cpuset-cpus":         true,
+	"datastore-type":      true,
+	"directive":           true,
+	"dry-run":             true,
+	"enabled":             true,
+	"file":                true,
+	"force":               true,
+	"format":              true,
+	"ha":                  true,
+	"ha-replicas":         true,
+	"image":               true,
+	"image-version":       true,
+	"initial-network":     true,
+	"keep-previous":       true,
+	"maxmemory-percent":   true,
+	"maxmemory-policy":    true,
+	"memory":              true,
+	"memory-reservation":  true,
+	"memory-swap":         true,
+	"method":              true,
+	"mode":                true,
+	"no-restart":          true,
+	"path":                true,
+	"persistence":         true,
+	"pids-limit":          true,
+	"ports":               true,
+	"post-create-network": true,
+	"post-start-network":  true,
+	"primary-name":        true,
+	"prune":               true,
+	"quiet":               true,
+	"replica-of":          true,
+	"replicas":            true,
+	"revoke-previous":     true,
+	"save":                true,
+	"seccomp-profile":     true,
+	"service-name":        true,
+	"shards":              true,
+	"shm-size":            true,
+	"tls":                 true,
+	"trace":               true,
+	"ulimit":              true,
+	"user":                true,
+}
+
+// AuditLogPath returns the path to the global audit log
+func AuditLogPath() string {
+	return filepath.Join(datastores.PluginDataRoot, "AUDIT_LOG")
+}
+
+// RecordAudit appends an entry to the global audit log and to the audit log of the service
+func RecordAudit(input RecordAuditInput) error {
+	sshUser, sshName := datastores.SSHIdentity()
+	entry := AuditEntry{
+		Timestamp:  input.Started.UTC(),
+		SSHUser:    sshUser,
+		SSHName:    sshName,
+		Source:     input.Source,
+		Command:    input.Command,
+		Args:       redactAuditArgs(input.Args),
+		Service:    input.ServiceName,
+		DurationMs: time.Since(input.Started).Milliseconds(),
+		Result:     "success",
+		ExitCode:   input.ExitCode,
+	}
+	if input.Datastore != nil {
+		entry.Type = input.Datastore.ServiceType()
+	}
+	if input.Error != nil {
+		entry.Error = input.Error.Error()
+		if entry.ExitCode == 0 {
+			entry.ExitCode = 1
+		}
+	}
+	if entry.ExitCode != 0 {
+		entry.Result = "failure"
+	}
+
+	err := appendAuditEntry(AuditLogPath(), entry)
+
+	// destroyed services no longer have a service root, so only the global log records them
+	if input.Datastore == nil || datastores.ValidateServiceName(input.ServiceName) != nil {
+		return err
+	}
+	if !common.DirectoryExists(datastores.Folders(input.Datastore, input.ServiceName).Root) {
+		return err
+	}
+
+	return errors.Join(err, appendAuditEntry(datastores.Files(input.Datastore, input.ServiceName).AuditLog, entry))
+}
+
+// ReadAuditLogInput is the input for the ReadAuditLog function
+type ReadAuditLogInput struct {
+	// Datastore is the datastore of the service
+	Datastore datastores.Datastore
+
+	// Num is the number of most recent entries to return, where 0 returns every entry
+	Num int
+
+	// ServiceName is the name of the service
+	ServiceName string
+}
+
+// ReadAuditLog returns the audit log entries for a service, oldest first
+func ReadAuditLog(input ReadAuditLogInput) ([]AuditEntry, error) {
+	path := datastores.Files(input.Datastore, input.ServiceName).AuditLog
+	filter := false
+	if !common.FileExists(path) {
+		path = AuditLogPath()
+		filter = true
+	}
+
+	entries := []AuditEntry{}
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entries, nil
+		}
+		return entries, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		entry := AuditEntry{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			continue
+		}
+		if filter && (entry.Type != input.Datastore.ServiceType() || entry.Service != input.ServiceName) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return entries, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	if input.Num > 0 && len(entries) > input.Num {
+		entries = entries[len(entries)-input.Num:]
+	}
+
+	return entries, nil
+}
+
+// appendAuditEntry appends a single json line to an audit log
+func appendAuditEntry(path string, entry AuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+
+	created := !common.FileExists(path)
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log %s: %w", path, err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log %s: %w", path, err)
+	}
+
+	if created {
+		err := common.SetPermissions(common.SetPermissionInput{
+			Filename:  path,
+			GroupName: datastores.SystemGroup(),
+			Mode:      0640,
+			Username:  datastores.SystemUser(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set permissions on audit log %s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+// redactAuditArgs formats arguments and flags for the audit log, redacting the value of any not known to be safe
+func redactAuditArgs(args []AuditArg) []string {
+	redacted := []string{}
+	for _, arg := range args {
+		value := arg.Value
+		if !auditLoggableArgs[arg.Name] {
+			value = "[REDACTED]"
+		}
+
+		if arg.Flag {
+			redacted = append(redacted, fmt.Sprintf("--%s=%s", arg.Name, value))
+		} else {
+			redacted = append(redacted, value)
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRedactAuditArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []AuditArg
+		want []string
+	}{
+		{name: "empty", args: []AuditArg{}, want: []string{}},
+		{
+			name: "service arguments",
+			args: []AuditArg{
+				{Name: "datastore-type", Value: "redis"},
+				{Name: "service-name", Value: "lollipop"},
+				{Name: "app-name", Value: "my-app"},
+			},
+			want: []string{"redis", "lollipop", "my-app"},
+		},
+		{
+			name: "safe flags",
+			args: []AuditArg{
+				{Flag: true, Name: "memory", Value: "512"},
+				{Flag: true, Name: "allow", Value: "[10.0.0.0/8]"},
+			},
+			want: []string{"--memory=512", "--allow=[10.0.0.0/8]"},
+		},
+		{
+			name: "secret flags",
+			args: []AuditArg{
+				{Flag: true, Name: "password", Value: "hunter2"},
+				{Flag: true, Name: "root-password", Value: "hunter2"},
+				{Flag: true, Name: "custom-env", Value: "SECRET=hunter2"},
+				{Flag: true, Name: "config-options", Value: "--requirepass hunter2"},
+			},
+			want: []string{"--password=[REDACTED]", "--root-password=[REDACTED]", "--custom-env=[REDACTED]", "--config-options=[REDACTED]"},
+		},
+		{
+			name: "directive value",
+			args: []AuditArg{
+				{Name: "datastore-type", Value: "redis"},
+				{Name: "service-name", Value: "lollipop"},
+				{Name: "directive", Value: "masterauth"},
+				{Name: "value", Value: "hunter2"},
+			},
+			want: []string{"redis", "lollipop", "masterauth", "[REDACTED]"},
+		},
+		{
+			name: "unknown names",
+			args: []AuditArg{
+				{Name: "argument", Value: "hunter2"},
+				{Flag: true, Name: "token", Value: "hunter2"},
+			},
+			want: []string{"[REDACTED]", "--token=[REDACTED]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactAuditArgs(tt.args); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("redactAuditArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dokku/dokku-datastore/internal/datastores"
+)
+
+// DefaultClusterShards is the number of shards of a cluster service when none is given
+var DefaultClusterShards = 3
+
+// prepareClusterService validates a cluster service and moves it onto its dedicated network
+//
+// A requested initial network is attached after creation instead, along with the other networks
+func prepareClusterService(input *CreateServiceInput) error {
+	if _, ok := input.Datastore.(datastores.ClusterManager); !ok {
+		return fmt.Errorf("%s services do not support clustering", input.Datastore.ServiceType())
+	}
+
+	if input.HA {
+		return fmt.Errorf("cluster services cannot also be high availability services")
+	}
+
+	if input.ReplicaOf != "" {
+		return fmt.Errorf("cluster services cannot replicate from another service")
+	}
+
+	if input.TLS {
+		return fmt.Errorf("cluster services do not support tls yet")
+	}
+
+	if input.ClusterShards == 0 {
+		input.ClusterShards = DefaultClusterShards
+	}
+	if input.ClusterShards < 3 {
+		return fmt.Errorf("cluster services need at least 3 shards")
+	}
+	if input.ClusterReplicas < 0 {
+		return fmt.Errorf("the number of replicas per shard cannot be negative")
+	}
+
+	if input.InitialNetwork != "" {
+		input.PostCreateNetworks = append([]string{input.InitialNetwork}, input.PostCreateNetworks...)
+	}
+	input.InitialNetwork = datastores.ClusterNetwork(input.Datastore, input.ServiceName)
+	return nil
+}
+
+// configureClusterMembers records the members of a cluster service and configures them before their containers are created
+func configureClusterMembers(ctx context.Context, input CreateServiceInput) error {
+	err := datastores.WriteClusterMembers(datastores.WriteClusterMembersInput{
+		Count:       input.ClusterShards * (input.ClusterReplicas + 1),
+		Datastore:   input.Datastore,
+		ServiceName: input.ServiceName,
+	})
+	if err != nil {
+		return err
+	}
+
+	manager := input.Datastore.(datastores.ClusterManager)
+	if err := manager.ConfigureCluster(ctx, input.ServiceName); err != nil {
+		return fmt.Errorf("failed to configure cluster: %w", err)
+	}
+	return nil
+}
+
+// createCluster joins the members of a cluster service into a cluster once all of them are running
+func createCluster(ctx context.Context, input CreateServiceInput) error {
+	err := WaitForService(ctx, WaitForServiceInput{
+		Datastore:   input.Datastore,
+		ServiceName: input.ServiceName,
+	})
+	if err != nil {
+		return err
+	}
+
+	manager := input.Datastore.(datastores.ClusterManager)
+	return manager.CreateCluster(ctx, input.ServiceName, input.ClusterReplicas)
+}
@@ -0,0 +1,119 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dokku/dokku-datastore/internal/datastores"
+)
+
+// ConfigResult is the json result of the config-get and config-show commands
+type ConfigResult struct {
+	// Type is the datastore type of the service
+	Type string `json:"type"`
+	// Service is the name of the service
+	Service string `json:"service"`
+	// Directives is the directives of the service
+	Directives []datastores.ConfigDirective `json:"directives"`
+}
+
+// ConfigChangeResult is the json result of the config-set and config-unset commands
+type ConfigChangeResult struct {
+	// Type is the datastore type of the service
+	Type string `json:"type"`
+	// Service is the name of the service
+	Service string `json:"service"`
+	datastores.ConfigChange
+}
+
+// configManager returns the config manager of a datastore, or an error when it has none
+func configManager(s datastores.Datastore) (datastores.ConfigManager, error) {
+	manager, ok := s.(datastores.ConfigManager)
+	if !ok {
+		return nil, fmt.Errorf("%s services do not support config directives", s.ServiceType())
+	}
+	return manager, nil
+}
+
+// GetConfigDirective returns every line of the configuration file of a service that sets a directive
+func GetConfigDirective(s datastores.Datastore, serviceName string, name string) ([]datastores.ConfigDirective, error) {
+	manager, err := configManager(s)
+	if err != nil {
+		return nil, err
+	}
+
+	directives, err := manager.ConfigDirectives(serviceName)
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.ToLower(name)
+	matches := []datastores.ConfigDirective{}
+	for _, directive := range directives {
+		if directive.Name == name {
+			matches = append(matches, directive)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("directive %s is not set for service %s", name, serviceName)
+	}
+	return matches, nil
+}
+
+// ShowConfig returns the effective configuration of a service
+func ShowConfig(ctx context.Context, s datastores.Datastore, serviceName string) ([]datastores.ConfigDirective, error) {
+	manager, err := configManager(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return manager.EffectiveConfig(ctx, serviceName)
+}
+
+// SetConfigDirectiveInput is the input for the SetConfigDirective function
+type SetConfigDirectiveInput struct {
+	// Datastore is the service to set the directive of
+	Datastore datastores.Datastore
+
+	// Name is the name of the directive
+	Name string
+
+	// ServiceName is the name of the service to set the directive of
+	ServiceName string
+
+	// Value is the value of the directive as written in the configuration file
+	Value string
+}
+
+// SetConfigDirective sets a directive in the configuration file of a service
+func SetConfigDirective(ctx context.Context, input SetConfigDirectiveInput) (datastores.ConfigChange, error) {
+	manager, err := configManager(input.Datastore)
+	if err != nil {
+		return datastores.ConfigChange{}, err
+	}
+
+	return manager.SetConfigDirective(ctx, input.ServiceName, input.Name, input.Value)
+}
+
+// UnsetConfigDirectiveInput is the input for the UnsetConfigDirective function
+type UnsetConfigDirectiveInput struct {
+	// Datastore is the service to unset the directive of
+	Datastore datastores.Datastore
+
+	// Name is the name of the directive
+	Name string
+
+	// ServiceName is the name of the service to unset the directive of
+	ServiceName string
+}
+
+// UnsetConfigDirective removes a directive from the configuration file of a service
+func UnsetConfigDirective(ctx context.Context, input UnsetConfigDirectiveInput) (datastores.ConfigChange, error) {
+	manager, err := configManager(input.Datastore)
+	if err != nil {
+		return datastores.ConfigChange{}, err
+	}
+
+	return manager.UnsetConfigDirective(ctx, input.ServiceName, input.Name)
+}
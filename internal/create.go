@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/dokku/dokku-datastore/internal/datastores"
 	"github.com/dokku/dokku/plugins/common"
@@ -16,12 +17,27 @@ type CreateServiceInput struct {
 	// ConfigOptions is the configuration options to use for the service
 	ConfigOptions string
 
+	// Cluster is whether to shard the service over many member containers
+	Cluster bool
+
+	// ClusterReplicas is the number of replicas of each shard of a cluster service
+	ClusterReplicas int
+
+	// ClusterShards is the number of shards of a cluster service, defaulting to DefaultClusterShards
+	ClusterShards int
+
 	// CustomEnv is the custom environment variables to use for the service
 	CustomEnv string
 
 	// Datastore is the service to create
 	Datastore datastores.Datastore
 
+	// HA is whether to run the service as a primary with replicas failed over by monitors
+	HA bool
+
+	// HAReplicas is the number of replica services of a high availability service, defaulting to DefaultHAReplicas
+	HAReplicas int
+
 	// Image is the image to use for the service
 	Image string
 
@@ -31,23 +47,80 @@ type CreateServiceInput struct {
 	// InitialNetwork is the initial network to use for the service
 	InitialNetwork string
 
+	// MaxmemoryPercent is the share of the memory limit the datastore may use, defaulting to datastores.DefaultMaxmemoryPercent
+	MaxmemoryPercent int
+
+	// MaxmemoryPolicy is the eviction policy once the datastore uses its share of the memory limit
+	MaxmemoryPolicy string
+
 	// Memory is the memory limit to use for the service
 	Memory int
 
 	// Password is the password to use for the service
 	Password string
 
+	// Persistence is how the service persists its data, where zero values keep the datastore defaults
+	Persistence datastores.PersistenceSettings
+
 	// PostCreateNetworks is the networks to attach the service container to after service creation
 	PostCreateNetworks []string
 
 	// PostStartNetworks is the networks to attach the service container to after service start
 	PostStartNetworks []string
 
+	// ReplicaOf is the name of the primary service to replicate from
+	ReplicaOf string
+
+	// Resources is the resource limits to use for the service
+	Resources datastores.ResourceLimits
+
 	// ServiceName is the name of the service to create
 	ServiceName string
 
 	// ShmSize is the shared memory size to use for the service
 	ShmSize string
+
+	// TLS is whether the service only accepts tls connections
+	TLS bool
+}
+
+// inheritPrimarySettings validates a replica's primary and copies the networks and tls setting it needs to reach it
+func inheritPrimarySettings(ctx context.Context, input *CreateServiceInput) error {
+	if _, ok := input.Datastore.(datastores.Replicator); !ok {
+		return fmt.Errorf("%s services do not support replication", input.Datastore.ServiceType())
+	}
+
+	if !datastores.Exists(ctx, input.Datastore, input.ReplicaOf) {
+		return fmt.Errorf("primary service %s does not exist", input.ReplicaOf)
+	}
+
+	if err := checkManualReplication(input.Datastore, input.ReplicaOf); err != nil {
+		return err
+	}
+
+	if primary := datastores.ReplicaOf(input.Datastore, input.ReplicaOf); primary != "" {
+		return fmt.Errorf("service %s is itself a replica of %s", input.ReplicaOf, primary)
+	}
+
+	if input.InitialNetwork == "" && len(input.PostCreateNetworks) == 0 && len(input.PostStartNetworks) == 0 {
+		input.InitialNetwork = datastores.InitialNetwork(input.Datastore, input.ReplicaOf)
+		if network := datastores.PostCreateNetwork(input.Datastore, input.ReplicaOf); network != "" {
+			input.PostCreateNetworks = strings.Split(network, ",")
+		}
+		if network := datastores.PostStartNetwork(input.Datastore, input.ReplicaOf); network != "" {
+			input.PostStartNetworks = strings.Split(network, ",")
+		}
+	}
+
+	if input.InitialNetwork == "" && len(input.PostCreateNetworks) == 0 && len(input.PostStartNetworks) == 0 {
+		return fmt.Errorf("primary service %s is not attached to a network, replicas reach their primary over a docker network", input.ReplicaOf)
+	}
+
+	if datastores.TLSEnabled(input.Datastore, input.ReplicaOf) {
+		input.TLS = true
+	}
+
+	return nil
 }
 
 // CreateService creates a new service
@@ -56,6 +129,40 @@ func CreateService(ctx context.Context, input CreateServiceInput) error {
 		return err
 	}
 
+	if err := input.Resources.Validate(); err != nil {
+		return err
+	}
+
+	if err := datastores.ValidateMaxmemorySettings(input.Datastore, input.MaxmemoryPercent, input.MaxmemoryPolicy); err != nil {
+		return err
+	}
+
+	if err := datastores.ValidatePersistenceSettings(input.Datastore, input.Persistence); err != nil {
+		return err
+	}
+
+	if _, ok := input.Datastore.(datastores.TLSConfigurer); input.TLS && !ok {
+		return fmt.Errorf("%s services do not support tls", input.Datastore.ServiceType())
+	}
+
+	if input.ReplicaOf != "" {
+		if err := inheritPrimarySettings(ctx, &input); err != nil {
+			return err
+		}
+	}
+
+	if input.HA {
+		if err := prepareHAService(ctx, &input); err != nil {
+			return err
+		}
+	}
+
+	if input.Cluster {
+		if err := prepareClusterService(&input); err != nil {
+			return err
+		}
+	}
+
 	serviceFolders := datastores.Folders(input.Datastore, input.ServiceName)
 	serviceRoot := serviceFolders.Root
 	if _, err := os.Stat(serviceRoot); err == nil {
@@ -73,21 +180,12 @@ func CreateService(ctx context.Context, input CreateServiceInput) error {
 		return fmt.Errorf("failed to get image for service: %w", err)
 	}
 
-	properties := input.Datastore.Properties()
-	if err := datastores.ValidateTaggedImageExists(taggedImage); err != nil {
-		if os.Getenv(properties.ImagePullVariable) == "true" {
-			message := []string{
-				fmt.Sprintf("%s environment variable detected. Not running pull command.", properties.ImagePullVariable),
-				fmt.Sprintf("docker image pull %s", taggedImage),
-				fmt.Sprintf("%s service creation failed", input.ServiceName),
-			}
-			return errors.New(strings.Join(message, "\n"))
-		}
-
-		// pull the image
-		if _, err := datastores.PullTaggedImage(ctx, taggedImage); err != nil {
-			return fmt.Errorf("failed to pull image %s: %w", taggedImage, err)
-		}
+	if err := EnsureTaggedImage(ctx, EnsureTaggedImageInput{
+		Datastore:   input.Datastore,
+		ServiceName: input.ServiceName,
+		TaggedImage: taggedImage,
+	}); err != nil {
+		return err
 	}
 
 	_, err = datastores.CallPlugnTriggerWithContext(ctx, common.PlugnTriggerInput{
@@ -129,6 +227,18 @@ func CreateService(ctx context.Context, input CreateServiceInput) error {
 		}
 	}
 
+	if input.HA {
+		if err := datastores.CreateHANetwork(ctx, input.Datastore, input.ServiceName); err != nil {
+			return err
+		}
+	}
+
+	if input.Cluster {
+		if err := datastores.CreateClusterNetwork(ctx, input.Datastore, input.ServiceName); err != nil {
+			return err
+		}
+	}
+
 	err = input.Datastore.CreateService(ctx, input.ServiceName)
 	if err != nil {
 		return fmt.Errorf("failed to create service: %w", err)
@@ -144,6 +254,7 @@ func CreateService(ctx context.Context, input CreateServiceInput) error {
 		Memory:             input.Memory,
 		PostCreateNetworks: input.PostCreateNetworks,
 		PostStartNetworks:  input.PostStartNetworks,
+		Resources:          input.Resources,
 		ServiceName:        input.ServiceName,
 		ShmSize:            input.ShmSize,
 	}); err != nil {
@@ -157,6 +268,52 @@ func CreateService(ctx context.Context, input CreateServiceInput) error {
 		return fmt.Errorf("failed to write database name: %w", err)
 	}
 
+	if input.MaxmemoryPercent != 0 {
+		if err := datastores.SetMaxmemoryPercent(input.Datastore, input.ServiceName, input.MaxmemoryPercent); err != nil {
+			return err
+		}
+	}
+	if input.MaxmemoryPolicy != "" {
+		if err := datastores.SetMaxmemoryPolicy(input.Datastore, input.ServiceName, input.MaxmemoryPolicy); err != nil {
+			return err
+		}
+	}
+	if err := datastores.ConfigureMaxmemory(ctx, input.Datastore, input.ServiceName); err != nil {
+		return err
+	}
+
+	if err := datastores.ConfigurePersistence(ctx, input.Datastore, input.ServiceName, input.Persistence); err != nil {
+		return err
+	}
+
+	if input.TLS {
+		err := datastores.ConfigureServiceTLS(ctx, datastores.ConfigureServiceTLSInput{
+			Datastore:   input.Datastore,
+			Enabled:     true,
+			ServiceName: input.ServiceName,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to configure tls: %w", err)
+		}
+	}
+
+	if input.ReplicaOf != "" {
+		err := datastores.ConfigureReplica(ctx, datastores.ConfigureReplicaInput{
+			Datastore:   input.Datastore,
+			PrimaryName: input.ReplicaOf,
+			ServiceName: input.ServiceName,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if input.Cluster {
+		if err := configureClusterMembers(ctx, input); err != nil {
+			return err
+		}
+	}
+
 	_, err = datastores.CallPlugnTriggerWithContext(ctx, common.PlugnTriggerInput{
 		Trigger:      "service-action",
 		Args:         []string{"post-create", input.Datastore.ServiceType(), input.ServiceName},
@@ -167,13 +324,16 @@ func CreateService(ctx context.Context, input CreateServiceInput) error {
 		return fmt.Errorf("failed to call service-action post-create trigger: %w", err)
 	}
 
-	err = input.Datastore.CreateServiceContainer(ctx, datastores.CreateServiceContainerInput{
-		Datastore:   input.Datastore,
-		ServiceName: input.ServiceName,
-		TaggedImage: taggedImage,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to create service container: %w", err)
+	for _, member := range datastores.ServiceMembers(input.Datastore, input.ServiceName) {
+		err = input.Datastore.CreateServiceContainer(ctx, datastores.CreateServiceContainerInput{
+			Datastore:   input.Datastore,
+			Member:      member,
+			ServiceName: input.ServiceName,
+			TaggedImage: taggedImage,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create service container: %w", err)
+		}
 	}
 
 	_, err = datastores.CallPlugnTriggerWithContext(ctx, common.PlugnTriggerInput{
@@ -186,5 +346,151 @@ func CreateService(ctx context.Context, input CreateServiceInput) error {
 		return fmt.Errorf("failed to call service-action post-create-complete trigger: %w", err)
 	}
 
+	if input.HA {
+		return createHAMembers(ctx, input)
+	}
+
+	if input.Cluster {
+		return createCluster(ctx, input)
+	}
+
+	return nil
+}
+
+// EnsureTaggedImageInput is the input for the EnsureTaggedImage function
+type EnsureTaggedImageInput struct {
+	// Datastore is the service the image is for
+	Datastore datastores.Datastore
+
+	// ServiceName is the name of the service the image is for
+	ServiceName string
+
+	// TaggedImage is the image to ensure exists locally
+	TaggedImage string
+}
+
+// EnsureTaggedImage pulls an image if it does not exist locally, unless pulling is disabled
+func EnsureTaggedImage(ctx context.Context, input EnsureTaggedImageInput) error {
+	if err := datastores.ValidateTaggedImageExists(input.TaggedImage); err == nil {
+		return nil
+	}
+
+	properties := input.Datastore.Properties()
+	if os.Getenv(properties.ImagePullVariable) == "true" {
+		message := []string{
+			fmt.Sprintf("%s environment variable detected. Not running pull command.", properties.ImagePullVariable),
+			fmt.Sprintf("docker image pull %s", input.TaggedImage),
+			fmt.Sprintf("%s service creation failed", input.ServiceName),
+		}
+		return errors.New(strings.Join(message, "\n"))
+	}
+
+	// pull the image
+	if _, err := datastores.PullTaggedImage(ctx, input.TaggedImage); err != nil {
+		return fmt.Errorf("failed to pull image %s: %w", input.TaggedImage, err)
+	}
+
 	return nil
 }
+
+// WaitForServiceInput is the input for the WaitForService function
+type WaitForServiceInput struct {
+	// Datastore is the service to wait for
+	Datastore datastores.Datastore
+
+	// ServiceName is the name of the service to wait for
+	ServiceName string
+}
+
+// ServiceHealthyTimeout is how long WaitForService waits for a healthcheck to report the service as healthy
+var ServiceHealthyTimeout = 10 * time.Minute
+
+// WaitForService waits for a service container to accept connections
+//
+// Containers with a healthcheck are first waited on until they report healthy, so services loading
+// large datasets are not considered ready as soon as their port opens
+func WaitForService(ctx context.Context, input WaitForServiceInput) error {
+	if err := waitForHealthy(ctx, input); err != nil {
+		return err
+	}
+
+	serviceProperties := input.Datastore.Properties()
+	waitPort := serviceProperties.WaitPort
+	initialNetwork := datastores.InitialNetwork(input.Datastore, input.ServiceName)
+	networkAlias := datastores.DNSHostname(input.Datastore, input.ServiceName)
+	containerName := datastores.ContainerName(input.Datastore, input.ServiceName)
+
+	linkContainerDockerArgs := []string{
+		"container",
+		"run",
+		"--rm",
+		"--link=" + containerName + ":" + networkAlias,
+	}
+
+	if initialNetwork != "" {
+		linkContainerDockerArgs = append(linkContainerDockerArgs, "--network="+initialNetwork)
+	}
+
+	linkContainerDockerArgs = append(linkContainerDockerArgs, datastores.PluginWaitImage)
+	linkContainerDockerArgs = append(linkContainerDockerArgs, "-c", fmt.Sprintf("%s:%d", networkAlias, waitPort))
+
+	_, err := datastores.CallExecCommandWithContext(ctx, common.ExecCommandInput{
+		Command: common.DockerBin(),
+		Args:    linkContainerDockerArgs,
+	})
+	return err
+}
+
+// waitForHealthy polls the healthcheck of every container of a service until they all report healthy
+func waitForHealthy(ctx context.Context, input WaitForServiceInput) error {
+	containerIDs := datastores.LiveContainerIDs(ctx, datastores.LiveContainerIDsInput{
+		Datastore:   input.Datastore,
+		ServiceName: input.ServiceName,
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, ServiceHealthyTimeout)
+	defer cancel()
+
+	for _, member := range datastores.ServiceMembers(input.Datastore, input.ServiceName) {
+		containerID := containerIDs[member]
+		if containerID == "" || !datastores.HasHealthcheck(containerID) {
+			continue
+		}
+
+		if err := waitForContainerHealthy(ctx, input.ServiceName, containerID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitForContainerHealthy polls the healthcheck of a container until it reports healthy
+//
+// An unhealthy container is polled until the timeout as well, since a datastore still loading its data
+// can fail its checks for longer than the start period and recover once it is loaded
+func waitForContainerHealthy(ctx context.Context, serviceName string, containerID string) error {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		status := datastores.Status(ctx, datastores.StatusInput{ContainerID: containerID})
+		switch status {
+		case "healthy":
+			return nil
+		case "starting", "unhealthy":
+		default:
+			return fmt.Errorf("service %s is %s", serviceName, status)
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				if status == "unhealthy" {
+					return fmt.Errorf("service %s is unhealthy", serviceName)
+				}
+				return fmt.Errorf("timed out waiting for service %s to become healthy", serviceName)
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
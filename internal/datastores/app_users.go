@@ -0,0 +1,89 @@
+package datastores
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// AppUser is the dedicated credential of an app linked to a service
+type AppUser struct {
+	// App is the name of the app, which is also the username
+	App string `json:"app"`
+
+	// Enabled is whether the credential is accepted, where revoked credentials are disabled
+	Enabled bool `json:"enabled"`
+
+	// Rules is the permissions of the user in the datastore's own syntax
+	Rules string `json:"rules"`
+}
+
+// AppUserManager is implemented by datastores that give every linked app its own credential
+type AppUserManager interface {
+	// SetAppUser creates or updates the user of an app, where empty rules keep the current permissions
+	SetAppUser(ctx context.Context, serviceName string, appName string, rules string) error
+
+	// RevokeAppUser disables the user of an app without unlinking it
+	RevokeAppUser(ctx context.Context, serviceName string, appName string) error
+
+	// RemoveAppUser deletes the user of an app
+	RemoveAppUser(ctx context.Context, serviceName string, appName string) error
+
+	// AppUsers returns the users of every app with its own credential
+	AppUsers(serviceName string) []AppUser
+
+	// AppURL returns the dsn of a service carrying the credential of an app, or empty when the app has none
+	AppURL(serviceName string, appName string) string
+}
+
+// AppURL returns the dsn an app should use for a service, carrying the credential of the app when it has one
+func AppURL(s Datastore, serviceName string, appName string) string {
+	if manager, ok := s.(AppUserManager); ok {
+		if dsn := manager.AppURL(serviceName, appName); dsn != "" {
+			return dsn
+		}
+	}
+	return s.URL(serviceName)
+}
+
+// readAppPasswords reads the password of each app with its own credential
+func readAppPasswords(s Datastore, serviceName string) map[string]string {
+	passwords := map[string]string{}
+	lines, err := common.FileToSlice(Files(s, serviceName).AppPasswords)
+	if err != nil {
+		return passwords
+	}
+
+	for _, line := range lines {
+		appName, password, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if ok && appName != "" && password != "" {
+			passwords[appName] = password
+		}
+	}
+	return passwords
+}
+
+// writeAppPasswords persists the password of each app with its own credential
+func writeAppPasswords(s Datastore, serviceName string, passwords map[string]string) error {
+	lines := []string{}
+	for _, appName := range slices.Sorted(maps.Keys(passwords)) {
+		lines = append(lines, appName+" "+passwords[appName])
+	}
+
+	passwordsFile := Files(s, serviceName).AppPasswords
+	err := common.WriteSliceToFile(common.WriteSliceToFileInput{
+		Filename:  passwordsFile,
+		GroupName: SystemGroup(),
+		Lines:     lines,
+		Mode:      0640,
+		Username:  SystemUser(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", passwordsFile, err)
+	}
+	return nil
+}
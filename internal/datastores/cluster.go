@@ -0,0 +1,195 @@
+package datastores
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// ClusterManager is implemented by datastores that can shard a service over many member containers
+//
+// The service container is the first member of a cluster, and the other members are containers named
+// after it that share its configuration but keep their own data
+type ClusterManager interface {
+	// ConfigureCluster rewrites the service configuration so its containers start as cluster members
+	ConfigureCluster(ctx context.Context, serviceName string) error
+
+	// CreateCluster joins the running members of a service into a cluster, with replicas members per shard
+	CreateCluster(ctx context.Context, serviceName string, replicas int) error
+}
+
+// ClusterEnabled returns whether a service is backed by a cluster of containers
+func ClusterEnabled(s Datastore, serviceName string) bool {
+	return len(ClusterMembers(s, serviceName)) > 0
+}
+
+// ClusterMembers returns the members of a cluster besides the service container, as recorded in the service root
+func ClusterMembers(s Datastore, serviceName string) []string {
+	lines, err := common.FileToSlice(Files(s, serviceName).ClusterMembers)
+	if err != nil {
+		return []string{}
+	}
+
+	members := []string{}
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			members = append(members, line)
+		}
+	}
+	return members
+}
+
+// ClusterMemberName returns the name of a member of a cluster
+func ClusterMemberName(index int) string {
+	return fmt.Sprintf("node-%d", index)
+}
+
+// MemberDisplayName returns the name a member of a service is shown as, where the service container is node-0
+func MemberDisplayName(member string) string {
+	if member == "" {
+		return ClusterMemberName(0)
+	}
+	return member
+}
+
+// ParseMember returns the member of a service shown as a name, where node-0 is the service container
+func ParseMember(s Datastore, serviceName string, name string) (string, error) {
+	if name == ClusterMemberName(0) {
+		return "", nil
+	}
+
+	for _, member := range ClusterMembers(s, serviceName) {
+		if member == name {
+			return member, nil
+		}
+	}
+	return "", fmt.Errorf("service %s has no cluster member %s", serviceName, name)
+}
+
+// ClusterNetwork returns the docker network dedicated to the members of a cluster
+func ClusterNetwork(s Datastore, serviceName string) string {
+	return fmt.Sprintf("%s.cluster", ContainerName(s, serviceName))
+}
+
+// ServiceMembers returns the members backing a service, where the empty member is the service container itself
+func ServiceMembers(s Datastore, serviceName string) []string {
+	return append([]string{""}, ClusterMembers(s, serviceName)...)
+}
+
+// MemberContainerName returns the container name of a member of a service
+func MemberContainerName(s Datastore, serviceName string, member string) string {
+	if member == "" {
+		return ContainerName(s, serviceName)
+	}
+	return fmt.Sprintf("%s.%s", ContainerName(s, serviceName), member)
+}
+
+// MemberDNSHostname returns the network alias of a member of a service
+func MemberDNSHostname(s Datastore, serviceName string, member string) string {
+	if member == "" {
+		return DNSHostname(s, serviceName)
+	}
+	return fmt.Sprintf("%s-%s", DNSHostname(s, serviceName), member)
+}
+
+// MemberFolders returns the folders of a member of a service, which keeps its data in a folder of the service data folder
+func MemberFolders(s Datastore, serviceName string, member string) ServiceFolders {
+	folders := Folders(s, serviceName)
+	if member == "" {
+		return folders
+	}
+
+	folders.Data = filepath.Join(folders.Data, member)
+	folders.HostData = filepath.Join(folders.HostData, member)
+	return folders
+}
+
+// MemberIDFile returns the file holding the container id of a member of a service
+func MemberIDFile(s Datastore, serviceName string, member string) string {
+	idFile := Files(s, serviceName).ID
+	if member == "" {
+		return idFile
+	}
+	return idFile + "." + member
+}
+
+// WriteClusterMembersInput is the input for the WriteClusterMembers function
+type WriteClusterMembersInput struct {
+	// Count is the number of members of the cluster, including the service container
+	Count int
+
+	// Datastore is the clustered service
+	Datastore Datastore
+
+	// ServiceName is the name of the clustered service
+	ServiceName string
+}
+
+// WriteClusterMembers records the members of a cluster in the service root and creates their data folders
+func WriteClusterMembers(input WriteClusterMembersInput) error {
+	members := []string{}
+	for index := 1; index < input.Count; index++ {
+		member := ClusterMemberName(index)
+		folders := MemberFolders(input.Datastore, input.ServiceName, member)
+		if err := os.MkdirAll(folders.Data, 0755); err != nil {
+			return fmt.Errorf("failed to create data folder for member %s: %w", member, err)
+		}
+		members = append(members, member)
+	}
+
+	membersFile := Files(input.Datastore, input.ServiceName).ClusterMembers
+	err := common.WriteSliceToFile(common.WriteSliceToFileInput{
+		Filename:  membersFile,
+		Lines:     members,
+		GroupName: SystemGroup(),
+		Mode:      0644,
+		Username:  SystemUser(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write cluster members to %s: %w", membersFile, err)
+	}
+	return nil
+}
+
+// CreateClusterNetwork creates the dedicated network of a cluster unless it exists
+func CreateClusterNetwork(ctx context.Context, s Datastore, serviceName string) error {
+	return createServiceNetwork(ctx, s, ClusterNetwork(s, serviceName), "cluster")
+}
+
+// RemoveClusterNetwork removes the dedicated network of a cluster when it exists
+func RemoveClusterNetwork(ctx context.Context, s Datastore, serviceName string) error {
+	return removeServiceNetwork(ctx, ClusterNetwork(s, serviceName))
+}
+
+// LiveContainerIDsInput is the input for the LiveContainerIDs function
+type LiveContainerIDsInput struct {
+	// Datastore is the service to get the live container IDs for
+	Datastore Datastore
+
+	// ServiceName is the name of the service to get the live container IDs for
+	ServiceName string
+
+	// Filter is an additional docker ps filter, such as status=running
+	Filter string
+}
+
+// LiveContainerIDs returns the live container IDs of every member of a service, keyed by member
+func LiveContainerIDs(ctx context.Context, input LiveContainerIDsInput) map[string]string {
+	containerIDs := map[string]string{}
+	for _, member := range ServiceMembers(input.Datastore, input.ServiceName) {
+		containerID := LiveContainerID(ctx, LiveContainerIDInput{
+			Datastore:   input.Datastore,
+			Filter:      input.Filter,
+			Member:      member,
+			ServiceName: input.ServiceName,
+		})
+		if containerID != "" {
+			containerIDs[member] = containerID
+		}
+	}
+	return containerIDs
+}
@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/dokku/dokku/plugins/common"
 )
@@ -62,7 +65,16 @@ func ContainerIP(ctx context.Context, input ContainerIPInput) string {
 	}
 
 	containerIP, _ := common.DockerInspect(input.ContainerID, "{{ .NetworkSettings.IPAddress }}")
-	return containerIP
+	if containerIP != "" {
+		return containerIP
+	}
+
+	// containers attached only to user-defined networks have no top-level ip address
+	networkIPs, _ := common.DockerInspect(input.ContainerID, "{{ range .NetworkSettings.Networks }}{{ .IPAddress }} {{ end }}")
+	if ips := strings.Fields(networkIPs); len(ips) > 0 {
+		return ips[0]
+	}
+	return ""
 }
 
 // ContainerName gets the name of a service
@@ -77,6 +89,11 @@ func DNSHostname(s Datastore, serviceName string) string {
 	return strings.NewReplacer(".", "-", "_", "-").Replace(serviceName)
 }
 
+// DSNEnvVariable gets the app environment variable that holds the dsn for a linked service
+func DSNEnvVariable(s Datastore) string {
+	return strings.ToUpper(s.Properties().CommandPrefix) + "_URL"
+}
+
 // EnterServiceContainerInput is the input for the EnterServiceContainer function
 type EnterServiceContainerInput struct {
 	// Datastore is the service to enter
@@ -101,7 +118,7 @@ func EnterServiceContainer(ctx context.Context, input EnterServiceContainerInput
 	}
 
 	status := Status(ctx, StatusInput{ContainerID: containerID})
-	if strings.ToLower(status) != "running" {
+	if !IsRunningStatus(status) {
 		return fmt.Errorf("%s container %s is not running", input.Datastore.Properties().CommandPrefix, input.ServiceName)
 	}
 
@@ -134,11 +151,20 @@ func ExposedPorts(s Datastore, serviceName string) string {
 		return "-"
 	}
 
+	bind := exposeBindPrefix(s, serviceName)
+
 	datastorePorts := s.Properties().Ports
-	ports := strings.Split(common.ReadFirstLine(portFile), ",")
+	ports := strings.Fields(common.ReadFirstLine(portFile))
 	output := []string{}
 	for i := range ports {
-		output = append(output, fmt.Sprintf("%d->%s", datastorePorts[i], ports[i]))
+		if i >= len(datastorePorts) {
+			break
+		}
+		output = append(output, fmt.Sprintf("%d->%s%s", datastorePorts[i], bind, ports[i]))
+	}
+
+	if allowList := ExposeAllowList(s, serviceName); len(allowList) > 0 {
+		output = append(output, fmt.Sprintf("(allow: %s)", strings.Join(allowList, ", ")))
 	}
 
 	return strings.Join(output, " ")
@@ -146,9 +172,30 @@ func ExposedPorts(s Datastore, serviceName string) string {
 
 // ServiceFiles is the files for a service
 type ServiceFiles struct {
+	// AppPasswords is the file holding the password of each linked app with its own credentials
+	AppPasswords string
+
+	// AuditLog is the json-lines audit log for the service
+	AuditLog string
+
+	// BlkioWeight is the block io weight file for the service
+	BlkioWeight string
+
+	// ClusterMembers is the file listing the cluster members of the service besides the service container
+	ClusterMembers string
+
 	// ConfigOptions is the config options file for the service
 	ConfigOptions string
 
+	// CPUs is the cpu quota file for the service
+	CPUs string
+
+	// CPUSetCPUs is the file containing the cpus the service may run on
+	CPUSetCPUs string
+
+	// CPUShares is the cpu shares file for the service
+	CPUShares string
+
 	// CronFile is the cron file for the service
 	CronFile string
 
@@ -158,6 +205,12 @@ type ServiceFiles struct {
 	// Env is the environment file for the service
 	Env string
 
+	// ExposeAllow is the file listing the networks allowed to connect to the exposed ports
+	ExposeAllow string
+
+	// ExposeBind is the file containing the host address the exposed ports are bound to
+	ExposeBind string
+
 	// ID is the ID file for the service
 	ID string
 
@@ -173,28 +226,57 @@ type ServiceFiles struct {
 	// Memory is the memory file for the service
 	Memory string
 
+	// MemoryReservation is the soft memory limit file for the service
+	MemoryReservation string
+
+	// MemorySwap is the memory plus swap limit file for the service
+	MemorySwap string
+
+	// Password is the password file for the service
+	Password string
+
+	// PidsLimit is the process limit file for the service
+	PidsLimit string
+
 	// Port is the port file for the service
 	Port string
 
 	// ShmSize is the shared memory size file for the service
 	ShmSize string
+
+	// Ulimits is the ulimits file for the service, with one ulimit per line
+	Ulimits string
 }
 
 // Files returns the files for a service
 func Files(s Datastore, serviceName string) ServiceFiles {
 	folders := Folders(s, serviceName)
 	return ServiceFiles{
-		ConfigOptions: filepath.Join(folders.Root, "CONFIG_OPTIONS"),
-		CronFile:      fmt.Sprintf("/etc/cron.d/dokku-%s-%s", s.Properties().CommandPrefix, serviceName),
-		DatabaseName:  filepath.Join(folders.Root, "DATABASE_NAME"),
-		Env:           filepath.Join(folders.Root, "ENV"),
-		ID:            filepath.Join(folders.Root, "ID"),
-		Links:         filepath.Join(folders.Root, "LINKS"),
-		Image:         filepath.Join(folders.Root, "IMAGE"),
-		ImageVersion:  filepath.Join(folders.Root, "IMAGE_VERSION"),
-		Memory:        filepath.Join(folders.Root, "MEMORY"),
-		Port:          filepath.Join(folders.Root, "PORT"),
-		ShmSize:       filepath.Join(folders.Root, "SHM_SIZE"),
+		AppPasswords:      filepath.Join(folders.Root, "APP_PASSWORDS"),
+		AuditLog:          filepath.Join(folders.Root, "AUDIT_LOG"),
+		BlkioWeight:       filepath.Join(folders.Root, "BLKIO_WEIGHT"),
+		ClusterMembers:    filepath.Join(folders.Root, "CLUSTER_MEMBERS"),
+		ConfigOptions:     filepath.Join(folders.Root, "CONFIG_OPTIONS"),
+		CPUs:              filepath.Join(folders.Root, "CPUS"),
+		CPUSetCPUs:        filepath.Join(folders.Root, "CPUSET_CPUS"),
+		CPUShares:         filepath.Join(folders.Root, "CPU_SHARES"),
+		CronFile:          fmt.Sprintf("/etc/cron.d/dokku-%s-%s", s.Properties().CommandPrefix, serviceName),
+		DatabaseName:      filepath.Join(folders.Root, "DATABASE_NAME"),
+		Env:               filepath.Join(folders.Root, "ENV"),
+		ExposeAllow:       filepath.Join(folders.Root, "EXPOSE_ALLOW"),
+		ExposeBind:        filepath.Join(folders.Root, "EXPOSE_BIND"),
+		ID:                filepath.Join(folders.Root, "ID"),
+		Links:             filepath.Join(folders.Root, "LINKS"),
+		Image:             filepath.Join(folders.Root, "IMAGE"),
+		ImageVersion:      filepath.Join(folders.Root, "IMAGE_VERSION"),
+		Memory:            filepath.Join(folders.Root, "MEMORY"),
+		MemoryReservation: filepath.Join(folders.Root, "MEMORY_RESERVATION"),
+		MemorySwap:        filepath.Join(folders.Root, "MEMORY_SWAP"),
+		Password:          filepath.Join(folders.Root, "PASSWORD"),
+		PidsLimit:         filepath.Join(folders.Root, "PIDS_LIMIT"),
+		Port:              filepath.Join(folders.Root, "PORT"),
+		ShmSize:           filepath.Join(folders.Root, "SHM_SIZE"),
+		Ulimits:           filepath.Join(folders.Root, "ULIMITS"),
 	}
 }
 
@@ -232,39 +314,120 @@ func Folders(s Datastore, serviceName string) ServiceFolders {
 	}
 }
 
+// infoLiveTimeout is how long info waits for fields read from the running service or its monitors
+const infoLiveTimeout = 5 * time.Second
+
 // InfoInput is the input for the Info function
 type InfoInput struct {
 	// Datastore is the service to get the information for
 	Datastore Datastore
 
+	// Field is the single field to get, such as status, where empty gets every field
+	Field string
+
 	// ServiceName is the name of the service to get the information for
 	ServiceName string
 }
 
 // Info returns the information about a service
+//
+// Only the requested field is computed when one is given, so reading a field from disk never waits on the
+// running service, and fields read from the running service or its monitors give up after a short timeout
 func Info(ctx context.Context, input InfoInput) map[string]string {
-	serviceFolders := Folders(input.Datastore, input.ServiceName)
+	report := &infoReport{ctx: ctx, input: input}
+	fields := report.fields()
 
-	containerID := LiveContainerID(ctx, LiveContainerIDInput{
-		Datastore:   input.Datastore,
-		ServiceName: input.ServiceName,
-	})
-	return map[string]string{
-		"config-dir":          serviceFolders.Config,
-		"config-options":      ConfigOptions(input.Datastore, input.ServiceName),
-		"data-dir":            serviceFolders.Data,
-		"dsn":                 input.Datastore.URL(input.ServiceName),
-		"exposed-ports":       ExposedPorts(input.Datastore, input.ServiceName),
-		"id":                  containerID,
-		"internal-ip":         ContainerIP(ctx, ContainerIPInput{ContainerID: containerID}),
-		"initial-network":     InitialNetwork(input.Datastore, input.ServiceName),
-		"links":               strings.Join(LinkedApps(ctx, LinkedAppsInput{Datastore: input.Datastore, ServiceName: input.ServiceName}), ","),
-		"post-create-network": PostCreateNetwork(input.Datastore, input.ServiceName),
-		"post-start-network":  PostStartNetwork(input.Datastore, input.ServiceName),
-		"service-root":        serviceFolders.Root,
-		"status":              Status(ctx, StatusInput{ContainerID: containerID}),
-		"version":             Version(ctx, VersionInput{ContainerID: containerID}),
+	if input.Field != "" {
+		field, ok := fields[input.Field]
+		if !ok {
+			return map[string]string{}
+		}
+		return map[string]string{input.Field: field()}
+	}
+
+	info := map[string]string{}
+	for key, field := range fields {
+		info[key] = field()
+	}
+	return info
+}
+
+// infoReport computes the fields of an info report, sharing the container id and persistence status between them
+type infoReport struct {
+	// ctx is the context of the report
+	ctx context.Context
+
+	// input is the input of the report
+	input InfoInput
+
+	// containerID is the live container id, once looked up
+	containerID *string
+
+	// persistence is the persistence status, once looked up
+	persistence *PersistenceStatus
+}
+
+// fields returns the function computing each field of the report
+func (r *infoReport) fields() map[string]func() string {
+	s := r.input.Datastore
+	serviceName := r.input.ServiceName
+	serviceFolders := Folders(s, serviceName)
+	return map[string]func() string{
+		"aof-rewrite":     func() string { return r.persistenceStatus().AOFRewrite },
+		"cluster-members": func() string { return strings.Join(ClusterMembers(s, serviceName), ",") },
+		"config-dir":      func() string { return serviceFolders.Config },
+		"config-options":  func() string { return ConfigOptions(s, serviceName) },
+		"data-dir":        func() string { return serviceFolders.Data },
+		"dsn":             func() string { return s.URL(serviceName) },
+		"exposed-ports":   func() string { return ExposedPorts(s, serviceName) },
+		"expose-mode":     func() string { return ExposeMode(s, serviceName) },
+		"ha-members":      func() string { return strings.Join(HAMembers(s, serviceName), ",") },
+		"ha-primary": func() string {
+			ctx, cancel := context.WithTimeout(r.ctx, infoLiveTimeout)
+			defer cancel()
+			return HAPrimary(ctx, HAPrimaryInput{Datastore: s, ServiceName: serviceName})
+		},
+		"hardening":       func() string { return Hardening(s, serviceName).String() },
+		"replica-of":      func() string { return ReplicaOf(s, serviceName) },
+		"resource-limits": func() string { return ReadResourceLimits(s, serviceName).String() },
+		"id":              r.liveContainerID,
+		"internal-ip":     func() string { return ContainerIP(r.ctx, ContainerIPInput{ContainerID: r.liveContainerID()}) },
+		"initial-network": func() string { return InitialNetwork(s, serviceName) },
+		"last-save":       func() string { return r.persistenceStatus().LastSave },
+		"links": func() string {
+			return strings.Join(LinkedApps(r.ctx, LinkedAppsInput{Datastore: s, ServiceName: serviceName}), ",")
+		},
+		"persistence":         func() string { return ServicePersistenceMode(s, serviceName) },
+		"post-create-network": func() string { return PostCreateNetwork(s, serviceName) },
+		"post-start-network":  func() string { return PostStartNetwork(s, serviceName) },
+		"service-root":        func() string { return serviceFolders.Root },
+		"status":              func() string { return Status(r.ctx, StatusInput{Datastore: s, ServiceName: serviceName}) },
+		"tls":                 func() string { return TLSStatus(s, serviceName) },
+		"version":             func() string { return Version(r.ctx, VersionInput{ContainerID: r.liveContainerID()}) },
+	}
+}
+
+// liveContainerID returns the live container id of the service, looking it up once
+func (r *infoReport) liveContainerID() string {
+	if r.containerID == nil {
+		containerID := LiveContainerID(r.ctx, LiveContainerIDInput{
+			Datastore:   r.input.Datastore,
+			ServiceName: r.input.ServiceName,
+		})
+		r.containerID = &containerID
+	}
+	return *r.containerID
+}
+
+// persistenceStatus returns the persistence status of the service, asking the running service once
+func (r *infoReport) persistenceStatus() PersistenceStatus {
+	if r.persistence == nil {
+		ctx, cancel := context.WithTimeout(r.ctx, infoLiveTimeout)
+		defer cancel()
+		persistence := ServicePersistenceStatus(ctx, r.input.Datastore, r.input.ServiceName)
+		r.persistence = &persistence
 	}
+	return *r.persistence
 }
 
 // InitialNetwork gets the initial network for a service
@@ -303,15 +466,19 @@ type LiveContainerIDInput struct {
 	// Datastore is the service to get the live container ID for
 	Datastore Datastore
 
+	// Member is the cluster member to get the live container ID for, where empty is the service container
+	Member string
+
 	// ServiceName is the name of the service to get the live container ID for
 	ServiceName string
 
+	// Filter is an additional docker ps filter, such as status=running
 	Filter string
 }
 
 // LiveContainerID gets the live container ID for a service, regardless of what is set in the ID file
 func LiveContainerID(ctx context.Context, input LiveContainerIDInput) string {
-	containerName := ContainerName(input.Datastore, input.ServiceName)
+	containerName := MemberContainerName(input.Datastore, input.ServiceName, input.Member)
 	arguments := []string{"container", "ps", "-aq", "--no-trunc", "--filter", fmt.Sprintf("name=^/%s$", containerName)}
 	if input.Filter != "" {
 		arguments = append(arguments, "--filter", input.Filter)
@@ -341,41 +508,65 @@ type PauseServiceContainerInput struct {
 	// ServiceName is the name of the service to pause
 	ServiceName string
 
-	// ContainerID is the ID of the container to pause
+	// ContainerID is the ID of the container to pause, where empty pauses every member of the service
 	ContainerID string
 }
 
 // PauseServiceContainer pauses a service container
 func PauseServiceContainer(ctx context.Context, input PauseServiceContainerInput) error {
-	ambassadorContainerName := AmbassadorContainerName(input.Datastore, input.ServiceName)
-	if ContainerExists(ctx, ambassadorContainerName) {
+	for _, exposeContainerName := range ExposeContainerNames(input.Datastore, input.ServiceName) {
+		if !ContainerExists(ctx, exposeContainerName) {
+			continue
+		}
+
 		_, err := CallExecCommandWithContext(ctx, common.ExecCommandInput{
 			Command: common.DockerBin(),
-			Args:    []string{"container", "stop", ambassadorContainerName},
+			Args:    []string{"container", "stop", exposeContainerName},
 		})
 		if err != nil {
-			return fmt.Errorf("failed to stop ambassador container: %w", err)
+			return fmt.Errorf("failed to stop expose container %s: %w", exposeContainerName, err)
 		}
 	}
 
+	containerIDs := []string{input.ContainerID}
 	if input.ContainerID == "" {
-		input.ContainerID = LiveContainerID(ctx, LiveContainerIDInput{
+		containerIDs = sortedContainerIDs(LiveContainerIDs(ctx, LiveContainerIDsInput{
 			Datastore:   input.Datastore,
 			ServiceName: input.ServiceName,
-		})
+		}))
+	}
+	if len(containerIDs) == 0 {
+		return fmt.Errorf("%s container %s does not exist", input.Datastore.Properties().CommandPrefix, input.ServiceName)
 	}
 
-	_, err := CallExecCommandWithContext(ctx, common.ExecCommandInput{
-		Command: common.DockerBin(),
-		Args:    []string{"container", "stop", input.ContainerID},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to stop container: %w", err)
+	for _, containerID := range containerIDs {
+		_, err := CallExecCommandWithContext(ctx, common.ExecCommandInput{
+			Command: common.DockerBin(),
+			Args:    []string{"container", "stop", containerID},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to stop container: %w", err)
+		}
 	}
 
 	return nil
 }
 
+// sortedContainerIDs returns the container IDs of the members of a service, service container first
+func sortedContainerIDs(containerIDs map[string]string) []string {
+	members := make([]string, 0, len(containerIDs))
+	for member := range containerIDs {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+
+	sorted := make([]string, 0, len(members))
+	for _, member := range members {
+		sorted = append(sorted, containerIDs[member])
+	}
+	return sorted
+}
+
 // PostCreateNetwork gets the post create network for a service
 func PostCreateNetwork(s Datastore, serviceName string) string {
 	return common.PropertyGet(s.Properties().CommandPrefix, serviceName, "post-create-network")
@@ -436,41 +627,45 @@ type RemoveServiceContainerInput struct {
 	ServiceName string
 }
 
-// RemoveServiceContainer removes the service container for a service
+// RemoveServiceContainer removes the service container for a service, along with the containers of its cluster members
 func RemoveServiceContainer(ctx context.Context, input RemoveServiceContainerInput) error {
-	containerID := LiveContainerID(ctx, LiveContainerIDInput{
+	containerIDs := sortedContainerIDs(LiveContainerIDs(ctx, LiveContainerIDsInput{
 		Datastore:   input.Datastore,
 		ServiceName: input.ServiceName,
-	})
-	if containerID == "" {
+	}))
+	if len(containerIDs) == 0 {
 		return nil
 	}
 
 	if err := PauseServiceContainer(ctx, PauseServiceContainerInput{
 		Datastore:   input.Datastore,
 		ServiceName: input.ServiceName,
-		ContainerID: containerID,
 	}); err != nil {
 		return err
 	}
 
-	ambassadorContainerName := AmbassadorContainerName(input.Datastore, input.ServiceName)
-	if ContainerExists(ctx, ambassadorContainerName) {
-		if err := RemoveContainer(ctx, ambassadorContainerName); err != nil {
+	for _, exposeContainerName := range ExposeContainerNames(input.Datastore, input.ServiceName) {
+		if !ContainerExists(ctx, exposeContainerName) {
+			continue
+		}
+
+		if err := RemoveContainer(ctx, exposeContainerName); err != nil {
 			return err
 		}
 	}
 
-	_, err := CallExecCommandWithContext(ctx, common.ExecCommandInput{
-		Command: common.DockerBin(),
-		Args:    []string{"container", "update", "--restart=no", containerID},
-	})
-	if err != nil {
-		return fmt.Errorf("failed to update container restart policy: %w", err)
-	}
+	for _, containerID := range containerIDs {
+		_, err := CallExecCommandWithContext(ctx, common.ExecCommandInput{
+			Command: common.DockerBin(),
+			Args:    []string{"container", "update", "--restart=no", containerID},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update container restart policy: %w", err)
+		}
 
-	if err := RemoveContainer(ctx, containerID); err != nil {
-		return err
+		if err := RemoveContainer(ctx, containerID); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -489,20 +684,104 @@ type StatusInput struct {
 }
 
 // Status gets the status of a service
+//
+// Running containers with a healthcheck report their health instead, one of starting, healthy or unhealthy.
+// A cluster reports the worst health of its members while all of them run, and degraded when only some do.
 func Status(ctx context.Context, input StatusInput) string {
-	if input.ContainerID == "" {
-		input.ContainerID = LiveContainerID(ctx, LiveContainerIDInput{
+	if input.ContainerID != "" {
+		return containerStatus(input.ContainerID)
+	}
+
+	statuses := []string{}
+	running := 0
+	for _, member := range ServiceMembers(input.Datastore, input.ServiceName) {
+		status := containerStatus(LiveContainerID(ctx, LiveContainerIDInput{
 			Datastore:   input.Datastore,
+			Member:      member,
 			ServiceName: input.ServiceName,
-		})
+		}))
+		if IsRunningStatus(status) {
+			running++
+		}
+		statuses = append(statuses, status)
 	}
 
-	containerStatus, _ := common.DockerInspect(input.ContainerID, "{{ .State.Status }}")
-	if containerStatus == "" {
+	if running > 0 && running < len(statuses) {
+		return "degraded"
+	}
+	if running == len(statuses) {
+		for _, status := range []string{"unhealthy", "starting"} {
+			if slices.Contains(statuses, status) {
+				return status
+			}
+		}
+	}
+	return statuses[0]
+}
+
+// containerStatus gets the status of a single container
+func containerStatus(containerID string) string {
+	output, _ := common.DockerInspect(containerID, "{{ .State.Status }} {{ if .State.Health }}{{ .State.Health.Status }}{{ end }}")
+	state, health, _ := strings.Cut(strings.TrimSpace(output), " ")
+	if state == "" {
 		return "missing"
 	}
 
-	return containerStatus
+	if state == "running" && health != "" {
+		return health
+	}
+
+	return state
+}
+
+// IsRunningStatus returns whether a status returned by Status means the container is running
+func IsRunningStatus(status string) bool {
+	switch strings.ToLower(status) {
+	case "running", "starting", "healthy", "unhealthy", "degraded":
+		return true
+	}
+	return false
+}
+
+// HasHealthcheck returns whether a container was created with a healthcheck
+func HasHealthcheck(containerID string) bool {
+	output, _ := common.DockerInspect(containerID, "{{ if .Config.Healthcheck }}{{ len .Config.Healthcheck.Test }}{{ end }}")
+	return output != "" && output != "0"
+}
+
+// RecreateServiceContainerInput is the input for the RecreateServiceContainer function
+type RecreateServiceContainerInput struct {
+	// Datastore is the service to recreate the container for
+	Datastore Datastore
+
+	// ServiceName is the name of the service to recreate the container for
+	ServiceName string
+}
+
+// RecreateServiceContainer removes the service container so container level settings take effect, starting it again if it was running
+func RecreateServiceContainer(ctx context.Context, input RecreateServiceContainerInput) error {
+	runningContainerID := LiveContainerID(ctx, LiveContainerIDInput{
+		Datastore:   input.Datastore,
+		ServiceName: input.ServiceName,
+		Filter:      "status=running",
+	})
+
+	err := RemoveServiceContainer(ctx, RemoveServiceContainerInput{
+		Datastore:   input.Datastore,
+		ServiceName: input.ServiceName,
+	})
+	if err != nil {
+		return err
+	}
+
+	if runningContainerID == "" {
+		return nil
+	}
+
+	return Start(ctx, StartInput{
+		Datastore:   input.Datastore,
+		ServiceName: input.ServiceName,
+	})
 }
 
 // StartInput is the input for the Start function
@@ -514,17 +793,28 @@ type StartInput struct {
 	ServiceName string
 }
 
-// Start starts a service
+// Start starts a service, along with every member of its cluster
 func Start(ctx context.Context, input StartInput) error {
+	for _, member := range ServiceMembers(input.Datastore, input.ServiceName) {
+		if err := startMember(ctx, input, member); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startMember starts the container of a member of a service, creating it when it does not exist
+func startMember(ctx context.Context, input StartInput, member string) error {
 	runningContainerID := LiveContainerID(ctx, LiveContainerIDInput{
 		Datastore:   input.Datastore,
-		ServiceName: input.ServiceName,
 		Filter:      "status=running",
+		Member:      member,
+		ServiceName: input.ServiceName,
 	})
 	if runningContainerID != "" {
 		return common.WriteStringToFile(common.WriteStringToFileInput{
 			Content:   runningContainerID,
-			Filename:  Files(input.Datastore, input.ServiceName).ID,
+			Filename:  MemberIDFile(input.Datastore, input.ServiceName, member),
 			GroupName: SystemGroup(),
 			Mode:      0644,
 			Username:  SystemUser(),
@@ -533,8 +823,9 @@ func Start(ctx context.Context, input StartInput) error {
 
 	previousContainerID := LiveContainerID(ctx, LiveContainerIDInput{
 		Datastore:   input.Datastore,
-		ServiceName: input.ServiceName,
 		Filter:      "status=exited",
+		Member:      member,
+		ServiceName: input.ServiceName,
 	})
 	if previousContainerID != "" {
 		_, err := CallExecCommandWithContext(ctx, common.ExecCommandInput{
@@ -545,6 +836,11 @@ func Start(ctx context.Context, input StartInput) error {
 			return fmt.Errorf("failed to start container: %w", err)
 		}
 
+		// only the service container publishes ports
+		if member != "" {
+			return nil
+		}
+
 		err = ServicePortReconcileStatus(ctx, ServicePortReconcileStatusInput{
 			Datastore:   input.Datastore,
 			ServiceName: input.ServiceName,
@@ -569,6 +865,7 @@ func Start(ctx context.Context, input StartInput) error {
 	}
 	return input.Datastore.CreateServiceContainer(ctx, CreateServiceContainerInput{
 		Datastore:   input.Datastore,
+		Member:      member,
 		ServiceName: input.ServiceName,
 		TaggedImage: taggedImage,
 	})
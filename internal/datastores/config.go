@@ -0,0 +1,44 @@
+package datastores
+
+import (
+	"context"
+)
+
+// ConfigDirective is a directive of the configuration of a service
+type ConfigDirective struct {
+	// Name is the lowercased name of the directive
+	Name string `json:"name"`
+
+	// Value is the value of the directive as written in the configuration file
+	Value string `json:"value"`
+}
+
+// ConfigChange is the outcome of setting or unsetting a directive of a service
+type ConfigChange struct {
+	// Name is the lowercased name of the directive
+	Name string `json:"name"`
+
+	// Value is the new value of the directive, or empty when it was unset
+	Value string `json:"value"`
+
+	// Applied is whether the change was applied to the running service
+	Applied bool `json:"applied"`
+
+	// RestartRequired is whether the running service only picks up the change once restarted
+	RestartRequired bool `json:"restart-required"`
+}
+
+// ConfigManager is implemented by datastores whose configuration file can be edited directive by directive
+type ConfigManager interface {
+	// ConfigDirectives returns the directives set in the configuration file of a service, in file order
+	ConfigDirectives(serviceName string) ([]ConfigDirective, error)
+
+	// EffectiveConfig returns the configuration the running service uses, or that of its configuration file when stopped
+	EffectiveConfig(ctx context.Context, serviceName string) ([]ConfigDirective, error)
+
+	// SetConfigDirective writes a directive to the configuration file of a service, applying it live where possible
+	SetConfigDirective(ctx context.Context, serviceName string, name string, value string) (ConfigChange, error)
+
+	// UnsetConfigDirective removes a directive from the configuration file of a service
+	UnsetConfigDirective(ctx context.Context, serviceName string, name string) (ConfigChange, error)
+}
@@ -0,0 +1,333 @@
+package datastores
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/dokku/dokku/plugins/common"
+	"gopkg.in/yaml.v3"
+)
+
+// DatastoreDefinitionsPath is the directory containing yaml datastore definitions
+var DatastoreDefinitionsPath string
+
+// datastoreDefinitionType matches the datastore types a definition may declare, which are used in paths,
+// container names and property keys
+var datastoreDefinitionType = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// DatastoreDefinition is a datastore described in a yaml file rather than in go
+type DatastoreDefinition struct {
+	// Type is the datastore type, also used as the command prefix
+	Type string `yaml:"type"`
+
+	// Title is the datastore name in title case
+	Title string `yaml:"title"`
+
+	// Image is the default image for the datastore
+	Image string `yaml:"image"`
+
+	// ImageVersion is the default image version for the datastore
+	ImageVersion string `yaml:"image-version"`
+
+	// Ports is the ports the datastore listens on, the first being the primary port
+	Ports []int `yaml:"ports"`
+
+	// WaitPort is the port to wait for when starting the datastore
+	WaitPort int `yaml:"wait-port"`
+
+	// Volumes is the volumes to mount into the container
+	Volumes []DatastoreDefinitionVolume `yaml:"volumes"`
+
+	// Args is the command and arguments to run in the container
+	Args []string `yaml:"args"`
+
+	// Env is the environment variable templates to set on the container
+	Env map[string]string `yaml:"env"`
+
+	// Hardening is what the datastore needs to run in a hardened container
+	Hardening ServiceHardeningSpec `yaml:"hardening"`
+
+	// Healthcheck is the docker healthcheck, where the command is a template
+	Healthcheck ServiceHealthcheckSpec `yaml:"healthcheck"`
+
+	// Password configures password generation for the datastore
+	Password DatastoreDefinitionPassword `yaml:"password"`
+
+	// URL is the template for the datastore dsn
+	URL string `yaml:"url"`
+}
+
+// DatastoreDefinitionVolume is a volume mounted into a defined datastore container
+type DatastoreDefinitionVolume struct {
+	// Source is the service folder to mount, one of config or data
+	Source string `yaml:"source"`
+
+	// Target is the path inside the container
+	Target string `yaml:"target"`
+}
+
+// DatastoreDefinitionPassword configures password generation for a defined datastore
+type DatastoreDefinitionPassword struct {
+	// Length is the length of the generated password, where 0 disables password generation
+	Length int `yaml:"length"`
+}
+
+// DatastoreTemplateData is the data available to env and url templates
+type DatastoreTemplateData struct {
+	// ContainerName is the name of the service container
+	ContainerName string
+
+	// DatabaseName is the sanitized database name for the service
+	DatabaseName string
+
+	// Hostname is the dns hostname of the service
+	Hostname string
+
+	// Password is the service password
+	Password string
+
+	// Port is the primary port of the service
+	Port int
+
+	// ServiceName is the name of the service
+	ServiceName string
+}
+
+// DefinedService is a datastore backed by a yaml definition
+type DefinedService struct {
+	// Definition is the parsed datastore definition
+	Definition DatastoreDefinition
+}
+
+// LoadDatastoreDefinition parses a datastore definition from a yaml file
+func LoadDatastoreDefinition(path string) (*DefinedService, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read datastore definition %s: %w", path, err)
+	}
+
+	definition := DatastoreDefinition{}
+	if err := yaml.Unmarshal(content, &definition); err != nil {
+		return nil, fmt.Errorf("failed to parse datastore definition %s: %w", path, err)
+	}
+
+	if definition.Type == "" {
+		return nil, fmt.Errorf("datastore definition %s is missing a type", path)
+	}
+	if !datastoreDefinitionType.MatchString(definition.Type) {
+		return nil, fmt.Errorf("datastore definition %s has invalid type %s, must start with a lowercase letter and contain only lowercase letters, digits and dashes", path, definition.Type)
+	}
+	if definition.Image == "" {
+		return nil, fmt.Errorf("datastore definition %s is missing an image", path)
+	}
+	if len(definition.Ports) == 0 {
+		return nil, fmt.Errorf("datastore definition %s must specify at least one port", path)
+	}
+	for _, volume := range definition.Volumes {
+		if volume.Source != "config" && volume.Source != "data" {
+			return nil, fmt.Errorf("datastore definition %s has invalid volume source %s, must be config or data", path, volume.Source)
+		}
+	}
+
+	if definition.Title == "" {
+		definition.Title = common.UcFirst(definition.Type)
+	}
+	if definition.ImageVersion == "" {
+		definition.ImageVersion = "latest"
+	}
+	if definition.WaitPort == 0 {
+		definition.WaitPort = definition.Ports[0]
+	}
+
+	return &DefinedService{Definition: definition}, nil
+}
+
+// RegisterDefinedDatastores loads every datastore definition and registers it in Datastores, registering none
+// unless every definition is valid
+func RegisterDefinedDatastores() error {
+	paths := []string{}
+	for _, pattern := range []string{"*.yml", "*.yaml"} {
+		matches, err := filepath.Glob(filepath.Join(DatastoreDefinitionsPath, pattern))
+		if err != nil {
+			return fmt.Errorf("failed to glob datastore definitions: %w", err)
+		}
+		paths = append(paths, matches...)
+	}
+	sort.Strings(paths)
+
+	services := map[string]*DefinedService{}
+	for _, path := range paths {
+		service, err := LoadDatastoreDefinition(path)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := Datastores[service.Definition.Type]; ok {
+			return fmt.Errorf("datastore definition %s conflicts with built-in datastore type %s", path, service.Definition.Type)
+		}
+		if _, ok := services[service.Definition.Type]; ok {
+			return fmt.Errorf("datastore definition %s conflicts with another definition of datastore type %s", path, service.Definition.Type)
+		}
+		services[service.Definition.Type] = service
+	}
+
+	for datastoreType, service := range services {
+		Datastores[datastoreType] = service
+	}
+	return nil
+}
+
+// CreateService creates a new service
+func (s *DefinedService) CreateService(ctx context.Context, serviceName string) error {
+	if s.Definition.Password.Length == 0 {
+		return nil
+	}
+
+	password := os.Getenv("SERVICE_PASSWORD")
+	if password == "" {
+		var err error
+		password, err = GenerateRandomHexString(s.Definition.Password.Length)
+		if err != nil {
+			return fmt.Errorf("unable to generate random hex string: %w", err)
+		}
+	}
+
+	serviceFiles := Files(s, serviceName)
+	err := common.WriteStringToFile(common.WriteStringToFileInput{
+		Content:   password,
+		Filename:  serviceFiles.Password,
+		GroupName: SystemGroup(),
+		Mode:      0640,
+		Username:  SystemUser(),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to write password to %s: %w", serviceFiles.Password, err)
+	}
+
+	return nil
+}
+
+// CreateServiceContainer creates a new service container
+func (s *DefinedService) CreateServiceContainer(ctx context.Context, input CreateServiceContainerInput) error {
+	serviceFolders := MemberFolders(input.Datastore, input.ServiceName, input.Member)
+	volumes := []string{}
+	for _, volume := range s.Definition.Volumes {
+		source := serviceFolders.HostData
+		if volume.Source == "config" {
+			source = serviceFolders.HostConfig
+		}
+		volumes = append(volumes, source+":"+volume.Target)
+	}
+
+	data := s.templateData(input.ServiceName)
+	env := map[string]string{}
+	for key, value := range s.Definition.Env {
+		rendered, err := renderDatastoreTemplate(value, data)
+		if err != nil {
+			return fmt.Errorf("failed to render env variable %s: %w", key, err)
+		}
+		env[key] = rendered
+	}
+
+	healthcheck := s.Definition.Healthcheck
+	if healthcheck.Command != "" {
+		command, err := renderDatastoreTemplate(healthcheck.Command, data)
+		if err != nil {
+			return fmt.Errorf("failed to render healthcheck command: %w", err)
+		}
+		healthcheck.Command = command
+	}
+
+	return RunServiceContainer(ctx, RunServiceContainerInput{
+		Datastore:   input.Datastore,
+		Member:      input.Member,
+		ServiceName: input.ServiceName,
+		TaggedImage: input.TaggedImage,
+		Spec: ServiceContainerSpec{
+			Args:        s.Definition.Args,
+			Env:         env,
+			Hardening:   s.HardeningSpec(),
+			Healthcheck: healthcheck,
+			Volumes:     volumes,
+		},
+	})
+}
+
+// HardeningSpec returns what the definition declares the datastore needs to run in a hardened container
+func (s *DefinedService) HardeningSpec() ServiceHardeningSpec {
+	return s.Definition.Hardening
+}
+
+// Properties returns the properties for a service
+func (s *DefinedService) Properties() ServiceStruct {
+	prefix := strings.ToUpper(strings.ReplaceAll(s.Definition.Type, "-", "_"))
+	return ServiceStruct{
+		CommandPrefix:       s.Definition.Type,
+		ConfigSuffix:        "config",
+		ConfigVariable:      prefix + "_CONFIG_OPTIONS",
+		DefaultImage:        s.Definition.Image,
+		DefaultImageVersion: s.Definition.ImageVersion,
+		EnvVariable:         prefix + "_CUSTOM_ENV",
+		ImagePullVariable:   prefix + "_DISABLE_PULL",
+		Ports:               s.Definition.Ports,
+		WaitPort:            s.Definition.WaitPort,
+	}
+}
+
+// ServiceType returns the type of service
+func (s *DefinedService) ServiceType() string {
+	return s.Definition.Type
+}
+
+// Title returns the service name in title case
+func (s *DefinedService) Title() string {
+	return s.Definition.Title
+}
+
+// URL gets the url for a service
+func (s *DefinedService) URL(serviceName string) string {
+	data := s.templateData(serviceName)
+	if s.Definition.URL == "" {
+		return fmt.Sprintf("%s://%s:%d", s.Definition.Type, data.Hostname, data.Port)
+	}
+
+	url, err := renderDatastoreTemplate(s.Definition.URL, data)
+	if err != nil {
+		return ""
+	}
+	return url
+}
+
+// templateData returns the data available to templates for a service
+func (s *DefinedService) templateData(serviceName string) DatastoreTemplateData {
+	serviceFiles := Files(s, serviceName)
+	return DatastoreTemplateData{
+		ContainerName: ContainerName(s, serviceName),
+		DatabaseName:  common.ReadFirstLine(serviceFiles.DatabaseName),
+		Hostname:      DNSHostname(s, serviceName),
+		Password:      common.ReadFirstLine(serviceFiles.Password),
+		Port:          s.Definition.Ports[0],
+		ServiceName:   serviceName,
+	}
+}
+
+// renderDatastoreTemplate renders a definition template
+func renderDatastoreTemplate(text string, data DatastoreTemplateData) (string, error) {
+	tmpl, err := template.New("datastore").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,103 @@
+package datastores
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRegisterDefinedDatastores(t *testing.T) {
+	tests := []struct {
+		name        string
+		definitions map[string]string
+		wantErr     bool
+		wantTypes   []string
+	}{
+		{
+			name:        "valid",
+			definitions: map[string]string{"a.yml": "type: memcached\nimage: memcached\nports: [11211]\n"},
+			wantTypes:   []string{"memcached"},
+		},
+		{
+			name:        "dashed type",
+			definitions: map[string]string{"a.yml": "type: my-store2\nimage: memcached\nports: [11211]\n"},
+			wantTypes:   []string{"my-store2"},
+		},
+		{
+			name:        "uppercase type",
+			definitions: map[string]string{"a.yml": "type: Memcached\nimage: memcached\nports: [11211]\n"},
+			wantErr:     true,
+		},
+		{
+			name:        "path in type",
+			definitions: map[string]string{"a.yml": "type: ../memcached\nimage: memcached\nports: [11211]\n"},
+			wantErr:     true,
+		},
+		{
+			name:        "built-in type",
+			definitions: map[string]string{"a.yml": "type: redis\nimage: redis\nports: [6379]\n"},
+			wantErr:     true,
+		},
+		{
+			name: "duplicate type",
+			definitions: map[string]string{
+				"a.yml":  "type: memcached\nimage: memcached\nports: [11211]\n",
+				"b.yaml": "type: memcached\nimage: memcached\nports: [11212]\n",
+			},
+			wantErr: true,
+		},
+		{
+			name: "one invalid definition",
+			definitions: map[string]string{
+				"a.yml": "type: memcached\nimage: memcached\nports: [11211]\n",
+				"b.yml": "type: broken\nports: [1234]\n",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.definitions {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			originalPath := DatastoreDefinitionsPath
+			DatastoreDefinitionsPath = dir
+			t.Cleanup(func() {
+				DatastoreDefinitionsPath = originalPath
+				for datastoreType, datastore := range Datastores {
+					if _, ok := datastore.(*DefinedService); ok {
+						delete(Datastores, datastoreType)
+					}
+				}
+			})
+
+			err := RegisterDefinedDatastores()
+			if tt.wantErr && err == nil {
+				t.Errorf("RegisterDefinedDatastores() returned no error")
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("RegisterDefinedDatastores() returned %v", err)
+			}
+
+			registered := 0
+			for _, datastore := range Datastores {
+				if _, ok := datastore.(*DefinedService); ok {
+					registered++
+				}
+			}
+			if registered != len(tt.wantTypes) {
+				t.Errorf("RegisterDefinedDatastores() registered %d datastores, want %d", registered, len(tt.wantTypes))
+			}
+			for _, datastoreType := range tt.wantTypes {
+				if _, ok := Datastores[datastoreType].(*DefinedService); !ok {
+					t.Errorf("RegisterDefinedDatastores() did not register %s", datastoreType)
+				}
+			}
+		})
+	}
+}
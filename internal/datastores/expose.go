@@ -0,0 +1,416 @@
+package datastores
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+	"mvdan.cc/sh/v3/shell"
+)
+
+const (
+	// ExposeModeAmbassador exposes a service through a linked dokku/ambassador container
+	ExposeModeAmbassador = "ambassador"
+
+	// ExposeModeProxy exposes a service through a tcp proxy sidecar joined to the service network
+	ExposeModeProxy = "proxy"
+
+	// ExposeModePublish exposes a service by publishing ports on the service container itself
+	ExposeModePublish = "publish"
+)
+
+// ExposeModes is the list of supported expose modes
+var ExposeModes = []string{ExposeModeAmbassador, ExposeModeProxy, ExposeModePublish}
+
+// ExposeFirewallChain is the iptables chain docker evaluates before its own forwarding rules
+var ExposeFirewallChain = "DOCKER-USER"
+
+// ExposeMode returns the expose mode of a service, defaulting to the ambassador
+func ExposeMode(s Datastore, serviceName string) string {
+	mode := common.PropertyGet(s.Properties().CommandPrefix, serviceName, "expose-mode")
+	if mode == "" {
+		return ExposeModeAmbassador
+	}
+	return mode
+}
+
+// ProxyContainerName returns the name of the proxy sidecar container for a service
+func ProxyContainerName(s Datastore, serviceName string) string {
+	return fmt.Sprintf("%s.proxy", ContainerName(s, serviceName))
+}
+
+// ExposeContainerNames returns the names of every container that may expose a service
+func ExposeContainerNames(s Datastore, serviceName string) []string {
+	return []string{AmbassadorContainerName(s, serviceName), ProxyContainerName(s, serviceName)}
+}
+
+// PublishArgs returns the docker publish flags for a service exposed in publish mode
+func PublishArgs(s Datastore, serviceName string) []string {
+	if ExposeMode(s, serviceName) != ExposeModePublish {
+		return []string{}
+	}
+
+	return publishArgs(s, serviceName)
+}
+
+// publishArgs maps the exposed host ports of a service onto its datastore ports
+func publishArgs(s Datastore, serviceName string) []string {
+	bind := exposeBindPrefix(s, serviceName)
+	datastorePorts := s.Properties().Ports
+	hostPorts := strings.Fields(common.ReadFirstLine(Files(s, serviceName).Port))
+	args := []string{}
+	for i, hostPort := range hostPorts {
+		if i >= len(datastorePorts) {
+			break
+		}
+		args = append(args, fmt.Sprintf("--publish=%s%s:%d", bind, hostPort, datastorePorts[i]))
+	}
+	return args
+}
+
+// ensureExposeContainer starts an existing expose container, or runs a new one with the given arguments
+func ensureExposeContainer(ctx context.Context, containerName string, runArgs func() ([]string, error)) error {
+	if common.ContainerIsRunning(containerName) {
+		return nil
+	}
+
+	if ContainerExists(ctx, containerName) {
+		_, err := CallExecCommandWithContext(ctx, common.ExecCommandInput{
+			Command: common.DockerBin(),
+			Args:    []string{"container", "start", containerName},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to start container %s: %w", containerName, err)
+		}
+		return nil
+	}
+
+	args, err := runArgs()
+	if err != nil {
+		return err
+	}
+
+	_, err = CallExecCommandWithContext(ctx, common.ExecCommandInput{
+		Command: common.DockerBin(),
+		Args:    append([]string{"container", "run", "-d", "--name=" + containerName, "--restart=always"}, args...),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to run container %s: %w", containerName, err)
+	}
+	return nil
+}
+
+// ambassadorRunArgs returns the docker run arguments for the ambassador container of a service
+func ambassadorRunArgs(s Datastore, serviceName string) []string {
+	prefix := s.Properties().CommandPrefix
+	args := []string{
+		"--link=" + fmt.Sprintf("%s:%s", ContainerName(s, serviceName), prefix),
+		"--label=dokku=ambassador",
+		"--label=dokku.ambassador=" + prefix,
+	}
+	args = append(args, publishArgs(s, serviceName)...)
+	return append(args, PluginAmbassadorImage)
+}
+
+// proxyTargetLabel is the label recording the host the proxy sidecar of a service forwards to
+const proxyTargetLabel = "dokku.proxy.target"
+
+// proxyTarget returns the host the proxy sidecar of a service forwards to, along with the network it joins
+//
+// user-defined networks resolve the service by its alias, while the default bridge needs the container ip
+func proxyTarget(ctx context.Context, s Datastore, serviceName string) (string, string, error) {
+	if initialNetwork := InitialNetwork(s, serviceName); initialNetwork != "" {
+		return DNSHostname(s, serviceName), initialNetwork, nil
+	}
+
+	containerID := LiveContainerID(ctx, LiveContainerIDInput{
+		Datastore:   s,
+		ServiceName: serviceName,
+		Filter:      "status=running",
+	})
+	target := ContainerIP(ctx, ContainerIPInput{ContainerID: containerID})
+	if target == "" {
+		return "", "", fmt.Errorf("failed to find the ip address of service %s", serviceName)
+	}
+	return target, "", nil
+}
+
+// ensureProxyContainer starts the proxy sidecar of a service, recreating it when the service has moved to
+// another address since the sidecar was created
+func ensureProxyContainer(ctx context.Context, s Datastore, serviceName string) error {
+	target, network, err := proxyTarget(ctx, s, serviceName)
+	if err != nil {
+		return err
+	}
+
+	containerName := ProxyContainerName(s, serviceName)
+	if ContainerExists(ctx, containerName) {
+		currentTarget, _ := common.DockerInspect(containerName, fmt.Sprintf("{{ index .Config.Labels %q }}", proxyTargetLabel))
+		if strings.TrimSpace(currentTarget) != target {
+			_, err := CallExecCommandWithContext(ctx, common.ExecCommandInput{
+				Command: common.DockerBin(),
+				Args:    []string{"container", "rm", "-f", containerName},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to remove container %s: %w", containerName, err)
+			}
+		}
+	}
+
+	return ensureExposeContainer(ctx, containerName, func() ([]string, error) {
+		return proxyRunArgs(s, serviceName, target, network)
+	})
+}
+
+// proxyRunArgs returns the docker run arguments for the proxy sidecar container of a service
+//
+// The sidecar runs this binary's proxy command in a busybox container, so the binary must be statically linked
+func proxyRunArgs(s Datastore, serviceName string, target string, network string) ([]string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the dokku-datastore binary: %w", err)
+	}
+
+	prefix := s.Properties().CommandPrefix
+	args := []string{
+		"--label=dokku=proxy",
+		"--label=dokku.proxy=" + prefix,
+		"--label=" + proxyTargetLabel + "=" + target,
+		"--volume=" + executable + ":/usr/local/bin/dokku-datastore:ro",
+	}
+	if network != "" {
+		args = append(args, "--network="+network)
+	}
+
+	args = append(args, publishArgs(s, serviceName)...)
+	args = append(args, PluginBusyboxImage, "/usr/local/bin/dokku-datastore", "proxy")
+	for _, port := range s.Properties().Ports {
+		args = append(args, fmt.Sprintf("--forward=%d=%s:%d", port, target, port))
+	}
+	return args, nil
+}
+
+// ExposeAllowList returns the networks allowed to connect to the exposed ports of a service
+func ExposeAllowList(s Datastore, serviceName string) []string {
+	allowList, err := common.FileToSlice(Files(s, serviceName).ExposeAllow)
+	if err != nil {
+		return []string{}
+	}
+	return allowList
+}
+
+// ExposeBind returns the host address the exposed ports of a service are bound to
+func ExposeBind(s Datastore, serviceName string) string {
+	return common.ReadFirstLine(Files(s, serviceName).ExposeBind)
+}
+
+// exposeBindPrefix returns the bind address formatted as a docker publish prefix
+func exposeBindPrefix(s Datastore, serviceName string) string {
+	bind := ExposeBind(s, serviceName)
+	if bind == "" {
+		// the allowlist is enforced with iptables only, so allowlisted ports are never published on ipv6 interfaces
+		if len(ExposeAllowList(s, serviceName)) > 0 {
+			return "0.0.0.0:"
+		}
+		return ""
+	}
+	if strings.Contains(bind, ":") {
+		return "[" + bind + "]:"
+	}
+	return bind + ":"
+}
+
+// NormalizeAllowedNetwork validates an allowlist entry, converting a bare ip into a /32 network
+func NormalizeAllowedNetwork(network string) (string, error) {
+	if !strings.Contains(network, "/") {
+		network = network + "/32"
+	}
+
+	_, ipNet, err := net.ParseCIDR(network)
+	if err != nil {
+		return "", fmt.Errorf("invalid allowed network %s: %w", network, err)
+	}
+	// ipv4-mapped ipv6 addresses parse to 16 byte networks whose prefix length counts the ipv6 bits
+	if len(ipNet.IP) != net.IPv4len {
+		return "", fmt.Errorf("invalid allowed network %s: only ipv4 networks are supported", network)
+	}
+
+	return ipNet.String(), nil
+}
+
+// exposeFirewallComment is the comment used to identify the firewall rules of a service
+func exposeFirewallComment(s Datastore, serviceName string) string {
+	return fmt.Sprintf("dokku-datastore:%s:%s", s.Properties().CommandPrefix, serviceName)
+}
+
+// ExposeFirewallInput is the input for the ApplyExposeFirewall and RemoveExposeFirewall functions
+type ExposeFirewallInput struct {
+	// Datastore is the datastore of the service
+	Datastore Datastore
+
+	// ServiceName is the name of the service
+	ServiceName string
+}
+
+// ApplyExposeFirewall replaces the iptables rules restricting the exposed ports of a service to its allowlist
+func ApplyExposeFirewall(ctx context.Context, input ExposeFirewallInput) error {
+	if err := ensureExposeFirewallChain(ctx); err != nil {
+		return err
+	}
+
+	if err := RemoveExposeFirewall(ctx, input); err != nil {
+		return err
+	}
+
+	allowList := ExposeAllowList(input.Datastore, input.ServiceName)
+	if len(allowList) == 0 {
+		return nil
+	}
+
+	comment := exposeFirewallComment(input.Datastore, input.ServiceName)
+	hostPorts := strings.Fields(common.ReadFirstLine(Files(input.Datastore, input.ServiceName).Port))
+	for _, hostPort := range hostPorts {
+		for _, args := range exposeFirewallRules(hostPort, allowList, comment) {
+			if _, err := CallExecCommandWithContext(ctx, common.ExecCommandInput{
+				Command: "sudo",
+				Args:    args,
+			}); err != nil {
+				return fmt.Errorf("failed to add firewall rule for port %s: %w", hostPort, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// exposeFirewallRules returns the iptables commands restricting a published host port to an allowlist, in the
+// order they must run
+func exposeFirewallRules(hostPort string, allowList []string, comment string) [][]string {
+	// docker has already translated the destination by the time DOCKER-USER runs,
+	// so the published port is matched against the original destination in conntrack
+	match := []string{"-p", "tcp", "-m", "conntrack", "--ctorigdstport", hostPort, "--ctdir", "ORIGINAL"}
+
+	// rules are inserted at the top of the chain, so the drop is inserted before the allows
+	rules := [][]string{append(append([]string{}, match...), "-j", "DROP")}
+	for _, network := range allowList {
+		rules = append(rules, append(append([]string{}, match...), "-s", network, "-j", "RETURN"))
+	}
+
+	commands := [][]string{}
+	for _, rule := range rules {
+		args := append([]string{"iptables", "-I", ExposeFirewallChain}, rule...)
+		args = append(args, "-m", "comment", "--comment", comment)
+		commands = append(commands, args)
+	}
+	return commands
+}
+
+// ensureExposeFirewallChain creates the chain holding the allowlist rules when docker has not created it yet
+//
+// docker keeps the rules of an existing chain when it starts, so rules restored before docker apply from the start
+func ensureExposeFirewallChain(ctx context.Context) error {
+	_, err := CallExecCommandWithContext(ctx, common.ExecCommandInput{
+		Command: "sudo",
+		Args:    []string{"iptables", "-n", "-L", ExposeFirewallChain},
+	})
+	if err == nil {
+		return nil
+	}
+
+	if _, err := CallExecCommandWithContext(ctx, common.ExecCommandInput{
+		Command: "sudo",
+		Args:    []string{"iptables", "-N", ExposeFirewallChain},
+	}); err != nil {
+		return fmt.Errorf("failed to create firewall chain %s: %w", ExposeFirewallChain, err)
+	}
+	return nil
+}
+
+// RestoreExposeFirewalls reapplies the allowlist rules of every exposed service of every datastore
+//
+// Only iptables and the service files are needed, so it can run at boot before docker restarts the
+// ambassador and proxy containers, instead of leaving the exposed ports open until a command runs
+func RestoreExposeFirewalls(ctx context.Context) error {
+	errs := []error{}
+	for _, datastoreType := range slices.Sorted(maps.Keys(Datastores)) {
+		s := Datastores[datastoreType]
+		entries, err := os.ReadDir(filepath.Join(PluginDataRoot, s.Properties().CommandPrefix))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				errs = append(errs, fmt.Errorf("failed to list %s services: %w", datastoreType, err))
+			}
+			continue
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			if err := reconcileExposeFirewall(ctx, s, entry.Name()); err != nil {
+				errs = append(errs, fmt.Errorf("failed to restore firewall of %s service %s: %w", datastoreType, entry.Name(), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// reconcileExposeFirewall reapplies the allowlist rules of an exposed service, as iptables rules do not survive a reboot
+func reconcileExposeFirewall(ctx context.Context, s Datastore, serviceName string) error {
+	if len(ExposeAllowList(s, serviceName)) == 0 {
+		return nil
+	}
+
+	return ApplyExposeFirewall(ctx, ExposeFirewallInput{
+		Datastore:   s,
+		ServiceName: serviceName,
+	})
+}
+
+// RemoveExposeFirewall removes the iptables rules restricting the exposed ports of a service
+func RemoveExposeFirewall(ctx context.Context, input ExposeFirewallInput) error {
+	result, err := CallExecCommandWithContext(ctx, common.ExecCommandInput{
+		Command: "sudo",
+		Args:    []string{"iptables", "-S", ExposeFirewallChain},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list firewall rules: %w", err)
+	}
+
+	comment := exposeFirewallComment(input.Datastore, input.ServiceName)
+	for line := range strings.SplitSeq(result.StdoutContents(), "\n") {
+		fields, err := shell.Fields(line, nil)
+		if err != nil || len(fields) < 2 || fields[0] != "-A" {
+			continue
+		}
+		if !hasExposeFirewallComment(fields, comment) {
+			continue
+		}
+
+		fields[0] = "-D"
+		if _, err := CallExecCommandWithContext(ctx, common.ExecCommandInput{
+			Command: "sudo",
+			Args:    append([]string{"iptables"}, fields...),
+		}); err != nil {
+			return fmt.Errorf("failed to remove firewall rule: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// hasExposeFirewallComment checks if an iptables rule carries the given comment
+func hasExposeFirewallComment(fields []string, comment string) bool {
+	for i := 0; i < len(fields)-1; i++ {
+		if fields[i] == "--comment" && fields[i+1] == comment {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package datastores
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeAllowedNetwork(t *testing.T) {
+	tests := []struct {
+		network string
+		want    string
+		wantErr bool
+	}{
+		{network: "10.0.0.1", want: "10.0.0.1/32"},
+		{network: "10.0.0.0/8", want: "10.0.0.0/8"},
+		{network: "192.168.1.17/24", want: "192.168.1.0/24"},
+		{network: "0.0.0.0/0", want: "0.0.0.0/0"},
+		{network: "10.0.0.1/33", wantErr: true},
+		{network: "10.0.0", wantErr: true},
+		{network: "example.com", wantErr: true},
+		{network: "", wantErr: true},
+		{network: "2001:db8::/32", wantErr: true},
+		{network: "::1", wantErr: true},
+		{network: "::ffff:10.0.0.1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.network, func(t *testing.T) {
+			got, err := NormalizeAllowedNetwork(tt.network)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeAllowedNetwork(%q) error = %v, wantErr %v", tt.network, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeAllowedNetwork(%q) = %q, want %q", tt.network, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExposeFirewallRules(t *testing.T) {
+	comment := "dokku-datastore:redis:lollipop"
+	match := []string{"iptables", "-I", "DOCKER-USER", "-p", "tcp", "-m", "conntrack", "--ctorigdstport", "6379", "--ctdir", "ORIGINAL"}
+	rule := func(args ...string) []string {
+		rule := append(append([]string{}, match...), args...)
+		return append(rule, "-m", "comment", "--comment", comment)
+	}
+
+	tests := []struct {
+		name      string
+		allowList []string
+		want      [][]string
+	}{
+		{
+			name:      "drop only",
+			allowList: []string{},
+			want:      [][]string{rule("-j", "DROP")},
+		},
+		{
+			name:      "drop before allows",
+			allowList: []string{"10.0.0.0/8", "192.168.1.17/32"},
+			want: [][]string{
+				rule("-j", "DROP"),
+				rule("-s", "10.0.0.0/8", "-j", "RETURN"),
+				rule("-s", "192.168.1.17/32", "-j", "RETURN"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := exposeFirewallRules("6379", tt.allowList, comment)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("exposeFirewallRules(%q) = %v, want %v", tt.allowList, got, tt.want)
+			}
+			for _, args := range got {
+				if !hasExposeFirewallComment(args, comment) {
+					t.Errorf("exposeFirewallRules(%q) rule %v is missing the service comment", tt.allowList, args)
+				}
+			}
+		})
+	}
+}
+
+func TestHasExposeFirewallComment(t *testing.T) {
+	comment := "dokku-datastore:redis:lollipop"
+	tests := []struct {
+		name   string
+		fields []string
+		want   bool
+	}{
+		{name: "matching comment", fields: []string{"-A", "DOCKER-USER", "-j", "DROP", "-m", "comment", "--comment", comment}, want: true},
+		{name: "other service", fields: []string{"-A", "DOCKER-USER", "-m", "comment", "--comment", comment + "-2"}, want: false},
+		{name: "no comment", fields: []string{"-A", "DOCKER-USER", "-j", "DROP"}, want: false},
+		{name: "trailing comment flag", fields: []string{"-A", "DOCKER-USER", "--comment"}, want: false},
+		{name: "comment as another value", fields: []string{"-A", "DOCKER-USER", "-s", comment}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasExposeFirewallComment(tt.fields, comment); got != tt.want {
+				t.Errorf("hasExposeFirewallComment(%q) = %v, want %v", tt.fields, got, tt.want)
+			}
+		})
+	}
+}
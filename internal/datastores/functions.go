@@ -6,10 +6,12 @@ import (
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -42,11 +44,62 @@ func AttachNetworksToContainer(ctx context.Context, input AttachNetworksToContai
 	return nil
 }
 
+// createServiceNetwork creates a docker network dedicated to the containers of a service unless it exists
+func createServiceNetwork(ctx context.Context, s Datastore, network string, role string) error {
+	if _, err := common.DockerInspect(network, "{{ .Id }}"); err == nil {
+		return nil
+	}
+
+	_, err := CallExecCommandWithContext(ctx, common.ExecCommandInput{
+		Command: common.DockerBin(),
+		Args:    []string{"network", "create", "--label=dokku=" + role, "--label=dokku." + role + "=" + s.Properties().CommandPrefix, network},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create network %s: %w", network, err)
+	}
+	return nil
+}
+
+// removeServiceNetwork removes a docker network dedicated to the containers of a service when it exists
+func removeServiceNetwork(ctx context.Context, network string) error {
+	if _, err := common.DockerInspect(network, "{{ .Id }}"); err != nil {
+		return nil
+	}
+
+	_, err := CallExecCommandWithContext(ctx, common.ExecCommandInput{
+		Command: common.DockerBin(),
+		Args:    []string{"network", "rm", network},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove network %s: %w", network, err)
+	}
+	return nil
+}
+
+// stdoutWriterKey is the context key of the writer that streamed stdout of commands and triggers goes to
+type stdoutWriterKey struct{}
+
+// WithStdoutWriter returns a context whose commands and triggers stream their stdout to a writer instead of
+// os.Stdout, which json output uses so stdout only carries the json envelope
+func WithStdoutWriter(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, stdoutWriterKey{}, w)
+}
+
+// stdoutWriter returns the writer streamed stdout goes to, or nil when it goes to os.Stdout
+func stdoutWriter(ctx context.Context) io.Writer {
+	w, _ := ctx.Value(stdoutWriterKey{}).(io.Writer)
+	return w
+}
+
 // CallExecCommandWithContext calls a command with a context
 func CallExecCommandWithContext(ctx context.Context, input common.ExecCommandInput) (common.ExecCommandResponse, error) {
 	if os.Getenv("TRACE") != "" {
 		input.PrintCommand = true
 	}
+	if w := stdoutWriter(ctx); w != nil && input.StreamStdout && input.StdoutWriter == nil {
+		input.StreamStdout = false
+		input.StdoutWriter = w
+	}
 	result, err := common.CallExecCommandWithContext(ctx, input)
 	if err != nil {
 		return result, err
@@ -82,6 +135,9 @@ type CommitServiceConfigInput struct {
 	// Datastore is the service to commit the service config for
 	Datastore Datastore
 
+	// Resources is the resource limits to commit for the service
+	Resources ResourceLimits
+
 	// ServiceName is the name of the service to commit the service config for
 	ServiceName string
 
@@ -151,6 +207,15 @@ func CommitServiceConfig(input CommitServiceConfigInput) error {
 		return fmt.Errorf("failed to write shm size to %s: %w", serviceFiles.ShmSize, err)
 	}
 
+	err = WriteResourceLimits(WriteResourceLimitsInput{
+		Datastore:   input.Datastore,
+		Limits:      input.Resources,
+		ServiceName: input.ServiceName,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write resource limits: %w", err)
+	}
+
 	err = common.WriteStringToFile(common.WriteStringToFileInput{
 		Content:   input.Image,
 		Filename:  serviceFiles.Image,
@@ -192,6 +257,83 @@ func CommitServiceConfig(input CommitServiceConfigInput) error {
 	return nil
 }
 
+// DokkuBin returns the path to the dokku binary
+func DokkuBin() string {
+	dokkuBin := os.Getenv("DOKKU_BIN")
+	if dokkuBin == "" {
+		dokkuBin = "dokku"
+	}
+	return dokkuBin
+}
+
+// SetAppConfigInput is the input for the SetAppConfig function
+type SetAppConfigInput struct {
+	// AppName is the name of the app to set the config on
+	AppName string
+
+	// NoRestart is whether to skip restarting the app
+	NoRestart bool
+
+	// Values is the config values to set
+	Values map[string]string
+}
+
+// SetAppConfig sets config values on an app
+func SetAppConfig(ctx context.Context, input SetAppConfigInput) error {
+	args := []string{"config:set"}
+	if input.NoRestart {
+		args = append(args, "--no-restart")
+	}
+	args = append(args, input.AppName)
+	for key, value := range input.Values {
+		args = append(args, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	_, err := CallExecCommandWithContext(ctx, common.ExecCommandInput{
+		Command:      DokkuBin(),
+		Args:         args,
+		StreamStdout: true,
+		StreamStderr: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set config on app %s: %w", input.AppName, err)
+	}
+	return nil
+}
+
+// UnsetAppConfigInput is the input for the UnsetAppConfig function
+type UnsetAppConfigInput struct {
+	// AppName is the name of the app to unset the config on
+	AppName string
+
+	// Keys is the config keys to unset
+	Keys []string
+
+	// NoRestart is whether to skip restarting the app
+	NoRestart bool
+}
+
+// UnsetAppConfig unsets config values on an app
+func UnsetAppConfig(ctx context.Context, input UnsetAppConfigInput) error {
+	args := []string{"config:unset"}
+	if input.NoRestart {
+		args = append(args, "--no-restart")
+	}
+	args = append(args, input.AppName)
+	args = append(args, input.Keys...)
+
+	_, err := CallExecCommandWithContext(ctx, common.ExecCommandInput{
+		Command:      DokkuBin(),
+		Args:         args,
+		StreamStdout: true,
+		StreamStderr: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unset config on app %s: %w", input.AppName, err)
+	}
+	return nil
+}
+
 // GenerateRandomHexString generates a random hex string
 func GenerateRandomHexString(length int) (string, error) {
 	bytes := make([]byte, length/2)
@@ -204,12 +346,27 @@ func GenerateRandomHexString(length int) (string, error) {
 
 // GenerateRandomPorts generates random ports
 func GenerateRandomPorts(iterations int) ([]int, error) {
+	reservations, err := PortReservations()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read port registry: %w", err)
+	}
+
+	// ports held by stopped services are free on the host but must not be handed out again
+	unavailable := map[int]bool{}
+	for _, reservation := range reservations {
+		unavailable[reservation.Port] = true
+	}
+
 	var ports []int
-	for i := 0; i < iterations; i++ {
+	for len(ports) < iterations {
 		port := GetAvailablePort()
 		if port == 0 {
 			return nil, fmt.Errorf("failed to get available port")
 		}
+		if unavailable[port] {
+			continue
+		}
+		unavailable[port] = true
 		ports = append(ports, port)
 	}
 	return ports, nil
@@ -315,6 +472,40 @@ func PullTaggedImage(ctx context.Context, taggedImage string) (bool, error) {
 	return false, errors.New("unspecified error")
 }
 
+// ErrAccessDenied is returned when the user-auth-service trigger does not allow access to a service
+var ErrAccessDenied = errors.New("access denied")
+
+// AuthorizeServiceInput is the input for the AuthorizeService function
+type AuthorizeServiceInput struct {
+	// Datastore is the datastore of the service
+	Datastore Datastore
+
+	// ServiceName is the name of the service to authorize
+	ServiceName string
+
+	// Trace is whether to enable trace output
+	Trace bool
+}
+
+// AuthorizeService checks that the user-auth-service trigger allows the current user access to a service
+func AuthorizeService(ctx context.Context, input AuthorizeServiceInput) error {
+	services, err := FilterServices(ctx, FilterServicesInput{
+		Datastore: input.Datastore,
+		Services:  []string{input.ServiceName},
+		Trace:     input.Trace,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to authorize service: %w", err)
+	}
+
+	if !slices.Contains(services, input.ServiceName) {
+		sshUser, sshName := SSHIdentity()
+		return fmt.Errorf("%w: %s (%s) may not access %s service %s", ErrAccessDenied, sshUser, sshName, input.Datastore.ServiceType(), input.ServiceName)
+	}
+
+	return nil
+}
+
 // FilterServicesInput is the input for the FilterServices function
 type FilterServicesInput struct {
 	// Datastore is the service to filter services for
@@ -354,18 +545,11 @@ func FilterServices(ctx context.Context, input FilterServicesInput) ([]string, e
 	}
 
 	// the output of this trigger should be all the services a user has access to
-	defaultSShUser := os.Getenv("SSH_USER")
-	defaultSShName := os.Getenv("SSH_NAME")
-	if defaultSShUser == "" {
-		defaultSShUser = os.Getenv("USER")
-	}
-	if defaultSShName == "" {
-		defaultSShName = "default"
-	}
+	defaultSShUser, defaultSShName := SSHIdentity()
 
 	pluginCommandPrefix := input.Datastore.Properties().CommandPrefix
 	results, err := CallPlugnTriggerWithContext(ctx, common.PlugnTriggerInput{
-		Trigger: "user-auth-app",
+		Trigger: "user-auth-service",
 		Args:    append([]string{defaultSShUser, defaultSShName, pluginCommandPrefix}, input.Services...),
 		Env: map[string]string{
 			"SSH_NAME": defaultSShName,
@@ -378,7 +562,7 @@ func FilterServices(ctx context.Context, input FilterServicesInput) ([]string, e
 	}
 
 	filteredServices := make([]string, 0)
-	for line := range strings.SplitSeq(results.StderrContents(), "\n") {
+	for line := range strings.SplitSeq(results.StdoutContents(), "\n") {
 		trimmedLine := strings.TrimSpace(line)
 		if trimmedLine == "" {
 			continue
@@ -398,7 +582,34 @@ func CallPlugnTriggerWithContext(ctx context.Context, input common.PlugnTriggerI
 		}, nil
 	}
 
-	return common.CallPlugnTriggerWithContext(ctx, input)
+	w := stdoutWriter(ctx)
+	if w == nil || !input.StreamStdout {
+		return common.CallPlugnTriggerWithContext(ctx, input)
+	}
+
+	// plugn triggers cannot be given a writer, so they are run the way common runs them with stdout moved to
+	// the writer, which keeps the exit code semantics of the trigger the same
+	result, err := common.CallExecCommandWithContext(ctx, common.ExecCommandInput{
+		Command:            "plugn",
+		Args:               append([]string{"trigger", input.Trigger}, input.Args...),
+		DisableStdioBuffer: input.DisableStdioBuffer,
+		Env:                input.Env,
+		Stdin:              input.Stdin,
+		StreamStdio:        input.StreamStdio,
+		StreamStderr:       input.StreamStderr,
+		StdoutWriter:       w,
+	})
+
+	if input.PrintCommand || os.Getenv("DOKKU_TRACE") == "1" {
+		for _, line := range strings.Split(result.Stderr, "\n") {
+			common.LogDebug(fmt.Sprintf("plugn trigger %s stderr: %s", input.Trigger, line))
+		}
+		for _, line := range strings.Split(result.Stdout, "\n") {
+			common.LogDebug(fmt.Sprintf("plugn trigger %s stdout: %s", input.Trigger, line))
+		}
+	}
+
+	return result, err
 }
 
 // ServicePortReconcileStatusInput is the input for the ServicePortReconcileStatus function
@@ -412,74 +623,55 @@ type ServicePortReconcileStatusInput struct {
 
 // ServicePortReconcileStatus reconciles the port for a service
 func ServicePortReconcileStatus(ctx context.Context, input ServicePortReconcileStatusInput) error {
-	serviceProperties := input.Datastore.Properties()
-	serviceFiles := Files(input.Datastore, input.ServiceName)
-	portFile := serviceFiles.Port
-	containerName := ContainerName(input.Datastore, input.ServiceName)
-	ambassadorContainerName := fmt.Sprintf("%s.ambassador", containerName)
-
+	portFile := Files(input.Datastore, input.ServiceName).Port
 	if !common.FileExists(portFile) || common.ReadFirstLine(portFile) == "" {
-		if ContainerExists(ctx, ambassadorContainerName) {
+		for _, containerName := range ExposeContainerNames(input.Datastore, input.ServiceName) {
+			if !ContainerExists(ctx, containerName) {
+				continue
+			}
+
 			_, err := CallExecCommandWithContext(ctx, common.ExecCommandInput{
 				Command: common.DockerBin(),
-				Args:    []string{"container", "stop", ambassadorContainerName},
+				Args:    []string{"container", "stop", containerName},
 			})
 			if err != nil {
-				return fmt.Errorf("failed to stop container %s: %w", ambassadorContainerName, err)
+				return fmt.Errorf("failed to stop container %s: %w", containerName, err)
 			}
 		}
 
 		return nil
 	}
 
-	if common.ContainerIsRunning(ambassadorContainerName) {
-		return nil
-	}
-
-	if ContainerExists(ctx, ambassadorContainerName) {
-		_, err := CallExecCommandWithContext(ctx, common.ExecCommandInput{
-			Command: common.DockerBin(),
-			Args:    []string{"container", "start", ambassadorContainerName},
+	switch ExposeMode(input.Datastore, input.ServiceName) {
+	case ExposeModePublish:
+		// the ports are published by the service container itself
+	case ExposeModeProxy:
+		if err := ensureProxyContainer(ctx, input.Datastore, input.ServiceName); err != nil {
+			return err
+		}
+	default:
+		err := ensureExposeContainer(ctx, AmbassadorContainerName(input.Datastore, input.ServiceName), func() ([]string, error) {
+			return ambassadorRunArgs(input.Datastore, input.ServiceName), nil
 		})
 		if err != nil {
-			return fmt.Errorf("failed to start container %s: %w", ambassadorContainerName, err)
+			return err
 		}
-		return nil
-	}
-
-	portContents, err := common.FileToSlice(portFile)
-	if err != nil {
-		return fmt.Errorf("failed to read port file %s: %w", portFile, err)
-	}
-	if len(portContents) == 0 {
-		return fmt.Errorf("port file %s is empty", portFile)
 	}
 
-	dockerRunOptions := []string{
-		"container",
-		"run",
-		"-d",
-		"--link=" + fmt.Sprintf("%s:%s", containerName, serviceProperties.CommandPrefix),
-		"--name=" + ambassadorContainerName,
-		"--restart=always",
-		"--label=dokku=ambassador",
-		"--label=dokku.ambassador=" + serviceProperties.CommandPrefix,
-	}
+	return reconcileExposeFirewall(ctx, input.Datastore, input.ServiceName)
+}
 
-	for _, port := range portContents {
-		dockerRunOptions = append(dockerRunOptions, "--publish="+port)
+// SSHIdentity returns the ssh user and ssh key name of the caller
+func SSHIdentity() (string, string) {
+	sshUser := os.Getenv("SSH_USER")
+	sshName := os.Getenv("SSH_NAME")
+	if sshUser == "" {
+		sshUser = os.Getenv("USER")
 	}
-
-	dockerRunOptions = append(dockerRunOptions, PluginAmbassadorImage)
-
-	_, err = CallExecCommandWithContext(ctx, common.ExecCommandInput{
-		Command: common.DockerBin(),
-		Args:    dockerRunOptions,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to run container %s: %w", ambassadorContainerName, err)
+	if sshName == "" {
+		sshName = "default"
 	}
-	return nil
+	return sshUser, sshName
 }
 
 // SystemGroup returns the system group
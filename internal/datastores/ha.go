@@ -0,0 +1,294 @@
+package datastores
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// HAMonitorCount is the number of monitor containers watching a high availability service, enough for a quorum of two
+var HAMonitorCount = 3
+
+// HAWatcherUnit is the systemd template unit running ha-watch for a high availability service
+var HAWatcherUnit = "dokku-datastore-ha-watch@.service"
+
+// HAWatcherUnitFolder is the folder the watcher template unit is installed in
+var HAWatcherUnitFolder = "/lib/systemd/system"
+
+// HAManager is implemented by datastores that can run a service as a primary and replicas failed over by monitors
+//
+// The members of a high availability service are the service itself and replica services named after it, and
+// whichever of them the monitors elected is the primary
+type HAManager interface {
+	// ConfigureHA shares the credentials of the service with its members and writes the monitor configuration,
+	// applying both live when running
+	ConfigureHA(ctx context.Context, serviceName string) error
+
+	// StartMonitors runs the monitor containers of a service, starting existing ones
+	StartMonitors(ctx context.Context, serviceName string) error
+
+	// MonitorContainerNames returns the names of the monitor containers of a service
+	MonitorContainerNames(serviceName string) []string
+
+	// CurrentPrimary asks the monitors which member of a service is the primary
+	CurrentPrimary(ctx context.Context, serviceName string) (string, error)
+
+	// WatchFailovers calls fn with the new primary member after every failover, until the context is cancelled
+	WatchFailovers(ctx context.Context, serviceName string, fn func(primary string) error) error
+}
+
+// HAEnabled returns whether a service is a high availability service
+func HAEnabled(s Datastore, serviceName string) bool {
+	return len(HAMembers(s, serviceName)) > 0
+}
+
+// HAMembers returns the replica services of a high availability service
+func HAMembers(s Datastore, serviceName string) []string {
+	members := common.PropertyGet(s.Properties().CommandPrefix, serviceName, "ha-members")
+	if members == "" {
+		return []string{}
+	}
+	return strings.Split(members, ",")
+}
+
+// HAServices returns a high availability service followed by its replica services
+func HAServices(s Datastore, serviceName string) []string {
+	return append([]string{serviceName}, HAMembers(s, serviceName)...)
+}
+
+// HAMemberOf returns the high availability service a replica service belongs to, or empty when it belongs to none
+func HAMemberOf(s Datastore, serviceName string) string {
+	return common.PropertyGet(s.Properties().CommandPrefix, serviceName, "ha-member-of")
+}
+
+// HAMemberName returns the name of a replica service of a high availability service
+func HAMemberName(serviceName string, index int) string {
+	return fmt.Sprintf("%s-replica-%d", serviceName, index)
+}
+
+// HANetwork returns the docker network dedicated to the members and monitors of a high availability service
+func HANetwork(s Datastore, serviceName string) string {
+	return fmt.Sprintf("%s.ha", ContainerName(s, serviceName))
+}
+
+// RecordedHAPrimary returns the member last known to be the primary of a high availability service
+func RecordedHAPrimary(s Datastore, serviceName string) string {
+	primary := common.PropertyGet(s.Properties().CommandPrefix, serviceName, "ha-primary")
+	if primary == "" {
+		return serviceName
+	}
+	return primary
+}
+
+// WriteHAMembersInput is the input for the WriteHAMembers function
+type WriteHAMembersInput struct {
+	// Datastore is the high availability service
+	Datastore Datastore
+
+	// Members is the replica services of the high availability service
+	Members []string
+
+	// ServiceName is the name of the high availability service
+	ServiceName string
+}
+
+// WriteHAMembers records the replica services of a high availability service, and the service they belong to on each
+func WriteHAMembers(input WriteHAMembersInput) error {
+	prefix := input.Datastore.Properties().CommandPrefix
+	for _, member := range input.Members {
+		if err := common.PropertyWrite(prefix, member, "ha-member-of", input.ServiceName); err != nil {
+			return fmt.Errorf("failed to write ha-member-of property: %w", err)
+		}
+	}
+
+	if err := common.PropertyWrite(prefix, input.ServiceName, "ha-members", strings.Join(input.Members, ",")); err != nil {
+		return fmt.Errorf("failed to write ha-members property: %w", err)
+	}
+	return nil
+}
+
+// ForgetHAMembers removes the high availability and replication properties of a service and its members,
+// so each can be destroyed on its own
+func ForgetHAMembers(s Datastore, serviceName string) error {
+	prefix := s.Properties().CommandPrefix
+	for _, member := range HAServices(s, serviceName) {
+		for _, property := range []string{"ha-member-of", "replica-of"} {
+			if !common.PropertyExists(prefix, member, property) {
+				continue
+			}
+			if err := common.PropertyDelete(prefix, member, property); err != nil {
+				return fmt.Errorf("failed to remove %s property: %w", property, err)
+			}
+		}
+	}
+	return nil
+}
+
+// CreateHANetwork creates the dedicated network of a high availability service unless it exists
+func CreateHANetwork(ctx context.Context, s Datastore, serviceName string) error {
+	return createServiceNetwork(ctx, s, HANetwork(s, serviceName), "ha")
+}
+
+// RemoveHANetwork removes the dedicated network of a high availability service when it exists
+func RemoveHANetwork(ctx context.Context, s Datastore, serviceName string) error {
+	return removeServiceNetwork(ctx, HANetwork(s, serviceName))
+}
+
+// haWatcherUnitName returns the instance of the watcher template unit for a high availability service
+func haWatcherUnitName(s Datastore, serviceName string) string {
+	return strings.Replace(HAWatcherUnit, "@", fmt.Sprintf("@%s:%s", s.ServiceType(), serviceName), 1)
+}
+
+// StartHAWatcher enables the systemd unit running ha-watch for a high availability service, so failovers are
+// recorded and trigger service-action without an operator watching
+//
+// Nothing happens on hosts without the template unit, where ha-watch must be kept running by other means
+func StartHAWatcher(ctx context.Context, s Datastore, serviceName string) error {
+	if !common.FileExists(filepath.Join(HAWatcherUnitFolder, HAWatcherUnit)) {
+		return nil
+	}
+
+	unit := haWatcherUnitName(s, serviceName)
+	if _, err := CallExecCommandWithContext(ctx, common.ExecCommandInput{
+		Command: "sudo",
+		Args:    []string{"systemctl", "enable", "--now", unit},
+	}); err != nil {
+		return fmt.Errorf("failed to start %s: %w", unit, err)
+	}
+	return nil
+}
+
+// StopHAWatcher disables the systemd unit running ha-watch for a high availability service
+func StopHAWatcher(ctx context.Context, s Datastore, serviceName string) error {
+	if !common.FileExists(filepath.Join(HAWatcherUnitFolder, HAWatcherUnit)) {
+		return nil
+	}
+
+	unit := haWatcherUnitName(s, serviceName)
+	if _, err := CallExecCommandWithContext(ctx, common.ExecCommandInput{
+		Command: "sudo",
+		Args:    []string{"systemctl", "disable", "--now", unit},
+	}); err != nil {
+		return fmt.Errorf("failed to stop %s: %w", unit, err)
+	}
+	return nil
+}
+
+// RemoveHAMonitors removes the monitor containers of a high availability service
+func RemoveHAMonitors(ctx context.Context, s Datastore, serviceName string) error {
+	manager, ok := s.(HAManager)
+	if !ok {
+		return nil
+	}
+
+	for _, containerName := range manager.MonitorContainerNames(serviceName) {
+		if !ContainerExists(ctx, containerName) {
+			continue
+		}
+		if err := RemoveContainer(ctx, containerName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HAPrimaryInput is the input for the HAPrimary function
+type HAPrimaryInput struct {
+	// Datastore is the high availability service
+	Datastore Datastore
+
+	// ServiceName is the name of the high availability service
+	ServiceName string
+}
+
+// HAPrimary returns the member the monitors of a service elected as primary, falling back to the one last recorded
+func HAPrimary(ctx context.Context, input HAPrimaryInput) string {
+	if !HAEnabled(input.Datastore, input.ServiceName) {
+		return ""
+	}
+
+	manager, ok := input.Datastore.(HAManager)
+	if !ok {
+		return RecordedHAPrimary(input.Datastore, input.ServiceName)
+	}
+
+	primary, err := manager.CurrentPrimary(ctx, input.ServiceName)
+	if err != nil {
+		return RecordedHAPrimary(input.Datastore, input.ServiceName)
+	}
+	return primary
+}
+
+// ReconcileHAPrimaryInput is the input for the ReconcileHAPrimary function
+type ReconcileHAPrimaryInput struct {
+	// Datastore is the high availability service
+	Datastore Datastore
+
+	// Primary is the member that became the primary, where empty asks the monitors
+	Primary string
+
+	// ServiceName is the name of the high availability service
+	ServiceName string
+}
+
+// ReconcileHAPrimary records a failover of a high availability service, returning the previous primary
+//
+// The replication properties of the members are pointed at the new primary and the service-action trigger
+// is called with failover, the datastore type, the service, the new primary and the previous primary.
+// Nothing happens when the primary did not change.
+func ReconcileHAPrimary(ctx context.Context, input ReconcileHAPrimaryInput) (string, error) {
+	manager, ok := input.Datastore.(HAManager)
+	if !ok {
+		return "", fmt.Errorf("%s services do not support high availability", input.Datastore.ServiceType())
+	}
+
+	if !HAEnabled(input.Datastore, input.ServiceName) {
+		return "", fmt.Errorf("service %s is not a high availability service", input.ServiceName)
+	}
+
+	primary := input.Primary
+	if primary == "" {
+		var err error
+		primary, err = manager.CurrentPrimary(ctx, input.ServiceName)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	previous := RecordedHAPrimary(input.Datastore, input.ServiceName)
+	if primary == previous {
+		return previous, nil
+	}
+
+	prefix := input.Datastore.Properties().CommandPrefix
+	for _, member := range HAServices(input.Datastore, input.ServiceName) {
+		var err error
+		if member != primary {
+			err = common.PropertyWrite(prefix, member, "replica-of", primary)
+		} else if common.PropertyExists(prefix, member, "replica-of") {
+			err = common.PropertyDelete(prefix, member, "replica-of")
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to update replica-of property of %s: %w", member, err)
+		}
+	}
+
+	if err := common.PropertyWrite(prefix, input.ServiceName, "ha-primary", primary); err != nil {
+		return "", fmt.Errorf("failed to write ha-primary property: %w", err)
+	}
+
+	_, err := CallPlugnTriggerWithContext(ctx, common.PlugnTriggerInput{
+		Trigger:      "service-action",
+		Args:         []string{"failover", input.Datastore.ServiceType(), input.ServiceName, primary, previous},
+		StreamStderr: true,
+		StreamStdout: true,
+	})
+	if err != nil {
+		return previous, fmt.Errorf("failed to call service-action failover trigger: %w", err)
+	}
+
+	return previous, nil
+}
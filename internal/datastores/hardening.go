@@ -0,0 +1,262 @@
+package datastores
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// HardeningGlobalScope is the property scope holding the hardening defaults for every service of a datastore
+const HardeningGlobalScope = "--global"
+
+// hardeningNumericUser matches a numeric uid with an optional gid
+var hardeningNumericUser = regexp.MustCompile(`^[0-9]+(:[0-9]+)?$`)
+
+// HardeningSettings is the hardening configuration of a service container
+type HardeningSettings struct {
+	// Enabled is whether the container runs read-only, without capabilities and as a non-root user
+	Enabled bool `json:"enabled"`
+
+	// User is the user the container runs as, overriding the datastore default
+	User string `json:"user,omitempty"`
+
+	// SeccompProfile is the path to a custom seccomp profile
+	SeccompProfile string `json:"seccomp-profile,omitempty"`
+
+	// AppArmorProfile is the name of a custom apparmor profile
+	AppArmorProfile string `json:"apparmor-profile,omitempty"`
+}
+
+// HardeningPropertyKeys is the list of properties hardening settings are stored in
+var HardeningPropertyKeys = []string{"hardening", "hardening-user", "hardening-seccomp-profile", "hardening-apparmor-profile"}
+
+// ServiceHardener is implemented by datastores that know what they need to run in a hardened container
+type ServiceHardener interface {
+	// HardeningSpec returns what the datastore needs to run in a hardened container
+	HardeningSpec() ServiceHardeningSpec
+}
+
+// Hardening returns the hardening settings of a service, where service settings override the datastore defaults
+func Hardening(s Datastore, serviceName string) HardeningSettings {
+	return hardeningSettings(s, serviceName, nil)
+}
+
+// hardeningSettings returns the hardening settings of a service as they would be once the given properties
+// are changed, where an empty value falls back to the datastore default
+func hardeningSettings(s Datastore, serviceName string, changes map[string]string) HardeningSettings {
+	prefix := s.Properties().CommandPrefix
+	value := func(key string) string {
+		if changed, ok := changes[key]; ok {
+			if changed != "" || serviceName == HardeningGlobalScope {
+				return changed
+			}
+			return common.PropertyGet(prefix, HardeningGlobalScope, key)
+		}
+		if common.PropertyExists(prefix, serviceName, key) {
+			return common.PropertyGet(prefix, serviceName, key)
+		}
+		return common.PropertyGet(prefix, HardeningGlobalScope, key)
+	}
+
+	return HardeningSettings{
+		Enabled:         value("hardening") == "true",
+		User:            value("hardening-user"),
+		SeccompProfile:  value("hardening-seccomp-profile"),
+		AppArmorProfile: value("hardening-apparmor-profile"),
+	}
+}
+
+// String returns the hardening settings for display
+func (h HardeningSettings) String() string {
+	values := []string{"disabled"}
+	if h.Enabled {
+		values = []string{"enabled"}
+		if h.User != "" {
+			values = append(values, "user="+h.User)
+		}
+	}
+	if h.SeccompProfile != "" {
+		values = append(values, "seccomp="+h.SeccompProfile)
+	}
+	if h.AppArmorProfile != "" {
+		values = append(values, "apparmor="+h.AppArmorProfile)
+	}
+	return strings.Join(values, " ")
+}
+
+// CreateArgs returns the docker container create flags applying the hardening settings
+//
+// Custom seccomp and apparmor profiles apply even when the rest of the hardening is disabled
+func (h HardeningSettings) CreateArgs(spec ServiceHardeningSpec) []string {
+	args := []string{}
+	if h.Enabled {
+		args = append(args, "--read-only", "--cap-drop=ALL", "--security-opt=no-new-privileges:true")
+		for _, capability := range spec.CapAdd {
+			args = append(args, "--cap-add="+capability)
+		}
+
+		tmpfs := spec.Tmpfs
+		if len(tmpfs) == 0 {
+			tmpfs = []string{"/tmp"}
+		}
+		for _, path := range tmpfs {
+			args = append(args, "--tmpfs="+path)
+		}
+
+		if user := h.containerUser(spec); user != "" {
+			args = append(args, "--user="+user)
+		}
+	}
+	if h.SeccompProfile != "" {
+		args = append(args, "--security-opt=seccomp="+h.SeccompProfile)
+	}
+	if h.AppArmorProfile != "" {
+		args = append(args, "--security-opt=apparmor="+h.AppArmorProfile)
+	}
+	return args
+}
+
+// containerUser returns the user a hardened container runs as
+func (h HardeningSettings) containerUser(spec ServiceHardeningSpec) string {
+	if h.User != "" {
+		return h.User
+	}
+	return spec.User
+}
+
+// ValidateUser checks that a hardened container runs as a non-root user, which the datastore or the
+// hardening-user setting must provide
+func (h HardeningSettings) ValidateUser(spec ServiceHardeningSpec) error {
+	if !h.Enabled {
+		return nil
+	}
+
+	user := h.containerUser(spec)
+	if user == "" {
+		return fmt.Errorf("hardened containers must run as a non-root user, set one with hardening-user")
+	}
+	if isRootUser(user) {
+		return fmt.Errorf("hardened containers must run as a non-root user")
+	}
+	return nil
+}
+
+// isRootUser checks if a user or uid:gid pair refers to the root user
+func isRootUser(user string) bool {
+	name, _, _ := strings.Cut(user, ":")
+	return name == "0" || name == "root"
+}
+
+// SetHardeningInput is the input for the SetHardening function
+type SetHardeningInput struct {
+	// Datastore is the datastore to set the hardening settings for
+	Datastore Datastore
+
+	// ServiceName is the name of the service to set the hardening settings for, or HardeningGlobalScope
+	ServiceName string
+
+	// Values is the properties to change, where an empty value falls back to the datastore default
+	Values map[string]string
+}
+
+// SetHardening persists hardening settings for a service or as the datastore default
+//
+// Settings that would leave a service without a non-root user are rejected before any is written
+func SetHardening(input SetHardeningInput) error {
+	prefix := input.Datastore.Properties().CommandPrefix
+	if hardener, ok := input.Datastore.(ServiceHardener); ok && input.ServiceName != HardeningGlobalScope {
+		settings := hardeningSettings(input.Datastore, input.ServiceName, input.Values)
+		if err := settings.ValidateUser(hardener.HardeningSpec()); err != nil {
+			return err
+		}
+	}
+
+	for key, value := range input.Values {
+		switch key {
+		case "hardening":
+			if value != "" && value != "true" && value != "false" {
+				return fmt.Errorf("invalid hardening value %s, must be true or false", value)
+			}
+		case "hardening-user":
+			if isRootUser(value) {
+				return fmt.Errorf("hardened containers must run as a non-root user")
+			}
+		case "hardening-seccomp-profile":
+			if value != "" && value != "unconfined" && !common.FileExists(value) {
+				return fmt.Errorf("seccomp profile %s does not exist", value)
+			}
+		case "hardening-apparmor-profile":
+		default:
+			return fmt.Errorf("unknown hardening setting %s", key)
+		}
+
+		if value == "" {
+			if common.PropertyExists(prefix, input.ServiceName, key) {
+				if err := common.PropertyDelete(prefix, input.ServiceName, key); err != nil {
+					return fmt.Errorf("failed to remove %s property: %w", key, err)
+				}
+			}
+			continue
+		}
+
+		if err := common.PropertyWrite(prefix, input.ServiceName, key, value); err != nil {
+			return fmt.Errorf("failed to write %s property: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// prepareHardenedDataFolder hands the data folder to the non-root user of a hardened container
+//
+// Only numeric users can be applied from outside the image, so named users must already own the folder
+func prepareHardenedDataFolder(ctx context.Context, s Datastore, serviceName string, spec ServiceHardeningSpec) error {
+	hardening := Hardening(s, serviceName)
+	user := hardening.containerUser(spec)
+	if !hardening.Enabled || !hardeningNumericUser.MatchString(user) {
+		return nil
+	}
+
+	uid, gid, ok := strings.Cut(user, ":")
+	if !ok {
+		gid = uid
+	}
+	if _, err := strconv.Atoi(gid); err != nil {
+		return fmt.Errorf("invalid group in hardening user %s", user)
+	}
+
+	// a data folder whose root already has the owner is assumed to have been chowned whole before
+	serviceFolders := Folders(s, serviceName)
+	if folderOwnedBy(serviceFolders.Data, uid, gid) {
+		return nil
+	}
+
+	_, err := CallExecCommandWithContext(ctx, common.ExecCommandInput{
+		Command: common.DockerBin(),
+		Args:    []string{"container", "run", "--rm", "-v", serviceFolders.HostData + ":/data", PluginBusyboxImage, "chown", "-R", uid + ":" + gid, "/data"},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to change the owner of the data folder: %w", err)
+	}
+	return nil
+}
+
+// folderOwnedBy returns whether a folder is owned by a numeric uid and gid
+func folderOwnedBy(path string, uid string, gid string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return false
+	}
+	return strconv.FormatUint(uint64(stat.Uid), 10) == uid && strconv.FormatUint(uint64(stat.Gid), 10) == gid
+}
@@ -0,0 +1,116 @@
+package datastores
+
+import (
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestHardeningCreateArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings HardeningSettings
+		spec     ServiceHardeningSpec
+		want     []string
+	}{
+		{name: "disabled", settings: HardeningSettings{}, spec: ServiceHardeningSpec{User: "999:999"}, want: []string{}},
+		{
+			name:     "disabled with profiles",
+			settings: HardeningSettings{SeccompProfile: "/etc/seccomp.json", AppArmorProfile: "docker-default"},
+			want:     []string{"--security-opt=seccomp=/etc/seccomp.json", "--security-opt=apparmor=docker-default"},
+		},
+		{
+			name:     "enabled with defaults",
+			settings: HardeningSettings{Enabled: true},
+			spec:     ServiceHardeningSpec{User: "999:999"},
+			want:     []string{"--read-only", "--cap-drop=ALL", "--security-opt=no-new-privileges:true", "--tmpfs=/tmp", "--user=999:999"},
+		},
+		{
+			name:     "enabled with datastore spec",
+			settings: HardeningSettings{Enabled: true},
+			spec:     ServiceHardeningSpec{CapAdd: []string{"CHOWN", "SETUID"}, Tmpfs: []string{"/run", "/var/run"}, User: "redis"},
+			want: []string{
+				"--read-only", "--cap-drop=ALL", "--security-opt=no-new-privileges:true",
+				"--cap-add=CHOWN", "--cap-add=SETUID", "--tmpfs=/run", "--tmpfs=/var/run", "--user=redis",
+			},
+		},
+		{
+			name:     "user setting overrides datastore user",
+			settings: HardeningSettings{Enabled: true, User: "1000:1000", SeccompProfile: "/etc/seccomp.json"},
+			spec:     ServiceHardeningSpec{User: "999:999"},
+			want: []string{
+				"--read-only", "--cap-drop=ALL", "--security-opt=no-new-privileges:true", "--tmpfs=/tmp",
+				"--user=1000:1000", "--security-opt=seccomp=/etc/seccomp.json",
+			},
+		},
+		{
+			name:     "enabled without a user",
+			settings: HardeningSettings{Enabled: true},
+			want:     []string{"--read-only", "--cap-drop=ALL", "--security-opt=no-new-privileges:true", "--tmpfs=/tmp"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.settings.CreateArgs(tt.spec); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CreateArgs(%v) = %v, want %v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHardeningValidateUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings HardeningSettings
+		spec     ServiceHardeningSpec
+		wantErr  bool
+	}{
+		{name: "disabled as root", settings: HardeningSettings{User: "root"}, wantErr: false},
+		{name: "datastore user", settings: HardeningSettings{Enabled: true}, spec: ServiceHardeningSpec{User: "999:999"}, wantErr: false},
+		{name: "user setting", settings: HardeningSettings{Enabled: true, User: "redis"}, wantErr: false},
+		{name: "no user", settings: HardeningSettings{Enabled: true}, wantErr: true},
+		{name: "root name", settings: HardeningSettings{Enabled: true, User: "root"}, wantErr: true},
+		{name: "root uid", settings: HardeningSettings{Enabled: true, User: "0"}, wantErr: true},
+		{name: "root uid with gid", settings: HardeningSettings{Enabled: true, User: "0:999"}, wantErr: true},
+		{name: "root datastore user", settings: HardeningSettings{Enabled: true}, spec: ServiceHardeningSpec{User: "0:0"}, wantErr: true},
+		{name: "root gid only", settings: HardeningSettings{Enabled: true, User: "999:0"}, wantErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.settings.ValidateUser(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateUser(%v) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFolderOwnedBy(t *testing.T) {
+	dir := t.TempDir()
+	uid := strconv.Itoa(os.Getuid())
+	gid := strconv.Itoa(os.Getgid())
+
+	tests := []struct {
+		name string
+		path string
+		uid  string
+		gid  string
+		want bool
+	}{
+		{name: "owner", path: dir, uid: uid, gid: gid, want: true},
+		{name: "other uid", path: dir, uid: uid + "1", gid: gid, want: false},
+		{name: "other gid", path: dir, uid: uid, gid: gid + "1", want: false},
+		{name: "missing folder", path: dir + "/missing", uid: uid, gid: gid, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := folderOwnedBy(tt.path, tt.uid, tt.gid); got != tt.want {
+				t.Errorf("folderOwnedBy(%q, %q, %q) = %v, want %v", tt.path, tt.uid, tt.gid, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package datastores
+
+import (
+	"context"
+	"fmt"
+)
+
+// HealthStatus is the overall health of a service
+type HealthStatus string
+
+const (
+	// HealthStatusHealthy means the service is fully operational
+	HealthStatusHealthy HealthStatus = "healthy"
+
+	// HealthStatusDegraded means the service is reachable but has problems
+	HealthStatusDegraded HealthStatus = "degraded"
+
+	// HealthStatusDown means the service is unreachable
+	HealthStatusDown HealthStatus = "down"
+)
+
+// severity returns the ordering of a health status, where higher is worse
+func (h HealthStatus) severity() int {
+	switch h {
+	case HealthStatusHealthy:
+		return 0
+	case HealthStatusDegraded:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// HealthCheck is the result of a single health check
+type HealthCheck struct {
+	// Name is the name of the check
+	Name string `json:"name"`
+
+	// Status is the status of the check
+	Status HealthStatus `json:"status"`
+
+	// Message is a human readable description of the check result
+	Message string `json:"message"`
+}
+
+// HealthReport is the result of all health checks for a service
+type HealthReport struct {
+	// Status is the worst status across all checks
+	Status HealthStatus `json:"status"`
+
+	// Checks are the individual check results
+	Checks []HealthCheck `json:"checks"`
+}
+
+// Add adds a check to the report, downgrading the overall status if necessary
+func (r *HealthReport) Add(check HealthCheck) {
+	r.Checks = append(r.Checks, check)
+	if r.Status == "" || check.Status.severity() > r.Status.severity() {
+		r.Status = check.Status
+	}
+}
+
+// HealthInput is the input for the Health function
+type HealthInput struct {
+	// Datastore is the service to check the health of
+	Datastore Datastore
+
+	// ServiceName is the name of the service to check the health of
+	ServiceName string
+}
+
+// Health checks the health of a service, using protocol-level checks when the datastore supports them
+func Health(ctx context.Context, input HealthInput) (HealthReport, error) {
+	if checker, ok := input.Datastore.(HealthChecker); ok {
+		return checker.Health(ctx, input.ServiceName)
+	}
+
+	report := HealthReport{}
+	report.Add(ContainerHealthCheck(ctx, input.Datastore, input.ServiceName))
+	return report, nil
+}
+
+// ContainerHealthCheck checks that the service container is running
+func ContainerHealthCheck(ctx context.Context, s Datastore, serviceName string) HealthCheck {
+	status := Status(ctx, StatusInput{
+		Datastore:   s,
+		ServiceName: serviceName,
+	})
+	if !IsRunningStatus(status) || status == "unhealthy" {
+		return HealthCheck{
+			Name:    "container",
+			Status:  HealthStatusDown,
+			Message: fmt.Sprintf("container is %s", status),
+		}
+	}
+
+	if status == "starting" {
+		return HealthCheck{
+			Name:    "container",
+			Status:  HealthStatusDegraded,
+			Message: "container healthcheck is starting",
+		}
+	}
+
+	if status == "degraded" {
+		return HealthCheck{
+			Name:    "container",
+			Status:  HealthStatusDegraded,
+			Message: "some cluster members are not running",
+		}
+	}
+
+	return HealthCheck{
+		Name:    "container",
+		Status:  HealthStatusHealthy,
+		Message: "container is running",
+	}
+}
@@ -41,6 +41,9 @@ type CreateServiceContainerInput struct {
 	// Datastore is the service to create the container for
 	Datastore Datastore
 
+	// Member is the cluster member to create the container for, where empty is the service container
+	Member string
+
 	// ServiceName is the name of the service to create the container for
 	ServiceName string
 
@@ -69,6 +72,12 @@ type Datastore interface {
 	URL(serviceName string) string
 }
 
+// HealthChecker is implemented by datastores that can perform protocol-level health checks
+type HealthChecker interface {
+	// Health returns the health report for a service
+	Health(ctx context.Context, serviceName string) (HealthReport, error)
+}
+
 var (
 	// PluginDataRoot is the root of the plugin data
 	PluginDataRoot string
@@ -108,6 +117,7 @@ func init() {
 	}
 
 	PluginPath = filepath.Join(DokkuLibRoot, "plugins")
+	DatastoreDefinitionsPath = filepath.Join(PluginPath, "datastores")
 	PluginDataRoot = filepath.Join(DokkuLibRoot, "services")
 
 	Datastores["redis"] = &RedisService{}
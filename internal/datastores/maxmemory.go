@@ -0,0 +1,118 @@
+package datastores
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// DefaultMaxmemoryPercent is the share of the MEMORY limit a datastore may use when none is set,
+// leaving the rest for connections, buffers and forks
+var DefaultMaxmemoryPercent = 75
+
+// MaxmemoryConfigurer is implemented by datastores that can cap their own memory use below the MEMORY limit,
+// evicting data instead of being killed by the kernel
+type MaxmemoryConfigurer interface {
+	// ConfigureMaxmemory rewrites the service configuration from its MEMORY limit and maxmemory settings,
+	// applying it live when running
+	ConfigureMaxmemory(ctx context.Context, serviceName string) error
+
+	// MaxmemoryPolicies returns the eviction policies the datastore supports
+	MaxmemoryPolicies() []string
+}
+
+// MaxmemoryPercent returns the share of the MEMORY limit a service may use
+func MaxmemoryPercent(s Datastore, serviceName string) int {
+	percent, err := strconv.Atoi(common.PropertyGet(s.Properties().CommandPrefix, serviceName, "maxmemory-percent"))
+	if err != nil || percent == 0 {
+		return DefaultMaxmemoryPercent
+	}
+	return percent
+}
+
+// MaxmemoryPolicy returns the eviction policy of a service, or empty for the datastore default
+func MaxmemoryPolicy(s Datastore, serviceName string) string {
+	return common.PropertyGet(s.Properties().CommandPrefix, serviceName, "maxmemory-policy")
+}
+
+// MaxmemoryMegabytes returns the memory a service may use in megabytes, or 0 when it has no MEMORY limit
+func MaxmemoryMegabytes(s Datastore, serviceName string) int {
+	memory, _ := strconv.Atoi(common.ReadFirstLine(Files(s, serviceName).Memory))
+	return memory * MaxmemoryPercent(s, serviceName) / 100
+}
+
+// ValidateMaxmemorySettings checks a maxmemory percent and eviction policy, where zero values are the defaults
+func ValidateMaxmemorySettings(s Datastore, percent int, policy string) error {
+	configurer, ok := s.(MaxmemoryConfigurer)
+	if !ok {
+		if percent != 0 || policy != "" {
+			return fmt.Errorf("%s services do not support maxmemory", s.ServiceType())
+		}
+		return nil
+	}
+
+	if percent != 0 && (percent < 1 || percent > 100) {
+		return fmt.Errorf("invalid maxmemory percent %d, must be between 1 and 100", percent)
+	}
+	if policies := configurer.MaxmemoryPolicies(); policy != "" && !slices.Contains(policies, policy) {
+		return fmt.Errorf("invalid maxmemory policy %s, must be one of %s", policy, strings.Join(policies, ", "))
+	}
+	return nil
+}
+
+// SetMaxmemoryPercent sets the share of the MEMORY limit a service may use, where 0 restores the default
+func SetMaxmemoryPercent(s Datastore, serviceName string, percent int) error {
+	if err := ValidateMaxmemorySettings(s, percent, ""); err != nil {
+		return err
+	}
+
+	prefix := s.Properties().CommandPrefix
+	if percent == 0 {
+		if !common.PropertyExists(prefix, serviceName, "maxmemory-percent") {
+			return nil
+		}
+		return common.PropertyDelete(prefix, serviceName, "maxmemory-percent")
+	}
+
+	if err := common.PropertyWrite(prefix, serviceName, "maxmemory-percent", strconv.Itoa(percent)); err != nil {
+		return fmt.Errorf("failed to write maxmemory-percent property: %w", err)
+	}
+	return nil
+}
+
+// SetMaxmemoryPolicy sets the eviction policy of a service, where empty restores the datastore default
+func SetMaxmemoryPolicy(s Datastore, serviceName string, policy string) error {
+	if err := ValidateMaxmemorySettings(s, 0, policy); err != nil {
+		return err
+	}
+
+	prefix := s.Properties().CommandPrefix
+	if policy == "" {
+		if !common.PropertyExists(prefix, serviceName, "maxmemory-policy") {
+			return nil
+		}
+		return common.PropertyDelete(prefix, serviceName, "maxmemory-policy")
+	}
+
+	if err := common.PropertyWrite(prefix, serviceName, "maxmemory-policy", policy); err != nil {
+		return fmt.Errorf("failed to write maxmemory-policy property: %w", err)
+	}
+	return nil
+}
+
+// ConfigureMaxmemory applies the MEMORY limit and maxmemory settings of a service when the datastore supports it
+func ConfigureMaxmemory(ctx context.Context, s Datastore, serviceName string) error {
+	configurer, ok := s.(MaxmemoryConfigurer)
+	if !ok {
+		return nil
+	}
+
+	if err := configurer.ConfigureMaxmemory(ctx, serviceName); err != nil {
+		return fmt.Errorf("failed to configure maxmemory: %w", err)
+	}
+	return nil
+}
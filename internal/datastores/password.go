@@ -0,0 +1,100 @@
+package datastores
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// PasswordRotator is implemented by datastores that can change the password of a running service
+type PasswordRotator interface {
+	// RotatePassword makes password the service password, keeping the current one valid when keepPrevious is set
+	RotatePassword(ctx context.Context, serviceName string, password string, keepPrevious bool) error
+
+	// RevokePreviousPassword stops accepting the password that was kept valid by the last rotation
+	RevokePreviousPassword(ctx context.Context, serviceName string) error
+}
+
+// RotatePasswordInput is the input for the RotatePassword function
+type RotatePasswordInput struct {
+	// Datastore is the service to rotate the password of
+	Datastore Datastore
+
+	// KeepPrevious is whether the current password stays valid until it is revoked
+	KeepPrevious bool
+
+	// Password is the new password, where empty generates one
+	Password string
+
+	// ServiceName is the name of the service to rotate the password of
+	ServiceName string
+}
+
+// RotatePassword changes the password of a service without restarting it and persists it to the PASSWORD file
+func RotatePassword(ctx context.Context, input RotatePasswordInput) error {
+	rotator, ok := input.Datastore.(PasswordRotator)
+	if !ok {
+		return fmt.Errorf("%s services do not support password rotation", input.Datastore.ServiceType())
+	}
+
+	if owner := HAMemberOf(input.Datastore, input.ServiceName); owner != "" {
+		return fmt.Errorf("service %s shares the password of high availability service %s, rotate that instead", input.ServiceName, owner)
+	}
+
+	password := input.Password
+	if password == "" {
+		var err error
+		password, err = GenerateRandomHexString(64)
+		if err != nil {
+			return fmt.Errorf("unable to generate random hex string: %w", err)
+		}
+	}
+
+	if err := rotator.RotatePassword(ctx, input.ServiceName, password, input.KeepPrevious); err != nil {
+		return fmt.Errorf("failed to rotate password: %w", err)
+	}
+
+	passwordFile := Files(input.Datastore, input.ServiceName).Password
+	err := common.WriteStringToFile(common.WriteStringToFileInput{
+		Content:   password,
+		Filename:  passwordFile,
+		GroupName: SystemGroup(),
+		Mode:      0640,
+		Username:  SystemUser(),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to write password to %s: %w", passwordFile, err)
+	}
+
+	// the members of a high availability service share its password, as any of them may become the primary
+	if manager, ok := input.Datastore.(HAManager); ok && HAEnabled(input.Datastore, input.ServiceName) {
+		if err := manager.ConfigureHA(ctx, input.ServiceName); err != nil {
+			return fmt.Errorf("failed to share password with members: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// RevokePreviousPasswordInput is the input for the RevokePreviousPassword function
+type RevokePreviousPasswordInput struct {
+	// Datastore is the service to revoke the previous password of
+	Datastore Datastore
+
+	// ServiceName is the name of the service to revoke the previous password of
+	ServiceName string
+}
+
+// RevokePreviousPassword ends the grace period of a rotation, so only the current password is accepted
+func RevokePreviousPassword(ctx context.Context, input RevokePreviousPasswordInput) error {
+	rotator, ok := input.Datastore.(PasswordRotator)
+	if !ok {
+		return fmt.Errorf("%s services do not support password rotation", input.Datastore.ServiceType())
+	}
+
+	if err := rotator.RevokePreviousPassword(ctx, input.ServiceName); err != nil {
+		return fmt.Errorf("failed to revoke previous password: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package datastores
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// PersistenceSettings is how a service persists its data to disk, where zero values keep the datastore defaults
+type PersistenceSettings struct {
+	// Mode is the persistence mode, such as rdb, aof, both or none
+	Mode string `json:"mode,omitempty"`
+
+	// Save is the snapshot schedule of the service in the datastore's own syntax
+	Save string `json:"save,omitempty"`
+
+	// Appendfsync is how often the append only file is flushed to disk
+	Appendfsync string `json:"appendfsync,omitempty"`
+}
+
+// IsZero returns whether no persistence setting was given
+func (p PersistenceSettings) IsZero() bool {
+	return p == PersistenceSettings{}
+}
+
+// PersistenceStatus is the persistence state of a service
+type PersistenceStatus struct {
+	// Mode is the persistence mode of the service
+	Mode string
+
+	// LastSave is when the service last saved a snapshot, or empty when it is not running
+	LastSave string
+
+	// AOFRewrite is the state of the latest rewrite of the append only file, or empty when it is not running
+	AOFRewrite string
+}
+
+// PersistenceConfigurer is implemented by datastores that let a service choose how it persists its data
+type PersistenceConfigurer interface {
+	// PersistenceModes returns the persistence modes the datastore supports
+	PersistenceModes() []string
+
+	// ValidatePersistence checks the snapshot schedule and fsync policy of persistence settings
+	ValidatePersistence(settings PersistenceSettings) error
+
+	// ConfigurePersistence rewrites the service configuration for persistence settings
+	ConfigurePersistence(ctx context.Context, serviceName string, settings PersistenceSettings) error
+
+	// PersistenceMode returns the persistence mode the configuration of a service sets, without asking the service
+	PersistenceMode(serviceName string) string
+
+	// PersistenceStatus returns the persistence state of a service
+	PersistenceStatus(ctx context.Context, serviceName string) PersistenceStatus
+}
+
+// ValidatePersistenceSettings checks persistence settings against what the datastore supports
+func ValidatePersistenceSettings(s Datastore, settings PersistenceSettings) error {
+	if settings.IsZero() {
+		return nil
+	}
+
+	configurer, ok := s.(PersistenceConfigurer)
+	if !ok {
+		return fmt.Errorf("%s services do not support persistence settings", s.ServiceType())
+	}
+
+	if settings.Mode == "" {
+		return fmt.Errorf("a persistence mode is required to set the snapshot schedule or fsync policy")
+	}
+	if modes := configurer.PersistenceModes(); !slices.Contains(modes, settings.Mode) {
+		return fmt.Errorf("invalid persistence mode %s, must be one of %s", settings.Mode, strings.Join(modes, ", "))
+	}
+	return configurer.ValidatePersistence(settings)
+}
+
+// ConfigurePersistence applies persistence settings to a service when any were given
+func ConfigurePersistence(ctx context.Context, s Datastore, serviceName string, settings PersistenceSettings) error {
+	if settings.IsZero() {
+		return nil
+	}
+
+	configurer, ok := s.(PersistenceConfigurer)
+	if !ok {
+		return fmt.Errorf("%s services do not support persistence settings", s.ServiceType())
+	}
+
+	if err := configurer.ConfigurePersistence(ctx, serviceName, settings); err != nil {
+		return fmt.Errorf("failed to configure persistence: %w", err)
+	}
+	return nil
+}
+
+// ServicePersistenceStatus returns the persistence state of a service, which is empty for datastores without persistence settings
+func ServicePersistenceStatus(ctx context.Context, s Datastore, serviceName string) PersistenceStatus {
+	configurer, ok := s.(PersistenceConfigurer)
+	if !ok {
+		return PersistenceStatus{}
+	}
+	return configurer.PersistenceStatus(ctx, serviceName)
+}
+
+// ServicePersistenceMode returns the persistence mode of a service, which is empty for datastores without
+// persistence settings
+func ServicePersistenceMode(s Datastore, serviceName string) string {
+	configurer, ok := s.(PersistenceConfigurer)
+	if !ok {
+		return ""
+	}
+	return configurer.PersistenceMode(serviceName)
+}
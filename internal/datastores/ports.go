@@ -0,0 +1,306 @@
+package datastores
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// PortReservation is a host port claimed by an exposed service
+type PortReservation struct {
+	// Port is the reserved host port
+	Port int `json:"port"`
+
+	// Type is the datastore type of the service holding the port
+	Type string `json:"type"`
+
+	// Service is the name of the service holding the port
+	Service string `json:"service"`
+}
+
+// PortRegistryPath returns the path to the host-wide port registry
+func PortRegistryPath() string {
+	return filepath.Join(PluginDataRoot, "PORT_REGISTRY")
+}
+
+// PortReservations returns every port reservation on the host, ordered by port
+func PortReservations() ([]PortReservation, error) {
+	reservations := []PortReservation{}
+	err := updatePortRegistry(func(current []PortReservation) ([]PortReservation, error) {
+		reservations = current
+		return current, nil
+	})
+	return reservations, err
+}
+
+// ReservePortsInput is the input for the ReservePorts function
+type ReservePortsInput struct {
+	// CheckListening is whether to reject ports that already have a listening socket on the host
+	CheckListening bool
+
+	// Datastore is the datastore of the service reserving the ports
+	Datastore Datastore
+
+	// Ports is the host ports to reserve
+	Ports []int
+
+	// ServiceName is the name of the service reserving the ports
+	ServiceName string
+}
+
+// ReservePorts claims host ports for a service, replacing any ports it previously held
+func ReservePorts(input ReservePortsInput) error {
+	serviceType := input.Datastore.ServiceType()
+	return updatePortRegistry(func(current []PortReservation) ([]PortReservation, error) {
+		reservations := []PortReservation{}
+		held := map[int]bool{}
+		for _, reservation := range current {
+			if reservation.Type == serviceType && reservation.Service == input.ServiceName {
+				held[reservation.Port] = true
+				continue
+			}
+			reservations = append(reservations, reservation)
+		}
+
+		requested := map[int]bool{}
+		for _, port := range input.Ports {
+			if port < 1 || port > 65535 {
+				return nil, fmt.Errorf("invalid port %d, must be between 1 and 65535", port)
+			}
+			if requested[port] {
+				return nil, fmt.Errorf("port %d is specified more than once", port)
+			}
+			requested[port] = true
+
+			for _, reservation := range reservations {
+				if reservation.Port == port {
+					return nil, fmt.Errorf("port %d is already reserved by %s service %s", port, reservation.Type, reservation.Service)
+				}
+			}
+
+			// ports the service already holds may be bound by its own expose container
+			if input.CheckListening && !held[port] && PortInUse(port) {
+				return nil, fmt.Errorf("port %d is already in use on the host", port)
+			}
+
+			reservations = append(reservations, PortReservation{
+				Port:    port,
+				Type:    serviceType,
+				Service: input.ServiceName,
+			})
+		}
+
+		return reservations, nil
+	})
+}
+
+// ReleasePortsInput is the input for the ReleasePorts function
+type ReleasePortsInput struct {
+	// Datastore is the datastore of the service releasing its ports
+	Datastore Datastore
+
+	// ServiceName is the name of the service releasing its ports
+	ServiceName string
+}
+
+// ReleasePorts removes every port reservation held by a service
+func ReleasePorts(input ReleasePortsInput) error {
+	serviceType := input.Datastore.ServiceType()
+	return updatePortRegistry(func(current []PortReservation) ([]PortReservation, error) {
+		reservations := []PortReservation{}
+		for _, reservation := range current {
+			if reservation.Type == serviceType && reservation.Service == input.ServiceName {
+				continue
+			}
+			reservations = append(reservations, reservation)
+		}
+		return reservations, nil
+	})
+}
+
+// PortInUse checks if a listening socket is already bound to a host port
+func PortInUse(port int) bool {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return true
+	}
+	listener.Close()
+	return false
+}
+
+// portRegistryHeader is the first line of a port registry that has been seeded from the PORT files of existing services
+const portRegistryHeader = "# dokku-datastore port registry"
+
+// updatePortRegistry applies a change to the port registry while holding an exclusive lock on it
+//
+// A registry that has not been seeded yet, which is one without the header line, is first merged with the PORT
+// files of every existing service and written back before the change is applied, so a failed change never leaves
+// an unseeded registry behind
+func updatePortRegistry(update func([]PortReservation) ([]PortReservation, error)) error {
+	path := PortRegistryPath()
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open port registry: %w", err)
+	}
+	defer file.Close()
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("failed to lock port registry: %w", err)
+	}
+	defer syscall.Flock(int(file.Fd()), syscall.LOCK_UN) //nolint:errcheck
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return fmt.Errorf("failed to read port registry: %w", err)
+	}
+
+	current := parsePortRegistry(string(content))
+	if !portRegistrySeeded(string(content)) {
+		err := common.SetPermissions(common.SetPermissionInput{
+			Filename:  path,
+			GroupName: SystemGroup(),
+			Mode:      0644,
+			Username:  SystemUser(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set permissions on port registry: %w", err)
+		}
+
+		current = mergePortReservations(current, existingPortReservations())
+		if err := writePortRegistry(file, current); err != nil {
+			return err
+		}
+		content = []byte(formatPortRegistry(current))
+	}
+
+	reservations, err := update(current)
+	if err != nil {
+		return err
+	}
+
+	if formatPortRegistry(reservations) == string(content) {
+		return nil
+	}
+	return writePortRegistry(file, reservations)
+}
+
+// writePortRegistry replaces the content of the locked port registry with reservations
+func writePortRegistry(file *os.File, reservations []PortReservation) error {
+	if err := file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate port registry: %w", err)
+	}
+	if _, err := file.WriteAt([]byte(formatPortRegistry(reservations)), 0); err != nil {
+		return fmt.Errorf("failed to write port registry: %w", err)
+	}
+	return nil
+}
+
+// portRegistrySeeded returns whether registry content starts with the header written once it has been seeded
+func portRegistrySeeded(content string) bool {
+	line, _, _ := strings.Cut(content, "\n")
+	return line == portRegistryHeader
+}
+
+// mergePortReservations adds the existing reservations to those of the registry, where the registry wins when
+// both hold the same port, ordered by port
+func mergePortReservations(registry []PortReservation, existing []PortReservation) []PortReservation {
+	reservations := []PortReservation{}
+	held := map[int]bool{}
+	for _, reservation := range append(append([]PortReservation{}, registry...), existing...) {
+		if held[reservation.Port] {
+			continue
+		}
+		held[reservation.Port] = true
+		reservations = append(reservations, reservation)
+	}
+
+	sort.SliceStable(reservations, func(i, j int) bool {
+		return reservations[i].Port < reservations[j].Port
+	})
+	return reservations
+}
+
+// parsePortRegistry parses registry lines in the format <port> <type> <service>, skipping comments
+func parsePortRegistry(content string) []PortReservation {
+	reservations := []PortReservation{}
+	for line := range strings.SplitSeq(content, "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+
+		port, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+
+		reservations = append(reservations, PortReservation{
+			Port:    port,
+			Type:    fields[1],
+			Service: fields[2],
+		})
+	}
+	return reservations
+}
+
+// formatPortRegistry formats reservations as registry lines ordered by port, after the header line
+func formatPortRegistry(reservations []PortReservation) string {
+	sorted := append([]PortReservation{}, reservations...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Port < sorted[j].Port
+	})
+
+	var builder strings.Builder
+	builder.WriteString(portRegistryHeader + "\n")
+	for _, reservation := range sorted {
+		fmt.Fprintf(&builder, "%d %s %s\n", reservation.Port, reservation.Type, reservation.Service)
+	}
+	return builder.String()
+}
+
+// existingPortReservations collects the ports of every exposed service from their PORT files
+func existingPortReservations() []PortReservation {
+	reservations := []PortReservation{}
+	for _, datastore := range Datastores {
+		entries, err := os.ReadDir(filepath.Join(PluginDataRoot, datastore.Properties().CommandPrefix))
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+
+			portFile := Files(datastore, entry.Name()).Port
+			if !common.FileExists(portFile) {
+				continue
+			}
+
+			for _, value := range strings.Fields(common.ReadFirstLine(portFile)) {
+				port, err := strconv.Atoi(value)
+				if err != nil {
+					continue
+				}
+				reservations = append(reservations, PortReservation{
+					Port:    port,
+					Type:    datastore.ServiceType(),
+					Service: entry.Name(),
+				})
+			}
+		}
+	}
+	return reservations
+}
@@ -0,0 +1,141 @@
+package datastores
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePortRegistry(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []PortReservation
+	}{
+		{name: "empty", content: "", want: []PortReservation{}},
+		{name: "header only", content: portRegistryHeader + "\n", want: []PortReservation{}},
+		{
+			name:    "reservations",
+			content: portRegistryHeader + "\n6379 redis cache\n6380 redis queue\n",
+			want: []PortReservation{
+				{Port: 6379, Type: "redis", Service: "cache"},
+				{Port: 6380, Type: "redis", Service: "queue"},
+			},
+		},
+		{
+			name:    "unseeded reservations",
+			content: "6379 redis cache\n",
+			want:    []PortReservation{{Port: 6379, Type: "redis", Service: "cache"}},
+		},
+		{
+			name:    "malformed lines",
+			content: "# comment 1 2\nport redis cache\n6379 redis\n6380 redis queue extra\n\n6381 redis jobs\n",
+			want:    []PortReservation{{Port: 6381, Type: "redis", Service: "jobs"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parsePortRegistry(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePortRegistry(%q) = %v, want %v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatPortRegistry(t *testing.T) {
+	tests := []struct {
+		name         string
+		reservations []PortReservation
+		want         string
+	}{
+		{name: "empty", reservations: []PortReservation{}, want: portRegistryHeader + "\n"},
+		{
+			name: "ordered by port",
+			reservations: []PortReservation{
+				{Port: 6380, Type: "redis", Service: "queue"},
+				{Port: 6379, Type: "redis", Service: "cache"},
+			},
+			want: portRegistryHeader + "\n6379 redis cache\n6380 redis queue\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatPortRegistry(tt.reservations)
+			if got != tt.want {
+				t.Errorf("formatPortRegistry() = %q, want %q", got, tt.want)
+			}
+			if !portRegistrySeeded(got) {
+				t.Errorf("formatPortRegistry() output is not seeded")
+			}
+			if parsed := parsePortRegistry(got); len(parsed) != len(tt.reservations) {
+				t.Errorf("parsePortRegistry(formatPortRegistry()) returned %d reservations, want %d", len(parsed), len(tt.reservations))
+			}
+		})
+	}
+}
+
+func TestPortRegistrySeeded(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{name: "empty", content: "", want: false},
+		{name: "unseeded", content: "6379 redis cache\n", want: false},
+		{name: "header only", content: portRegistryHeader + "\n", want: true},
+		{name: "header without newline", content: portRegistryHeader, want: true},
+		{name: "header after reservations", content: "6379 redis cache\n" + portRegistryHeader + "\n", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := portRegistrySeeded(tt.content); got != tt.want {
+				t.Errorf("portRegistrySeeded(%q) = %v, want %v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergePortReservations(t *testing.T) {
+	tests := []struct {
+		name     string
+		registry []PortReservation
+		existing []PortReservation
+		want     []PortReservation
+	}{
+		{name: "nothing", registry: []PortReservation{}, existing: []PortReservation{}, want: []PortReservation{}},
+		{
+			name:     "seeds from existing services",
+			registry: []PortReservation{},
+			existing: []PortReservation{
+				{Port: 6380, Type: "redis", Service: "queue"},
+				{Port: 6379, Type: "redis", Service: "cache"},
+			},
+			want: []PortReservation{
+				{Port: 6379, Type: "redis", Service: "cache"},
+				{Port: 6380, Type: "redis", Service: "queue"},
+			},
+		},
+		{
+			name:     "registry wins on the same port",
+			registry: []PortReservation{{Port: 6379, Type: "redis", Service: "cache"}},
+			existing: []PortReservation{
+				{Port: 6379, Type: "redis", Service: "stale"},
+				{Port: 6381, Type: "redis", Service: "jobs"},
+			},
+			want: []PortReservation{
+				{Port: 6379, Type: "redis", Service: "cache"},
+				{Port: 6381, Type: "redis", Service: "jobs"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergePortReservations(tt.registry, tt.existing); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergePortReservations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,22 +2,32 @@ package datastores
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/dokku/dokku/plugins/common"
-	"mvdan.cc/sh/v3/shell"
 )
 
 // RedisService is the service for Redis
 type RedisService struct{}
 
+// redisContainerUser is the uid and gid of the redis user in the official image
+const redisContainerUser = "999:999"
+
 // CreateService creates a new service
 func (s *RedisService) CreateService(ctx context.Context, serviceName string) error {
 	serviceFolders := Folders(s, serviceName)
-	serviceRoot := serviceFolders.Root
+	serviceFiles := Files(s, serviceName)
 	redisServiceConfig := filepath.Join(serviceFolders.Config, "redis.conf")
 
 	redisConfigPath := os.Getenv("REDIS_CONFIG_PATH")
@@ -50,13 +60,13 @@ func (s *RedisService) CreateService(ctx context.Context, serviceName string) er
 	if password != "" {
 		err := common.WriteStringToFile(common.WriteStringToFileInput{
 			Content:   password,
-			Filename:  filepath.Join(serviceRoot, "PASSWORD"),
+			Filename:  serviceFiles.Password,
 			GroupName: SystemGroup(),
 			Mode:      0640,
 			Username:  SystemUser(),
 		})
 		if err != nil {
-			return fmt.Errorf("unable to write password to %s: %w", filepath.Join(serviceRoot, "PASSWORD"), err)
+			return fmt.Errorf("unable to write password to %s: %w", serviceFiles.Password, err)
 		}
 	}
 
@@ -90,144 +100,414 @@ func (s *RedisService) CreateService(ctx context.Context, serviceName string) er
 
 // CreateServiceContainer creates a new service container
 func (s *RedisService) CreateServiceContainer(ctx context.Context, input CreateServiceContainerInput) error {
-	serviceProperties := s.Properties()
-	serviceFolders := Folders(input.Datastore, input.ServiceName)
-	serviceFiles := Files(input.Datastore, input.ServiceName)
-	containerName := ContainerName(input.Datastore, input.ServiceName)
-	cidFilename := serviceFiles.ID
+	serviceFolders := MemberFolders(input.Datastore, input.ServiceName, input.Member)
+	cliArgs := ""
+	if TLSEnabled(input.Datastore, input.ServiceName) {
+		cliArgs = " --tls --cacert " + redisConfigMount + "/tls/ca.crt"
+	}
+
+	// cluster members share redis.conf, so each is told the alias to announce on the command line
+	args := []string{"redis-server", redisConfigMount + "/redis.conf", "--bind", "0.0.0.0"}
+	if ClusterEnabled(input.Datastore, input.ServiceName) {
+		args = append(args, "--cluster-announce-hostname", MemberDNSHostname(input.Datastore, input.ServiceName, input.Member))
+	}
 
-	lines, err := common.FileToSlice(serviceFiles.ConfigOptions)
+	return RunServiceContainer(ctx, RunServiceContainerInput{
+		Datastore:   input.Datastore,
+		Member:      input.Member,
+		ServiceName: input.ServiceName,
+		TaggedImage: input.TaggedImage,
+		Spec: ServiceContainerSpec{
+			Args:      args,
+			Hardening: s.HardeningSpec(),
+			Healthcheck: ServiceHealthcheckSpec{
+				// the password is read from the config so it does not show up in docker inspect output
+				Command: fmt.Sprintf(`REDISCLI_AUTH="$(sed -n 's/^requirepass //p' %s/redis.conf)" redis-cli%s ping | grep -q PONG`, redisConfigMount, cliArgs),
+
+				// redis answers LOADING until a large rdb or aof file is loaded, which must not mark it unhealthy
+				StartPeriod: "5m",
+			},
+			Volumes: []string{
+				serviceFolders.HostConfig + ":" + redisConfigMount,
+				serviceFolders.HostData + ":/data",
+			},
+		},
+	})
+}
+
+// Health performs protocol-level health checks against a redis service
+func (s *RedisService) Health(ctx context.Context, serviceName string) (HealthReport, error) {
+	report := HealthReport{}
+	containerCheck := ContainerHealthCheck(ctx, s, serviceName)
+	report.Add(containerCheck)
+	if containerCheck.Status == HealthStatusDown {
+		return report, nil
+	}
+
+	client, err := s.client(ctx, serviceName)
 	if err != nil {
-		return fmt.Errorf("unable to read config options from %s: %w", serviceFiles.ConfigOptions, err)
+		report.Add(HealthCheck{
+			Name:    "connection",
+			Status:  HealthStatusDown,
+			Message: err.Error(),
+		})
+		return report, nil
+	}
+	defer client.Close() //nolint:errcheck
+
+	pong, err := client.String("PING")
+	if err != nil || pong != "PONG" {
+		message := fmt.Sprintf("unexpected PING reply: %s", pong)
+		if err != nil {
+			message = err.Error()
+		}
+		report.Add(HealthCheck{
+			Name:    "ping",
+			Status:  HealthStatusDown,
+			Message: message,
+		})
+		return report, nil
 	}
-	startArgsToAppend, err := shell.Fields(strings.Join(lines, "\n"), func(name string) string {
-		return ""
+	report.Add(HealthCheck{
+		Name:    "ping",
+		Status:  HealthStatusHealthy,
+		Message: "PONG",
 	})
+
+	persistence, err := client.Info("persistence")
 	if err != nil {
-		return fmt.Errorf("unable to parse config options: %w", err)
+		report.Add(HealthCheck{
+			Name:    "persistence",
+			Status:  HealthStatusDegraded,
+			Message: fmt.Sprintf("failed to read persistence info: %s", err.Error()),
+		})
+	} else {
+		report.Add(redisPersistenceCheck(persistence))
 	}
 
-	// remove the ID file if it exists
-	if err := os.RemoveAll(cidFilename); err != nil {
-		return fmt.Errorf("unable to remove ID file from %s: %w", cidFilename, err)
+	if ClusterEnabled(s, serviceName) {
+		clusterInfo, err := client.String("CLUSTER", "INFO")
+		if err != nil {
+			report.Add(HealthCheck{
+				Name:    "cluster",
+				Status:  HealthStatusDegraded,
+				Message: fmt.Sprintf("failed to read cluster info: %s", err.Error()),
+			})
+		} else {
+			report.Add(redisClusterCheck(ParseRedisInfo(clusterInfo)))
+		}
 	}
 
-	dockerCreateArgs := []string{
-		"container",
-		"create",
-		"--cidfile=" + cidFilename,
-		"--env-file=" + serviceFiles.Env,
-		"--hostname=" + containerName,
-		"--label=dokku.service=" + serviceProperties.CommandPrefix,
-		"--label=dokku=service",
-		"--name=" + containerName,
-		"--restart=always",
-		"--volume=" + serviceFolders.HostConfig + ":/usr/local/etc/redis",
-		"--volume=" + serviceFolders.HostData + ":/data",
+	replication, err := client.Info("replication")
+	if err != nil {
+		report.Add(HealthCheck{
+			Name:    "replication",
+			Status:  HealthStatusDegraded,
+			Message: fmt.Sprintf("failed to read replication info: %s", err.Error()),
+		})
+	} else {
+		report.Add(redisReplicationCheck(replication))
 	}
 
-	memory := common.ReadFirstLine(serviceFiles.Memory)
-	if memory != "" {
-		dockerCreateArgs = append(dockerCreateArgs, "--memory="+memory+"m")
+	return report, nil
+}
+
+// RedisMaxReplicationLag is the replication lag in seconds after which a service is considered degraded
+var RedisMaxReplicationLag = 10
+
+// redisPersistenceCheck checks the output of INFO persistence for failed saves
+func redisPersistenceCheck(info map[string]string) HealthCheck {
+	if info["loading"] == "1" {
+		return HealthCheck{
+			Name:    "persistence",
+			Status:  HealthStatusDegraded,
+			Message: "dataset is still loading",
+		}
 	}
 
-	shmSize := common.ReadFirstLine(serviceFiles.ShmSize)
-	if shmSize != "" {
-		dockerCreateArgs = append(dockerCreateArgs, "--shm-size="+shmSize)
+	if status := info["rdb_last_bgsave_status"]; status != "" && status != "ok" {
+		return HealthCheck{
+			Name:    "persistence",
+			Status:  HealthStatusDegraded,
+			Message: fmt.Sprintf("last rdb save failed: %s", status),
+		}
 	}
 
-	networkAlias := DNSHostname(input.Datastore, input.ServiceName)
-	initialNetwork := InitialNetwork(input.Datastore, input.ServiceName)
-	if err != nil {
-		return fmt.Errorf("failed to get initial network: %w", err)
+	if info["aof_enabled"] == "1" {
+		if status := info["aof_last_write_status"]; status != "" && status != "ok" {
+			return HealthCheck{
+				Name:    "persistence",
+				Status:  HealthStatusDegraded,
+				Message: fmt.Sprintf("last aof write failed: %s", status),
+			}
+		}
 	}
-	if initialNetwork != "" {
-		dockerCreateArgs = append(dockerCreateArgs, "--network="+initialNetwork)
-		dockerCreateArgs = append(dockerCreateArgs, "--network-alias="+networkAlias)
+
+	return HealthCheck{
+		Name:    "persistence",
+		Status:  HealthStatusHealthy,
+		Message: fmt.Sprintf("%s changes since last save", info["rdb_changes_since_last_save"]),
 	}
+}
 
-	taggedImage := input.TaggedImage
-	if taggedImage == "" {
-		image := common.ReadFirstLine(serviceFiles.Image)
-		if image == "" {
-			image = serviceProperties.DefaultImage
+// redisClusterCheck checks the output of CLUSTER INFO for unassigned or failing slots
+func redisClusterCheck(info map[string]string) HealthCheck {
+	message := fmt.Sprintf("%s known nodes, %s of 16384 slots ok", info["cluster_known_nodes"], info["cluster_slots_ok"])
+	if info["cluster_state"] != "ok" {
+		return HealthCheck{
+			Name:    "cluster",
+			Status:  HealthStatusDegraded,
+			Message: fmt.Sprintf("cluster state is %s, %s", info["cluster_state"], message),
 		}
+	}
 
-		imageVersion := common.ReadFirstLine(serviceFiles.ImageVersion)
-		if imageVersion == "" {
-			imageVersion = serviceProperties.DefaultImageVersion
+	return HealthCheck{
+		Name:    "cluster",
+		Status:  HealthStatusHealthy,
+		Message: message,
+	}
+}
+
+// redisReplicationCheck checks the output of INFO replication for link problems and lag
+func redisReplicationCheck(info map[string]string) HealthCheck {
+	role := info["role"]
+	if role == "slave" {
+		if info["master_link_status"] != "up" {
+			return HealthCheck{
+				Name:    "replication",
+				Status:  HealthStatusDegraded,
+				Message: fmt.Sprintf("replica link to %s:%s is %s", info["master_host"], info["master_port"], info["master_link_status"]),
+			}
+		}
+
+		lastIO, _ := strconv.Atoi(info["master_last_io_seconds_ago"])
+		masterOffset, _ := strconv.ParseInt(info["master_repl_offset"], 10, 64)
+		replicaOffset, _ := strconv.ParseInt(info["slave_repl_offset"], 10, 64)
+		message := fmt.Sprintf("replica of %s:%s, last io %ds ago, %d bytes behind", info["master_host"], info["master_port"], lastIO, masterOffset-replicaOffset)
+		if lastIO > RedisMaxReplicationLag {
+			return HealthCheck{
+				Name:    "replication",
+				Status:  HealthStatusDegraded,
+				Message: message,
+			}
+		}
+
+		return HealthCheck{
+			Name:    "replication",
+			Status:  HealthStatusHealthy,
+			Message: message,
 		}
-		taggedImage = fmt.Sprintf("%s:%s", image, imageVersion)
 	}
 
-	dockerCreateArgs = append(dockerCreateArgs, taggedImage)
-	dockerCreateArgs = append(dockerCreateArgs, "redis-server")
-	dockerCreateArgs = append(dockerCreateArgs, "/usr/local/etc/redis/redis.conf")
-	dockerCreateArgs = append(dockerCreateArgs, []string{"--bind", "0.0.0.0"}...)
-	for _, arg := range startArgsToAppend {
-		if arg == "" {
-			continue
+	connectedReplicas, _ := strconv.Atoi(info["connected_slaves"])
+	status := HealthStatusHealthy
+	messages := []string{fmt.Sprintf("%s with %d connected replica(s)", role, connectedReplicas)}
+	for i := 0; i < connectedReplicas; i++ {
+		replica := map[string]string{}
+		for field := range strings.SplitSeq(info[fmt.Sprintf("slave%d", i)], ",") {
+			key, value, _ := strings.Cut(field, "=")
+			replica[key] = value
 		}
 
-		dockerCreateArgs = append(dockerCreateArgs, arg)
+		lag, _ := strconv.Atoi(replica["lag"])
+		if replica["state"] != "online" || lag > RedisMaxReplicationLag {
+			status = HealthStatusDegraded
+		}
+		messages = append(messages, fmt.Sprintf("%s:%s %s lag=%ds", replica["ip"], replica["port"], replica["state"], lag))
 	}
 
-	// create the container
-	_, err = CallExecCommandWithContext(ctx, common.ExecCommandInput{
-		Command: common.DockerBin(),
-		Args:    dockerCreateArgs,
+	return HealthCheck{
+		Name:    "replication",
+		Status:  status,
+		Message: strings.Join(messages, ", "),
+	}
+}
+
+// client returns an authenticated client for a redis service
+func (s *RedisService) client(ctx context.Context, serviceName string) (*RedisClient, error) {
+	return s.memberClient(ctx, serviceName, "")
+}
+
+// memberClient returns an authenticated client for a member of a redis service
+func (s *RedisService) memberClient(ctx context.Context, serviceName string, member string) (*RedisClient, error) {
+	containerID := LiveContainerID(ctx, LiveContainerIDInput{
+		Datastore:   s,
+		Member:      member,
+		ServiceName: serviceName,
 	})
-	if err != nil {
-		return err
+	if containerID == "" {
+		return nil, fmt.Errorf("%s container %s does not exist", s.ServiceType(), MemberContainerName(s, serviceName, member))
 	}
 
-	postCreateNetworks := common.PropertyGet(serviceProperties.CommandPrefix, input.ServiceName, "post-create-network")
-	if postCreateNetworks != "" {
-		err := AttachNetworksToContainer(ctx, AttachNetworksToContainerInput{
-			ContainerID:  common.ReadFirstLine(cidFilename),
-			Networks:     strings.Split(postCreateNetworks, ","),
-			NetworkAlias: networkAlias,
-		})
+	containerIP := ContainerIP(ctx, ContainerIPInput{ContainerID: containerID})
+	if containerIP == "" {
+		return nil, fmt.Errorf("unable to determine ip address for %s service %s", s.ServiceType(), serviceName)
+	}
+
+	var tlsConfig *tls.Config
+	if TLSEnabled(s, serviceName) {
+		caBundle, err := os.ReadFile(ServiceTLSFiles(s, serviceName).CACert)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("failed to read tls certificate authority: %w", err)
 		}
-	}
 
-	containerID := common.ReadFirstLine(cidFilename)
-	if containerID == "" {
-		return fmt.Errorf("failed to read container ID from %s", cidFilename)
+		rootCAs := x509.NewCertPool()
+		rootCAs.AppendCertsFromPEM(caBundle)
+		tlsConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			RootCAs:    rootCAs,
+			ServerName: DNSHostname(s, serviceName),
+		}
 	}
 
-	// start the container
-	_, err = CallExecCommandWithContext(ctx, common.ExecCommandInput{
-		Command: common.DockerBin(),
-		Args:    []string{"container", "start", containerID},
+	return NewRedisClient(ctx, NewRedisClientInput{
+		Address:   net.JoinHostPort(containerIP, strconv.Itoa(s.Properties().Ports[0])),
+		Password:  common.ReadFirstLine(Files(s, serviceName).Password),
+		TLSConfig: tlsConfig,
+	})
+}
+
+// RotatePassword changes requirepass live and in redis.conf, along with the default user of the acl file
+//
+// A kept previous password is an additional password of the default acl user, so it only stays valid
+// until it is revoked, or the container restarts without an acl file listing it
+func (s *RedisService) RotatePassword(ctx context.Context, serviceName string, password string, keepPrevious bool) error {
+	prefix := s.Properties().CommandPrefix
+	previous := common.ReadFirstLine(Files(s, serviceName).Password)
+
+	runningContainerID := LiveContainerID(ctx, LiveContainerIDInput{
+		Datastore:   s,
+		ServiceName: serviceName,
+		Filter:      "status=running",
+	})
+	// the replicas of a cluster authenticate to the primaries of their shards with the same password
+	clustered := ClusterEnabled(s, serviceName)
+	err := s.withRunningClient(ctx, serviceName, func(client *RedisClient) error {
+		var err error
+		if keepPrevious {
+			// a password kept by an earlier rotation is dropped, so at most two passwords are ever valid,
+			// ignoring errors as a restart has already dropped it
+			if hash := common.PropertyGet(prefix, serviceName, "previous-password-hash"); hash != "" {
+				client.Do("ACL", "SETUSER", "default", "!"+hash) //nolint:errcheck
+			}
+			_, err = client.Do("ACL", "SETUSER", "default", "on", ">"+password)
+		} else {
+			_, err = client.Do("CONFIG", "SET", "requirepass", password)
+		}
+		if err == nil && clustered {
+			_, err = client.Do("CONFIG", "SET", "masterauth", password)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to set password: %w", err)
+		}
+		return nil
 	})
 	if err != nil {
-		return fmt.Errorf("failed to start container: %w", err)
+		return err
 	}
 
-	err = ServicePortReconcileStatus(ctx, ServicePortReconcileStatusInput{
-		Datastore:   input.Datastore,
-		ServiceName: input.ServiceName,
+	directives := map[string]string{"requirepass": password}
+	if clustered {
+		directives["masterauth"] = password
+	}
+	if err := setRedisConfigDirectives(s, serviceName, directives); err != nil {
+		return err
+	}
+
+	if err := s.updateReplicaMasterauth(ctx, serviceName, password); err != nil {
+		return err
+	}
+
+	// only a hash of the previous password is kept, which is enough to revoke it
+	if keepPrevious && runningContainerID != "" && previous != "" {
+		hash := sha256.Sum256([]byte(previous))
+		if err := common.PropertyWrite(prefix, serviceName, "previous-password-hash", hex.EncodeToString(hash[:])); err != nil {
+			return fmt.Errorf("failed to write previous password hash: %w", err)
+		}
+	} else if common.PropertyExists(prefix, serviceName, "previous-password-hash") {
+		if err := common.PropertyDelete(prefix, serviceName, "previous-password-hash"); err != nil {
+			return fmt.Errorf("failed to remove previous password hash: %w", err)
+		}
+	}
+
+	return syncRedisDefaultACLUser(s, serviceName, password)
+}
+
+// RevokePreviousPassword removes the previous password from the default acl user
+func (s *RedisService) RevokePreviousPassword(ctx context.Context, serviceName string) error {
+	prefix := s.Properties().CommandPrefix
+	hash := common.PropertyGet(prefix, serviceName, "previous-password-hash")
+	if hash == "" {
+		return fmt.Errorf("service %s has no previous password to revoke", serviceName)
+	}
+
+	err := s.withRunningClient(ctx, serviceName, func(client *RedisClient) error {
+		// a restart has already dropped the previous password when redis no longer knows it
+		_, err := client.Do("ACL", "SETUSER", "default", "!"+hash)
+		var redisErr RedisError
+		if err != nil && !(errors.As(err, &redisErr) && strings.Contains(redisErr.Message, "does not exist")) {
+			return fmt.Errorf("failed to remove previous password: %w", err)
+		}
+		return nil
 	})
 	if err != nil {
-		return fmt.Errorf("failed to reconcile port status: %w", err)
+		return err
 	}
 
-	postStartNetworks := common.PropertyGet(serviceProperties.CommandPrefix, input.ServiceName, "post-start-network")
-	if postStartNetworks != "" {
-		err := AttachNetworksToContainer(ctx, AttachNetworksToContainerInput{
-			ContainerID:  common.ReadFirstLine(cidFilename),
-			Networks:     strings.Split(postStartNetworks, ","),
-			NetworkAlias: networkAlias,
-		})
-		if err != nil {
-			return err
+	if err := common.PropertyDelete(prefix, serviceName, "previous-password-hash"); err != nil {
+		return err
+	}
+	return syncRedisDefaultACLUser(s, serviceName, common.ReadFirstLine(Files(s, serviceName).Password))
+}
+
+// HardeningSpec returns the non-root user of the official image, which redis runs as when hardened
+func (s *RedisService) HardeningSpec() ServiceHardeningSpec {
+	return ServiceHardeningSpec{User: redisContainerUser}
+}
+
+// TLSKeyUser returns the redis user of the official image, which the entrypoint drops to before reading the key
+func (s *RedisService) TLSKeyUser() string {
+	return redisContainerUser
+}
+
+// ConfigureTLS rewrites redis.conf so the service only accepts tls connections on its usual port, or none at all
+func (s *RedisService) ConfigureTLS(ctx context.Context, serviceName string, enabled bool) error {
+	directives := map[string]string{
+		"port":             "",
+		"tls-port":         "",
+		"tls-cert-file":    "",
+		"tls-key-file":     "",
+		"tls-ca-cert-file": "",
+		"tls-auth-clients": "",
+		"tls-replication":  "",
+	}
+	if enabled {
+		port := strconv.Itoa(s.Properties().Ports[0])
+		directives = map[string]string{
+			"port":             "0",
+			"tls-port":         port,
+			"tls-cert-file":    redisConfigMount + "/tls/server.crt",
+			"tls-key-file":     redisConfigMount + "/tls/server.key",
+			"tls-ca-cert-file": redisConfigMount + "/tls/ca.crt",
+			// apps authenticate with the password, so they only need the ca bundle and not a client certificate
+			"tls-auth-clients": "no",
+			"tls-replication":  "yes",
 		}
 	}
 
-	return nil
+	return setRedisConfigDirectives(s, serviceName, directives)
+}
+
+// ReloadTLS makes redis load the rotated certificate, as setting a tls file reloads every tls file
+func (s *RedisService) ReloadTLS(ctx context.Context, serviceName string) error {
+	client, err := s.client(ctx, serviceName)
+	if err != nil {
+		return err
+	}
+	defer client.Close() //nolint:errcheck
+
+	_, err = client.Do("CONFIG", "SET", "tls-cert-file", redisConfigMount+"/tls/server.crt")
+	return err
 }
 
 // Properties returns the properties for a service
@@ -256,6 +536,49 @@ func (s *RedisService) Title() string {
 }
 
 // URL gets the url for a service
+//
+// High availability services point at their sentinels, with the service name as the master name and the
+// sentinel password as a query parameter, and clusters list every member as a seed node
 func (s *RedisService) URL(serviceName string) string {
-	return fmt.Sprintf("redis://%s:%d", DNSHostname(s, serviceName), s.Properties().Ports[0])
+	password := common.ReadFirstLine(Files(s, serviceName).Password)
+	if ClusterEnabled(s, serviceName) {
+		hosts := []string{}
+		for _, member := range ServiceMembers(s, serviceName) {
+			hosts = append(hosts, net.JoinHostPort(MemberDNSHostname(s, serviceName, member), strconv.Itoa(s.Properties().Ports[0])))
+		}
+		dsn := url.URL{
+			Scheme: "redis-cluster",
+			User:   url.UserPassword("", password),
+			Host:   strings.Join(hosts, ","),
+		}
+		return dsn.String()
+	}
+
+	if HAEnabled(s, serviceName) {
+		hosts := []string{}
+		for index := 1; index <= HAMonitorCount; index++ {
+			hosts = append(hosts, net.JoinHostPort(redisSentinelHostname(s, serviceName, index), strconv.Itoa(redisSentinelPort)))
+		}
+		dsn := url.URL{
+			Scheme: "redis-sentinel",
+			User:   url.UserPassword("", password),
+			Host:   strings.Join(hosts, ","),
+			Path:   "/" + serviceName,
+		}
+		if sentinelPassword := redisSentinelPassword(s, serviceName); sentinelPassword != "" {
+			dsn.RawQuery = url.Values{"sentinel_password": []string{sentinelPassword}}.Encode()
+		}
+		return dsn.String()
+	}
+
+	scheme := "redis"
+	if TLSEnabled(s, serviceName) {
+		scheme = "rediss"
+	}
+	dsn := url.URL{
+		Scheme: scheme,
+		User:   url.UserPassword("", password),
+		Host:   net.JoinHostPort(DNSHostname(s, serviceName), strconv.Itoa(s.Properties().Ports[0])),
+	}
+	return dsn.String()
 }
@@ -0,0 +1,293 @@
+package datastores
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// RedisDefaultACLRules is the permissions of an app user when none are given, matching the shared password
+var RedisDefaultACLRules = "~* &* +@all"
+
+// redisDefaultACLUser is the user authenticating with the password of the service, which the acl file must define
+// as redis ignores requirepass once it uses an acl file
+const redisDefaultACLUser = "default"
+
+// redisACLKeywords is the acl rules that are not prefixed with a selector character
+var redisACLKeywords = []string{"allkeys", "allchannels", "allcommands", "nocommands", "resetkeys", "resetchannels"}
+
+// redisACLUser is a user line of the acl file of a redis service
+type redisACLUser struct {
+	// Name is the username
+	Name string
+
+	// Enabled is whether the user may authenticate
+	Enabled bool
+
+	// PasswordHash is the sha256 hash of the user password
+	PasswordHash string
+
+	// Rules is the key, channel and command permissions of the user
+	Rules string
+}
+
+// redisACLFile returns the path of the acl file of a service on the host
+func redisACLFile(s Datastore, serviceName string) string {
+	return filepath.Join(Folders(s, serviceName).Config, "users.acl")
+}
+
+// validateRedisACLRules checks acl rules only grant permissions, as credentials are managed separately
+//
+// An invalid rule in the acl file stops redis from starting, so rules are checked before they are written
+func validateRedisACLRules(rules string) error {
+	for _, rule := range strings.Fields(rules) {
+		if slices.Contains(redisACLKeywords, strings.ToLower(rule)) {
+			continue
+		}
+		if !strings.ContainsAny(rule[:1], "~%&+-") {
+			return fmt.Errorf("invalid acl rule %s, only key, channel and command rules are allowed", rule)
+		}
+	}
+	return nil
+}
+
+// readRedisACLUsers parses the acl file of a service
+func readRedisACLUsers(s Datastore, serviceName string) []redisACLUser {
+	users := []redisACLUser{}
+	lines, err := common.FileToSlice(redisACLFile(s, serviceName))
+	if err != nil {
+		return users
+	}
+
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[0] != "user" || !strings.HasPrefix(fields[3], "#") {
+			continue
+		}
+		if fields[1] == redisDefaultACLUser {
+			continue
+		}
+		users = append(users, redisACLUser{
+			Name:         fields[1],
+			Enabled:      fields[2] == "on",
+			PasswordHash: strings.TrimPrefix(fields[3], "#"),
+			Rules:        strings.Join(fields[4:], " "),
+		})
+	}
+	return users
+}
+
+// redisDefaultACLLine returns the acl file line of the default user, which accepts the password of the service
+// along with a previous password kept by a rotation
+func redisDefaultACLLine(s Datastore, serviceName string, password string) string {
+	hash := sha256.Sum256([]byte(password))
+	hashes := []string{"#" + hex.EncodeToString(hash[:])}
+	if previous := common.PropertyGet(s.Properties().CommandPrefix, serviceName, "previous-password-hash"); previous != "" {
+		hashes = append(hashes, "#"+previous)
+	}
+	return fmt.Sprintf("user %s on %s %s", redisDefaultACLUser, strings.Join(hashes, " "), RedisDefaultACLRules)
+}
+
+// syncRedisDefaultACLUser rewrites the default user of the acl file of a service after its password changes,
+// doing nothing when the service has no acl file yet
+func syncRedisDefaultACLUser(s Datastore, serviceName string, password string) error {
+	if !common.FileExists(redisACLFile(s, serviceName)) {
+		return nil
+	}
+	return writeRedisACLFile(s, serviceName, password, readRedisACLUsers(s, serviceName))
+}
+
+// writeRedisACLUsers persists the acl file of a service and points redis.conf at it
+func writeRedisACLUsers(s Datastore, serviceName string, users []redisACLUser) error {
+	return writeRedisACLFile(s, serviceName, common.ReadFirstLine(Files(s, serviceName).Password), users)
+}
+
+// writeRedisACLFile writes the default user with the given password and the app users to the acl file of a service
+//
+// The members of a high availability service get the same acl file, as any of them may become the primary
+func writeRedisACLFile(s Datastore, serviceName string, password string, users []redisACLUser) error {
+	lines := []string{redisDefaultACLLine(s, serviceName, password)}
+	for _, user := range users {
+		state := "off"
+		if user.Enabled {
+			state = "on"
+		}
+		lines = append(lines, strings.TrimSpace(fmt.Sprintf("user %s %s #%s %s", user.Name, state, user.PasswordHash, user.Rules)))
+	}
+
+	for _, member := range HAServices(s, serviceName) {
+		aclFile := redisACLFile(s, member)
+		err := common.WriteSliceToFile(common.WriteSliceToFileInput{
+			Filename:  aclFile,
+			GroupName: SystemGroup(),
+			Lines:     lines,
+			Mode:      0644,
+			Username:  SystemUser(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", aclFile, err)
+		}
+
+		// the aclfile directive only takes effect on restart, so running services are also updated with ACL SETUSER
+		if err := setRedisConfigDirectives(s, member, map[string]string{"aclfile": redisConfigMount + "/users.acl"}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyRedisACLUser applies a user to a service and its high availability members, skipping stopped ones
+func (s *RedisService) applyRedisACLUser(ctx context.Context, serviceName string, user redisACLUser, remove bool) error {
+	state := "off"
+	if user.Enabled {
+		state = "on"
+	}
+	args := []string{"ACL", "SETUSER", user.Name, "reset", state, "#" + user.PasswordHash}
+	args = append(args, strings.Fields(user.Rules)...)
+	if remove {
+		args = []string{"ACL", "DELUSER", user.Name}
+	}
+
+	for _, member := range HAServices(s, serviceName) {
+		err := s.withRunningClient(ctx, member, func(client *RedisClient) error {
+			_, err := client.Do(args...)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetAppUser creates or updates the acl user of an app, issuing a new password when the user is new or was revoked
+func (s *RedisService) SetAppUser(ctx context.Context, serviceName string, appName string, rules string) error {
+	if appName == redisDefaultACLUser {
+		return fmt.Errorf("app %s cannot have its own acl user, as it would replace the default user", appName)
+	}
+	if err := validateRedisACLRules(rules); err != nil {
+		return err
+	}
+
+	users := readRedisACLUsers(s, serviceName)
+	passwords := readAppPasswords(s, serviceName)
+	index := slices.IndexFunc(users, func(user redisACLUser) bool {
+		return user.Name == appName
+	})
+	if index == -1 {
+		users = append(users, redisACLUser{Name: appName, Rules: RedisDefaultACLRules})
+		index = len(users) - 1
+	}
+
+	user := users[index]
+	if rules != "" {
+		user.Rules = rules
+	}
+	if !user.Enabled || passwords[appName] == "" {
+		password, err := GenerateRandomHexString(64)
+		if err != nil {
+			return fmt.Errorf("unable to generate random hex string: %w", err)
+		}
+		hash := sha256.Sum256([]byte(password))
+		user.PasswordHash = hex.EncodeToString(hash[:])
+		passwords[appName] = password
+	}
+	user.Enabled = true
+	users[index] = user
+
+	if err := s.applyRedisACLUser(ctx, serviceName, user, false); err != nil {
+		return fmt.Errorf("failed to set acl user %s: %w", appName, err)
+	}
+	if err := writeRedisACLUsers(s, serviceName, users); err != nil {
+		return err
+	}
+	return writeAppPasswords(s, serviceName, passwords)
+}
+
+// RevokeAppUser disables the acl user of an app and forgets its password
+func (s *RedisService) RevokeAppUser(ctx context.Context, serviceName string, appName string) error {
+	users := readRedisACLUsers(s, serviceName)
+	index := slices.IndexFunc(users, func(user redisACLUser) bool {
+		return user.Name == appName
+	})
+	if index == -1 {
+		return fmt.Errorf("app %s has no acl user on service %s", appName, serviceName)
+	}
+
+	users[index].Enabled = false
+	if err := s.applyRedisACLUser(ctx, serviceName, users[index], false); err != nil {
+		return fmt.Errorf("failed to revoke acl user %s: %w", appName, err)
+	}
+	if err := writeRedisACLUsers(s, serviceName, users); err != nil {
+		return err
+	}
+
+	passwords := readAppPasswords(s, serviceName)
+	delete(passwords, appName)
+	return writeAppPasswords(s, serviceName, passwords)
+}
+
+// RemoveAppUser deletes the acl user of an app, doing nothing when it has none
+func (s *RedisService) RemoveAppUser(ctx context.Context, serviceName string, appName string) error {
+	users := readRedisACLUsers(s, serviceName)
+	index := slices.IndexFunc(users, func(user redisACLUser) bool {
+		return user.Name == appName
+	})
+	if index == -1 {
+		return nil
+	}
+
+	if err := s.applyRedisACLUser(ctx, serviceName, users[index], true); err != nil {
+		return fmt.Errorf("failed to remove acl user %s: %w", appName, err)
+	}
+	if err := writeRedisACLUsers(s, serviceName, slices.Delete(users, index, index+1)); err != nil {
+		return err
+	}
+
+	passwords := readAppPasswords(s, serviceName)
+	delete(passwords, appName)
+	return writeAppPasswords(s, serviceName, passwords)
+}
+
+// AppUsers returns the acl users of the apps linked to a service
+func (s *RedisService) AppUsers(serviceName string) []AppUser {
+	users := []AppUser{}
+	for _, user := range readRedisACLUsers(s, serviceName) {
+		users = append(users, AppUser{
+			App:     user.Name,
+			Enabled: user.Enabled,
+			Rules:   user.Rules,
+		})
+	}
+	return users
+}
+
+// AppURL returns the dsn of a service carrying the acl user of an app
+//
+// Revoked apps keep a dsn without a password, so relinking other apps never hands them working credentials
+func (s *RedisService) AppURL(serviceName string, appName string) string {
+	index := slices.IndexFunc(readRedisACLUsers(s, serviceName), func(user redisACLUser) bool {
+		return user.Name == appName
+	})
+	if index == -1 {
+		return ""
+	}
+
+	dsn, err := url.Parse(s.URL(serviceName))
+	if err != nil {
+		return ""
+	}
+	if password := readAppPasswords(s, serviceName)[appName]; password != "" {
+		dsn.User = url.UserPassword(appName, password)
+	} else {
+		dsn.User = url.User(appName)
+	}
+	return dsn.String()
+}
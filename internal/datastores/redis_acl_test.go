@@ -0,0 +1,43 @@
+package datastores
+
+import "testing"
+
+func TestValidateRedisACLRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   string
+		wantErr bool
+	}{
+		{name: "empty", rules: ""},
+		{name: "default rules", rules: RedisDefaultACLRules},
+		{name: "key pattern", rules: "~cache:*"},
+		{name: "read-only key pattern", rules: "%R~cache:*"},
+		{name: "channel pattern", rules: "&notifications:*"},
+		{name: "command category", rules: "+@read -@dangerous"},
+		{name: "single command", rules: "-flushall"},
+		{name: "keywords", rules: "allkeys allchannels allcommands"},
+		{name: "uppercase keyword", rules: "ResetKeys ~cache:* NOCOMMANDS +get"},
+		{name: "extra whitespace", rules: "  ~cache:*   +@read  "},
+		{name: "password", rules: "~* >secret", wantErr: true},
+		{name: "password removal", rules: "<secret", wantErr: true},
+		{name: "password hash", rules: "#5e884898da28047151d0e56f8dc6292773603d0d6aabbdd62a11ef721d1542d8", wantErr: true},
+		{name: "nopass", rules: "nopass +@all", wantErr: true},
+		{name: "enable", rules: "on ~*", wantErr: true},
+		{name: "disable", rules: "off", wantErr: true},
+		{name: "reset", rules: "reset", wantErr: true},
+		{name: "resetpass", rules: "resetpass", wantErr: true},
+		{name: "bare key", rules: "cache:*", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRedisACLRules(tt.rules)
+			if tt.wantErr && err == nil {
+				t.Errorf("validateRedisACLRules(%q) returned no error", tt.rules)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("validateRedisACLRules(%q) returned %v", tt.rules, err)
+			}
+		})
+	}
+}
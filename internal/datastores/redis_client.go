@@ -0,0 +1,220 @@
+package datastores
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RedisError is an error reply returned by a redis server
+type RedisError struct {
+	// Message is the error message returned by the server
+	Message string
+}
+
+// Error returns the error message
+func (e RedisError) Error() string {
+	return e.Message
+}
+
+// RedisClient is a minimal RESP client used to talk to redis services
+type RedisClient struct {
+	// conn is the underlying connection
+	conn net.Conn
+
+	// reader is the buffered reader for the connection
+	reader *bufio.Reader
+
+	// timeout is the per-command timeout
+	timeout time.Duration
+}
+
+// NewRedisClientInput is the input for the NewRedisClient function
+type NewRedisClientInput struct {
+	// Address is the host:port to connect to
+	Address string
+
+	// Password is the password to authenticate with
+	Password string
+
+	// TLSConfig is the tls configuration to connect with, where nil connects without tls
+	TLSConfig *tls.Config
+
+	// Timeout is the timeout for connecting and for each command
+	Timeout time.Duration
+}
+
+// NewRedisClient connects and authenticates to a redis server
+func NewRedisClient(ctx context.Context, input NewRedisClientInput) (*RedisClient, error) {
+	if input.Timeout == 0 {
+		input.Timeout = 5 * time.Second
+	}
+
+	dialer := net.Dialer{Timeout: input.Timeout}
+	var conn net.Conn
+	var err error
+	if input.TLSConfig != nil {
+		tlsDialer := tls.Dialer{NetDialer: &dialer, Config: input.TLSConfig}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", input.Address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", input.Address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", input.Address, err)
+	}
+
+	client := &RedisClient{
+		conn:    conn,
+		reader:  bufio.NewReader(conn),
+		timeout: input.Timeout,
+	}
+
+	if input.Password != "" {
+		if _, err := client.Do("AUTH", input.Password); err != nil {
+			client.Close() //nolint:errcheck
+			return nil, fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+
+	return client, nil
+}
+
+// Close closes the connection
+func (c *RedisClient) Close() error {
+	return c.conn.Close()
+}
+
+// Do sends a command and returns the reply
+func (c *RedisClient) Do(args ...string) (interface{}, error) {
+	if c.timeout > 0 {
+		if err := c.conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+			return nil, err
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, err
+	}
+
+	return c.readReply()
+}
+
+// String sends a command and returns the reply as a string
+func (c *RedisClient) String(args ...string) (string, error) {
+	reply, err := c.Do(args...)
+	if err != nil {
+		return "", err
+	}
+
+	switch v := reply.(type) {
+	case string:
+		return v, nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case nil:
+		return "", nil
+	default:
+		return "", fmt.Errorf("unexpected reply type %T", reply)
+	}
+}
+
+// Info runs the INFO command for a section and parses the result
+func (c *RedisClient) Info(section string) (map[string]string, error) {
+	reply, err := c.String("INFO", section)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseRedisInfo(reply), nil
+}
+
+// Receive waits for the next reply pushed by the server, such as a message on a subscribed channel
+func (c *RedisClient) Receive() (interface{}, error) {
+	if err := c.conn.SetDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+
+	return c.readReply()
+}
+
+// readReply reads a single RESP reply
+func (c *RedisClient) readReply() (interface{}, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty reply from server")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, RedisError{Message: line[1:]}
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		length, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, length+2)
+		if _, err := io.ReadFull(c.reader, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:length]), nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		values := make([]interface{}, count)
+		for i := range values {
+			values[i], err = c.readReply()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("unexpected reply: %s", line)
+	}
+}
+
+// ParseRedisInfo parses the output of the INFO command into a map
+func ParseRedisInfo(info string) map[string]string {
+	values := map[string]string{}
+	for line := range strings.SplitSeq(info, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		values[key] = value
+	}
+	return values
+}
@@ -0,0 +1,55 @@
+package datastores
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// ConfigureCluster enables cluster mode in the redis.conf shared by the members of a service
+//
+// Members announce their network alias rather than their ip address, so clients following redirects
+// reach them through docker dns
+func (s *RedisService) ConfigureCluster(ctx context.Context, serviceName string) error {
+	return setRedisConfigDirectives(s, serviceName, map[string]string{
+		"cluster-enabled":                 "yes",
+		"cluster-config-file":             "nodes.conf",
+		"cluster-preferred-endpoint-type": "hostname",
+		// replicas authenticate to the primary of their shard with the password of the service
+		"masterauth": common.ReadFirstLine(Files(s, serviceName).Password),
+	})
+}
+
+// CreateCluster runs redis-cli --cluster create in the service container against every member
+func (s *RedisService) CreateCluster(ctx context.Context, serviceName string, replicas int) error {
+	// redis-cli only accepts ip addresses when creating a cluster
+	network := ClusterNetwork(s, serviceName)
+	port := strconv.Itoa(s.Properties().Ports[0])
+	addresses := []string{}
+	for _, member := range ServiceMembers(s, serviceName) {
+		containerName := MemberContainerName(s, serviceName, member)
+		memberIP, _ := common.DockerInspect(containerName, fmt.Sprintf("{{ with index .NetworkSettings.Networks %q }}{{ .IPAddress }}{{ end }}", network))
+		if memberIP == "" {
+			return fmt.Errorf("unable to determine ip address of %s on network %s", containerName, network)
+		}
+		addresses = append(addresses, net.JoinHostPort(memberIP, port))
+	}
+
+	// the password is passed through the environment so it does not show up in the process list
+	args := []string{"container", "exec", "--env=REDISCLI_AUTH", ContainerName(s, serviceName), "redis-cli", "--cluster", "create"}
+	args = append(args, addresses...)
+	args = append(args, "--cluster-replicas", strconv.Itoa(replicas), "--cluster-yes")
+	result, err := CallExecCommandWithContext(ctx, common.ExecCommandInput{
+		Command: common.DockerBin(),
+		Args:    args,
+		Env:     map[string]string{"REDISCLI_AUTH": common.ReadFirstLine(Files(s, serviceName).Password)},
+	})
+	if err != nil {
+		// redis-cli reports why the cluster could not be created on stdout
+		return fmt.Errorf("failed to create cluster: %w\n%s", err, result.StdoutContents())
+	}
+	return nil
+}
@@ -0,0 +1,498 @@
+package datastores
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// redisConfigMount is where the config folder of a redis service is mounted in its container
+const redisConfigMount = "/usr/local/etc/redis"
+
+// redisConfigFile returns the path of the redis.conf of a service on the host
+func redisConfigFile(s Datastore, serviceName string) string {
+	return filepath.Join(Folders(s, serviceName).Config, "redis.conf")
+}
+
+// setRedisConfigDirectives rewrites directives in the redis.conf of a service, keeping comments and unrelated lines
+func setRedisConfigDirectives(s Datastore, serviceName string, directives map[string]string) error {
+	configFile := redisConfigFile(s, serviceName)
+	lines, err := common.FileToSlice(configFile)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", configFile, err)
+	}
+
+	err = common.WriteStringToFile(common.WriteStringToFileInput{
+		Content:   strings.Join(rewriteRedisConfigLines(lines, directives), "\n"),
+		Filename:  configFile,
+		GroupName: SystemGroup(),
+		Mode:      0644,
+		Username:  SystemUser(),
+	})
+	if err != nil {
+		return fmt.Errorf("unable to write to %s: %w", configFile, err)
+	}
+	return nil
+}
+
+// rewriteRedisConfigLines sets directives in the lines of a redis.conf
+//
+// Each directive replaces its existing line, or the first commented out example of it, and is appended otherwise.
+// Duplicate lines of a directive are dropped, and an empty value removes the directive but not its examples.
+func rewriteRedisConfigLines(lines []string, directives map[string]string) []string {
+	present := map[string]bool{}
+	for _, line := range lines {
+		if name, ok := redisConfigDirectiveName(line, false); ok {
+			present[name] = true
+		}
+	}
+
+	written := map[string]bool{}
+	newLines := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if name, ok := redisConfigDirectiveName(line, false); ok {
+			if value, managed := directives[name]; managed {
+				if !written[name] && value != "" {
+					newLines = append(newLines, name+" "+value)
+					written[name] = true
+				}
+				continue
+			}
+		}
+
+		// commented out examples are only replaced when the directive is not set elsewhere, and otherwise kept
+		if name, ok := redisConfigDirectiveName(line, true); ok && !present[name] && !written[name] && directives[name] != "" {
+			newLines = append(newLines, name+" "+directives[name])
+			written[name] = true
+			continue
+		}
+
+		newLines = append(newLines, line)
+	}
+
+	names := make([]string, 0, len(directives))
+	for name := range directives {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !written[name] && directives[name] != "" {
+			newLines = append(newLines, name+" "+directives[name])
+		}
+	}
+	return newLines
+}
+
+// redisConfigDirectiveName returns the lowercased directive a redis.conf line sets, or its commented out form sets
+func redisConfigDirectiveName(line string, commented bool) (string, bool) {
+	line = strings.TrimSpace(line)
+	if commented {
+		if !strings.HasPrefix(line, "#") {
+			return "", false
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "#"))
+	} else if strings.HasPrefix(line, "#") {
+		return "", false
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", false
+	}
+	return strings.ToLower(fields[0]), true
+}
+
+// redisManagedDirectives are the directives dokku-datastore writes itself, along with what manages them
+var redisManagedDirectives = map[string]string{
+	"aclfile":                         "the acl-set and acl-revoke commands",
+	"bind":                            "the service container",
+	"cluster-config-file":             "create --cluster",
+	"cluster-enabled":                 "create --cluster",
+	"cluster-preferred-endpoint-type": "create --cluster",
+	"dir":                             "the service container",
+	"masterauth":                      "the rotate-password command",
+	"maxmemory":                       "the resources command",
+	"maxmemory-policy":                "the resources command",
+	"port":                            "the tls-enable and tls-disable commands",
+	"replica-announce-ip":             "create --ha",
+	"replica-read-only":               "the replicate and promote-replica commands",
+	"replicaof":                       "the replicate and promote-replica commands",
+	"requirepass":                     "the rotate-password command",
+	"slaveof":                         "the replicate and promote-replica commands",
+}
+
+// redisRepeatableDirectives may appear many times in redis.conf, so they cannot be set as a single line
+var redisRepeatableDirectives = []string{
+	"include",
+	"loadmodule",
+	"rename-command",
+	"user",
+}
+
+// redisRestartDirectives cannot be changed with CONFIG SET, so running services only pick them up when restarted
+var redisRestartDirectives = []string{
+	"always-show-logo",
+	"cluster-port",
+	"daemonize",
+	"databases",
+	"disable-thp",
+	"enable-debug-command",
+	"enable-module-command",
+	"enable-protected-configs",
+	"io-threads",
+	"io-threads-do-reads",
+	"logfile",
+	"pidfile",
+	"proc-title-template",
+	"set-proc-title",
+	"supervised",
+	"syslog-enabled",
+	"syslog-facility",
+	"syslog-ident",
+	"tcp-backlog",
+	"unixsocket",
+	"unixsocketperm",
+}
+
+// redisKnownDirectives are the directives of redis 7 that are not already managed, repeatable or restart-only,
+// so a misspelled directive is rejected instead of stopping redis from starting
+var redisKnownDirectives = []string{
+	"acl-pubsub-default",
+	"acllog-max-len",
+	"active-defrag-cycle-max",
+	"active-defrag-cycle-min",
+	"active-defrag-ignore-bytes",
+	"active-defrag-max-scan-fields",
+	"active-defrag-threshold-lower",
+	"active-defrag-threshold-upper",
+	"active-expire-effort",
+	"activedefrag",
+	"activerehashing",
+	"aof-load-truncated",
+	"aof-rewrite-incremental-fsync",
+	"aof-timestamp-enabled",
+	"aof-use-rdb-preamble",
+	"appenddirname",
+	"appendfilename",
+	"appendfsync",
+	"appendonly",
+	"auto-aof-rewrite-min-size",
+	"auto-aof-rewrite-percentage",
+	"bind-source-addr",
+	"busy-reply-threshold",
+	"client-output-buffer-limit",
+	"client-query-buffer-limit",
+	"cluster-allow-pubsubshard-when-down",
+	"cluster-allow-reads-when-down",
+	"cluster-allow-replica-migration",
+	"cluster-announce-bus-port",
+	"cluster-announce-hostname",
+	"cluster-announce-human-nodename",
+	"cluster-announce-ip",
+	"cluster-announce-port",
+	"cluster-link-sendbuf-limit",
+	"cluster-migration-barrier",
+	"cluster-node-timeout",
+	"cluster-replica-no-failover",
+	"cluster-replica-validity-factor",
+	"cluster-require-full-coverage",
+	"crash-log-enabled",
+	"crash-memcheck-enabled",
+	"dbfilename",
+	"dynamic-hz",
+	"hash-max-listpack-entries",
+	"hash-max-listpack-value",
+	"hash-max-ziplist-entries",
+	"hash-max-ziplist-value",
+	"hll-sparse-max-bytes",
+	"hz",
+	"jemalloc-bg-thread",
+	"latency-monitor-threshold",
+	"latency-tracking",
+	"latency-tracking-info-percentiles",
+	"lazyfree-lazy-eviction",
+	"lazyfree-lazy-expire",
+	"lazyfree-lazy-server-del",
+	"lazyfree-lazy-user-del",
+	"lazyfree-lazy-user-flush",
+	"lfu-decay-time",
+	"lfu-log-factor",
+	"list-compress-depth",
+	"list-max-listpack-size",
+	"list-max-ziplist-size",
+	"locale-collate",
+	"loglevel",
+	"lua-time-limit",
+	"masteruser",
+	"maxclients",
+	"maxmemory-clients",
+	"maxmemory-eviction-tenacity",
+	"maxmemory-samples",
+	"min-replicas-max-lag",
+	"min-replicas-to-write",
+	"min-slaves-max-lag",
+	"min-slaves-to-write",
+	"no-appendfsync-on-rewrite",
+	"notify-keyspace-events",
+	"oom-score-adj",
+	"oom-score-adj-values",
+	"propagation-error-behavior",
+	"protected-mode",
+	"rdb-del-sync-files",
+	"rdb-save-incremental-fsync",
+	"rdbchecksum",
+	"rdbcompression",
+	"repl-backlog-size",
+	"repl-backlog-ttl",
+	"repl-disable-tcp-nodelay",
+	"repl-diskless-load",
+	"repl-diskless-sync",
+	"repl-diskless-sync-delay",
+	"repl-diskless-sync-max-replicas",
+	"repl-ping-replica-period",
+	"repl-ping-slave-period",
+	"repl-timeout",
+	"replica-announce-port",
+	"replica-announced",
+	"replica-ignore-disk-write-errors",
+	"replica-ignore-maxmemory",
+	"replica-lazy-flush",
+	"replica-priority",
+	"replica-serve-stale-data",
+	"sanitize-dump-payload",
+	"save",
+	"set-max-intset-entries",
+	"set-max-listpack-entries",
+	"set-max-listpack-value",
+	"shutdown-on-sigint",
+	"shutdown-on-sigterm",
+	"shutdown-timeout",
+	"slave-announce-port",
+	"slave-ignore-maxmemory",
+	"slave-lazy-flush",
+	"slave-priority",
+	"slave-serve-stale-data",
+	"slowlog-log-slower-than",
+	"slowlog-max-len",
+	"stop-writes-on-bgsave-error",
+	"stream-node-max-bytes",
+	"stream-node-max-entries",
+	"tcp-keepalive",
+	"timeout",
+	"tracking-table-max-keys",
+	"zset-max-listpack-entries",
+	"zset-max-listpack-value",
+	"zset-max-ziplist-entries",
+	"zset-max-ziplist-value",
+}
+
+// redisSecretDirectives hold passwords, so their values are redacted when the configuration is shown
+var redisSecretDirectives = []string{"masterauth", "requirepass"}
+
+// redactRedisConfigDirectives replaces the values of secret directives with a placeholder
+func redactRedisConfigDirectives(directives []ConfigDirective) []ConfigDirective {
+	for i, directive := range directives {
+		if slices.Contains(redisSecretDirectives, directive.Name) && directive.Value != "" {
+			directives[i].Value = "[REDACTED]"
+		}
+	}
+	return directives
+}
+
+// ConfigDirectives returns the directives set in the redis.conf of a service, with passwords redacted
+func (s *RedisService) ConfigDirectives(serviceName string) ([]ConfigDirective, error) {
+	configFile := redisConfigFile(s, serviceName)
+	lines, err := common.FileToSlice(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", configFile, err)
+	}
+
+	directives := []ConfigDirective{}
+	for _, line := range lines {
+		name, ok := redisConfigDirectiveName(line, false)
+		if !ok {
+			continue
+		}
+
+		value := strings.TrimSpace(line)
+		value = strings.TrimSpace(value[len(name):])
+		directives = append(directives, ConfigDirective{Name: name, Value: value})
+	}
+	return redactRedisConfigDirectives(directives), nil
+}
+
+// EffectiveConfig returns every parameter of the running service as reported by CONFIG GET, or the
+// directives of its redis.conf when it is not running, with passwords redacted
+func (s *RedisService) EffectiveConfig(ctx context.Context, serviceName string) ([]ConfigDirective, error) {
+	runningContainerID := LiveContainerID(ctx, LiveContainerIDInput{
+		Datastore:   s,
+		Filter:      "status=running",
+		ServiceName: serviceName,
+	})
+	if runningContainerID == "" {
+		return s.ConfigDirectives(serviceName)
+	}
+
+	directives := []ConfigDirective{}
+	err := s.withMemberClient(ctx, serviceName, "", func(client *RedisClient) error {
+		reply, err := client.Do("CONFIG", "GET", "*")
+		if err != nil {
+			return fmt.Errorf("failed to get config: %w", err)
+		}
+
+		values, ok := reply.([]interface{})
+		if !ok {
+			return fmt.Errorf("unexpected config reply: %v", reply)
+		}
+		for i := 0; i+1 < len(values); i += 2 {
+			name, _ := values[i].(string)
+			value, _ := values[i+1].(string)
+			directives = append(directives, ConfigDirective{Name: name, Value: value})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(directives, func(i, j int) bool {
+		return directives[i].Name < directives[j].Name
+	})
+	return redactRedisConfigDirectives(directives), nil
+}
+
+// SetConfigDirective writes a directive to redis.conf, setting it on running services with CONFIG SET first
+// so invalid values are rejected before they reach the file
+//
+// The directive is written to every member of a high availability service, and directives that cannot be
+// changed with CONFIG SET are flagged as requiring a restart.
+func (s *RedisService) SetConfigDirective(ctx context.Context, serviceName string, name string, value string) (ConfigChange, error) {
+	name = strings.ToLower(name)
+	if err := validateRedisConfigDirective(name); err != nil {
+		return ConfigChange{}, err
+	}
+	if !s.knownConfigDirective(ctx, serviceName, name) {
+		return ConfigChange{}, fmt.Errorf("unknown directive %s", name)
+	}
+	if strings.TrimSpace(value) == "" {
+		return ConfigChange{}, fmt.Errorf("a value is required for directive %s", name)
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return ConfigChange{}, fmt.Errorf("the value of directive %s must be a single line", name)
+	}
+
+	change := ConfigChange{Name: name, Value: value}
+	running := s.anyServiceRunning(ctx, serviceName)
+	if slices.Contains(redisRestartDirectives, name) {
+		change.RestartRequired = running
+	} else if running {
+		// redis.conf values may be quoted, while CONFIG SET takes the value itself
+		liveValue := value
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			liveValue = unquoted
+		}
+
+		for _, member := range HAServices(s, serviceName) {
+			err := s.withRunningClient(ctx, member, func(client *RedisClient) error {
+				if _, err := client.Do("CONFIG", "SET", name, liveValue); err != nil {
+					return fmt.Errorf("failed to set %s: %w", name, err)
+				}
+				return nil
+			})
+			if err != nil {
+				return ConfigChange{}, err
+			}
+		}
+		change.Applied = true
+	}
+
+	for _, member := range HAServices(s, serviceName) {
+		if err := setRedisConfigDirectives(s, member, map[string]string{name: value}); err != nil {
+			return ConfigChange{}, err
+		}
+	}
+	return change, nil
+}
+
+// UnsetConfigDirective removes a directive from redis.conf
+//
+// Redis cannot reset a single parameter to its default, so running services keep the previous value until restarted.
+func (s *RedisService) UnsetConfigDirective(ctx context.Context, serviceName string, name string) (ConfigChange, error) {
+	name = strings.ToLower(name)
+	if err := validateRedisConfigDirective(name); err != nil {
+		return ConfigChange{}, err
+	}
+
+	directives, err := s.ConfigDirectives(serviceName)
+	if err != nil {
+		return ConfigChange{}, err
+	}
+	if !slices.ContainsFunc(directives, func(directive ConfigDirective) bool { return directive.Name == name }) {
+		return ConfigChange{}, fmt.Errorf("directive %s is not set for service %s", name, serviceName)
+	}
+
+	for _, member := range HAServices(s, serviceName) {
+		if err := setRedisConfigDirectives(s, member, map[string]string{name: ""}); err != nil {
+			return ConfigChange{}, err
+		}
+	}
+	return ConfigChange{Name: name, RestartRequired: s.anyServiceRunning(ctx, serviceName)}, nil
+}
+
+// knownConfigDirective returns whether redis accepts a directive, which is one of the known directives or
+// one the running service reports with CONFIG GET, such as a directive added by a newer redis
+func (s *RedisService) knownConfigDirective(ctx context.Context, serviceName string, name string) bool {
+	if slices.Contains(redisKnownDirectives, name) || slices.Contains(redisRestartDirectives, name) {
+		return true
+	}
+
+	known := false
+	s.withRunningClient(ctx, serviceName, func(client *RedisClient) error { //nolint:errcheck
+		reply, err := client.Do("CONFIG", "GET", name)
+		if err != nil {
+			return err
+		}
+		values, ok := reply.([]interface{})
+		known = ok && len(values) > 0
+		return nil
+	})
+	return known
+}
+
+// anyServiceRunning returns whether a container of a service, or of a member of a high availability service, is running
+func (s *RedisService) anyServiceRunning(ctx context.Context, serviceName string) bool {
+	for _, member := range HAServices(s, serviceName) {
+		containerIDs := LiveContainerIDs(ctx, LiveContainerIDsInput{
+			Datastore:   s,
+			Filter:      "status=running",
+			ServiceName: member,
+		})
+		if len(containerIDs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// validateRedisConfigDirective checks that a directive can be managed through the config commands
+func validateRedisConfigDirective(name string) error {
+	if name == "" || strings.HasPrefix(name, "#") || strings.ContainsAny(name, " \t\r\n\"'") {
+		return fmt.Errorf("invalid directive name %q", name)
+	}
+	if manager, ok := redisManagedDirectives[name]; ok {
+		return fmt.Errorf("directive %s is managed by %s", name, manager)
+	}
+	if strings.HasPrefix(name, "tls-") {
+		return fmt.Errorf("directive %s is managed by the tls-enable and tls-disable commands", name)
+	}
+	if slices.Contains(redisRepeatableDirectives, name) {
+		return fmt.Errorf("directive %s may appear more than once and must be edited in redis.conf", name)
+	}
+	return nil
+}
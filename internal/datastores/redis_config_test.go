@@ -0,0 +1,110 @@
+package datastores
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRewriteRedisConfigLines(t *testing.T) {
+	tests := []struct {
+		name       string
+		lines      []string
+		directives map[string]string
+		want       []string
+	}{
+		{
+			name:       "replaces an existing line",
+			lines:      []string{"port 6379", "maxmemory 100mb", "timeout 0"},
+			directives: map[string]string{"maxmemory": "200mb"},
+			want:       []string{"port 6379", "maxmemory 200mb", "timeout 0"},
+		},
+		{
+			name:       "matches names case insensitively",
+			lines:      []string{"MaxMemory 100mb"},
+			directives: map[string]string{"maxmemory": "200mb"},
+			want:       []string{"maxmemory 200mb"},
+		},
+		{
+			name:       "replaces a commented example",
+			lines:      []string{"# maxmemory <bytes>", "timeout 0"},
+			directives: map[string]string{"maxmemory": "200mb"},
+			want:       []string{"maxmemory 200mb", "timeout 0"},
+		},
+		{
+			name:       "replaces only the first commented example",
+			lines:      []string{"# save 3600 1", "# save 300 100"},
+			directives: map[string]string{"save": "60 10000"},
+			want:       []string{"save 60 10000", "# save 300 100"},
+		},
+		{
+			name:       "keeps a commented example when the directive is set",
+			lines:      []string{"# maxmemory <bytes>", "maxmemory 100mb"},
+			directives: map[string]string{"maxmemory": "200mb"},
+			want:       []string{"# maxmemory <bytes>", "maxmemory 200mb"},
+		},
+		{
+			name:       "collapses duplicate lines",
+			lines:      []string{"maxmemory 100mb", "timeout 0", "maxmemory 150mb"},
+			directives: map[string]string{"maxmemory": "200mb"},
+			want:       []string{"maxmemory 200mb", "timeout 0"},
+		},
+		{
+			name:       "removes every line of a directive",
+			lines:      []string{"maxmemory 100mb", "timeout 0", "maxmemory 150mb"},
+			directives: map[string]string{"maxmemory": ""},
+			want:       []string{"timeout 0"},
+		},
+		{
+			name:       "keeps commented examples on removal",
+			lines:      []string{"# maxmemory <bytes>", "maxmemory 100mb"},
+			directives: map[string]string{"maxmemory": ""},
+			want:       []string{"# maxmemory <bytes>"},
+		},
+		{
+			name:       "ignores removal of a missing directive",
+			lines:      []string{"timeout 0"},
+			directives: map[string]string{"maxmemory": ""},
+			want:       []string{"timeout 0"},
+		},
+		{
+			name:       "appends missing directives in name order",
+			lines:      []string{"timeout 0"},
+			directives: map[string]string{"maxmemory-policy": "allkeys-lru", "maxmemory": "200mb"},
+			want:       []string{"timeout 0", "maxmemory 200mb", "maxmemory-policy allkeys-lru"},
+		},
+		{
+			name:       "keeps unrelated comments and blank lines",
+			lines:      []string{"# general", "", "timeout 0"},
+			directives: map[string]string{"timeout": "300"},
+			want:       []string{"# general", "", "timeout 300"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rewriteRedisConfigLines(tt.lines, tt.directives)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("rewriteRedisConfigLines(%q, %v) = %q, want %q", tt.lines, tt.directives, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactRedisConfigDirectives(t *testing.T) {
+	directives := []ConfigDirective{
+		{Name: "requirepass", Value: "secret"},
+		{Name: "masterauth", Value: "secret"},
+		{Name: "masterauth", Value: ""},
+		{Name: "maxmemory", Value: "100mb"},
+	}
+	want := []ConfigDirective{
+		{Name: "requirepass", Value: "[REDACTED]"},
+		{Name: "masterauth", Value: "[REDACTED]"},
+		{Name: "masterauth", Value: ""},
+		{Name: "maxmemory", Value: "100mb"},
+	}
+
+	if got := redactRedisConfigDirectives(directives); !slices.Equal(got, want) {
+		t.Errorf("redactRedisConfigDirectives() = %v, want %v", got, want)
+	}
+}
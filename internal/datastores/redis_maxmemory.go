@@ -0,0 +1,60 @@
+package datastores
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// redisDefaultMaxmemoryPolicy is the eviction policy redis uses when none is set
+const redisDefaultMaxmemoryPolicy = "noeviction"
+
+// MaxmemoryPolicies returns the eviction policies supported by redis
+func (s *RedisService) MaxmemoryPolicies() []string {
+	return []string{
+		"noeviction",
+		"allkeys-lru",
+		"allkeys-lfu",
+		"allkeys-random",
+		"volatile-lru",
+		"volatile-lfu",
+		"volatile-random",
+		"volatile-ttl",
+	}
+}
+
+// ConfigureMaxmemory sets maxmemory to the configured share of the MEMORY limit, along with the eviction policy
+//
+// Services without a MEMORY limit have no maxmemory, as redis cannot see the limit of its container
+func (s *RedisService) ConfigureMaxmemory(ctx context.Context, serviceName string) error {
+	maxmemory := ""
+	if megabytes := MaxmemoryMegabytes(s, serviceName); megabytes > 0 {
+		maxmemory = strconv.Itoa(megabytes) + "mb"
+	}
+	policy := MaxmemoryPolicy(s, serviceName)
+
+	err := setRedisConfigDirectives(s, serviceName, map[string]string{
+		"maxmemory":        maxmemory,
+		"maxmemory-policy": policy,
+	})
+	if err != nil {
+		return err
+	}
+
+	// the directives removed from redis.conf are reset to their defaults on running services
+	if maxmemory == "" {
+		maxmemory = "0"
+	}
+	if policy == "" {
+		policy = redisDefaultMaxmemoryPolicy
+	}
+	return s.withRunningClient(ctx, serviceName, func(client *RedisClient) error {
+		if _, err := client.Do("CONFIG", "SET", "maxmemory", maxmemory); err != nil {
+			return fmt.Errorf("failed to set maxmemory: %w", err)
+		}
+		if _, err := client.Do("CONFIG", "SET", "maxmemory-policy", policy); err != nil {
+			return fmt.Errorf("failed to set maxmemory-policy: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,173 @@
+package datastores
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// redisDefaultSave is the snapshot schedule redis uses when redis.conf has none
+const redisDefaultSave = "3600 1 300 100 60 10000"
+
+// redisAppendfsyncPolicies are the fsync policies of the redis append only file
+var redisAppendfsyncPolicies = []string{"always", "everysec", "no"}
+
+// PersistenceModes returns the persistence modes supported by redis
+func (s *RedisService) PersistenceModes() []string {
+	return []string{"rdb", "aof", "both", "none"}
+}
+
+// ValidatePersistence checks that a snapshot schedule is made of seconds and changes pairs, and that each
+// setting applies to the persistence mode
+func (s *RedisService) ValidatePersistence(settings PersistenceSettings) error {
+	rdb := settings.Mode == "rdb" || settings.Mode == "both"
+	aof := settings.Mode == "aof" || settings.Mode == "both"
+
+	if settings.Save != "" {
+		if !rdb {
+			return fmt.Errorf("a snapshot schedule only applies to the rdb and both persistence modes")
+		}
+
+		fields := strings.Fields(settings.Save)
+		if len(fields)%2 != 0 {
+			return fmt.Errorf("invalid snapshot schedule %q, must be pairs of seconds and changes", settings.Save)
+		}
+		for _, field := range fields {
+			if value, err := strconv.Atoi(field); err != nil || value < 1 {
+				return fmt.Errorf("invalid snapshot schedule %q, must be pairs of seconds and changes", settings.Save)
+			}
+		}
+	}
+
+	if settings.Appendfsync != "" {
+		if !aof {
+			return fmt.Errorf("an fsync policy only applies to the aof and both persistence modes")
+		}
+		if !slices.Contains(redisAppendfsyncPolicies, settings.Appendfsync) {
+			return fmt.Errorf("invalid fsync policy %s, must be one of %s", settings.Appendfsync, strings.Join(redisAppendfsyncPolicies, ", "))
+		}
+	}
+	return nil
+}
+
+// ConfigurePersistence writes the save, appendonly and appendfsync directives of a persistence mode to redis.conf
+//
+// The append only file is flushed every second unless told otherwise, and a snapshot schedule disabled by the
+// config template is restored to the redis default when snapshots are wanted without one.
+func (s *RedisService) ConfigurePersistence(ctx context.Context, serviceName string, settings PersistenceSettings) error {
+	rdb := settings.Mode == "rdb" || settings.Mode == "both"
+	aof := settings.Mode == "aof" || settings.Mode == "both"
+
+	directives := map[string]string{
+		"appendonly":  "no",
+		"appendfsync": "",
+		"save":        `""`,
+	}
+	if rdb {
+		directives["save"] = settings.Save
+		if settings.Save == "" {
+			if s.rdbEnabled(serviceName) {
+				delete(directives, "save")
+			} else {
+				directives["save"] = redisDefaultSave
+			}
+		}
+	}
+	if aof {
+		directives["appendonly"] = "yes"
+		directives["appendfsync"] = settings.Appendfsync
+		if settings.Appendfsync == "" {
+			directives["appendfsync"] = "everysec"
+		}
+	}
+
+	return setRedisConfigDirectives(s, serviceName, directives)
+}
+
+// PersistenceStatus returns the persistence mode of redis.conf, along with the last save and aof rewrite of the
+// running service as reported by INFO persistence
+func (s *RedisService) PersistenceStatus(ctx context.Context, serviceName string) PersistenceStatus {
+	status := PersistenceStatus{Mode: s.PersistenceMode(serviceName)}
+
+	runningContainerID := LiveContainerID(ctx, LiveContainerIDInput{
+		Datastore:   s,
+		Filter:      "status=running",
+		ServiceName: serviceName,
+	})
+	if runningContainerID == "" {
+		return status
+	}
+
+	client, err := s.client(ctx, serviceName)
+	if err != nil {
+		return status
+	}
+	defer client.Close() //nolint:errcheck
+
+	info, err := client.Info("persistence")
+	if err != nil {
+		return status
+	}
+
+	if lastSave, err := strconv.ParseInt(info["rdb_last_save_time"], 10, 64); err == nil {
+		status.LastSave = time.Unix(lastSave, 0).UTC().Format(time.RFC3339)
+	}
+
+	switch {
+	case info["aof_enabled"] != "1":
+		status.AOFRewrite = "disabled"
+	case info["aof_rewrite_in_progress"] == "1":
+		status.AOFRewrite = "in progress"
+	case info["aof_rewrite_scheduled"] == "1":
+		status.AOFRewrite = "scheduled"
+	default:
+		status.AOFRewrite = info["aof_last_bgrewrite_status"]
+	}
+	return status
+}
+
+// PersistenceMode returns the persistence mode redis.conf of a service configures
+func (s *RedisService) PersistenceMode(serviceName string) string {
+	directives, err := s.ConfigDirectives(serviceName)
+	if err != nil {
+		return ""
+	}
+
+	aof := false
+	for _, directive := range directives {
+		if directive.Name == "appendonly" {
+			aof = strings.EqualFold(directive.Value, "yes")
+		}
+	}
+
+	rdb := s.rdbEnabled(serviceName)
+	switch {
+	case rdb && aof:
+		return "both"
+	case aof:
+		return "aof"
+	case rdb:
+		return "rdb"
+	default:
+		return "none"
+	}
+}
+
+// rdbEnabled returns whether redis.conf of a service takes snapshots, which it does unless save is set to an empty value
+func (s *RedisService) rdbEnabled(serviceName string) bool {
+	directives, err := s.ConfigDirectives(serviceName)
+	if err != nil {
+		return true
+	}
+
+	enabled := true
+	for _, directive := range directives {
+		if directive.Name == "save" {
+			enabled = directive.Value != `""` && directive.Value != "''" && directive.Value != ""
+		}
+	}
+	return enabled
+}
@@ -0,0 +1,127 @@
+package datastores
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// ConfigureReplica points redis.conf at the primary and switches a running service over with REPLICAOF
+func (s *RedisService) ConfigureReplica(ctx context.Context, serviceName string, primaryName string) error {
+	primaryHost := DNSHostname(s, primaryName)
+	primaryPort := strconv.Itoa(s.Properties().Ports[0])
+	primaryPassword := common.ReadFirstLine(Files(s, primaryName).Password)
+
+	err := setRedisConfigDirectives(s, serviceName, map[string]string{
+		"replicaof":         primaryHost + " " + primaryPort,
+		"masterauth":        primaryPassword,
+		"replica-read-only": "yes",
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.withRunningClient(ctx, serviceName, func(client *RedisClient) error {
+		if _, err := client.Do("CONFIG", "SET", "masterauth", primaryPassword); err != nil {
+			return fmt.Errorf("failed to set masterauth: %w", err)
+		}
+		if _, err := client.Do("REPLICAOF", primaryHost, primaryPort); err != nil {
+			return fmt.Errorf("failed to start replication: %w", err)
+		}
+		return nil
+	})
+}
+
+// PromoteReplica removes the replication directives from redis.conf and runs REPLICAOF NO ONE
+func (s *RedisService) PromoteReplica(ctx context.Context, serviceName string) error {
+	err := s.withRunningClient(ctx, serviceName, func(client *RedisClient) error {
+		_, err := client.Do("REPLICAOF", "NO", "ONE")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return setRedisConfigDirectives(s, serviceName, map[string]string{
+		"replicaof":         "",
+		"masterauth":        "",
+		"replica-read-only": "",
+	})
+}
+
+// ReplicationStatus reads INFO replication from a running service
+func (s *RedisService) ReplicationStatus(ctx context.Context, serviceName string) (ReplicationStatus, error) {
+	client, err := s.client(ctx, serviceName)
+	if err != nil {
+		return ReplicationStatus{}, err
+	}
+	defer client.Close() //nolint:errcheck
+
+	info, err := client.Info("replication")
+	if err != nil {
+		return ReplicationStatus{}, fmt.Errorf("failed to read replication info: %w", err)
+	}
+
+	status := ReplicationStatus{Role: "primary"}
+	status.ConnectedReplicas, _ = strconv.Atoi(info["connected_slaves"])
+	status.Offset, _ = strconv.ParseInt(info["master_repl_offset"], 10, 64)
+	if info["role"] == "slave" {
+		status.Role = "replica"
+		status.LinkStatus = info["master_link_status"]
+		status.LastIOSeconds, _ = strconv.Atoi(info["master_last_io_seconds_ago"])
+		status.Offset, _ = strconv.ParseInt(info["slave_repl_offset"], 10, 64)
+	}
+	return status, nil
+}
+
+// updateReplicaMasterauth points the replicas of a primary at its new password
+func (s *RedisService) updateReplicaMasterauth(ctx context.Context, primaryName string, password string) error {
+	for _, replicaName := range Replicas(s, primaryName) {
+		if err := setRedisConfigDirectives(s, replicaName, map[string]string{"masterauth": password}); err != nil {
+			return err
+		}
+
+		err := s.withRunningClient(ctx, replicaName, func(client *RedisClient) error {
+			_, err := client.Do("CONFIG", "SET", "masterauth", password)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update masterauth of replica %s: %w", replicaName, err)
+		}
+	}
+	return nil
+}
+
+// withRunningClient calls fn with an authenticated client for each running member of a service,
+// and does nothing for stopped ones
+func (s *RedisService) withRunningClient(ctx context.Context, serviceName string, fn func(*RedisClient) error) error {
+	for _, member := range ServiceMembers(s, serviceName) {
+		runningContainerID := LiveContainerID(ctx, LiveContainerIDInput{
+			Datastore:   s,
+			Filter:      "status=running",
+			Member:      member,
+			ServiceName: serviceName,
+		})
+		if runningContainerID == "" {
+			continue
+		}
+
+		if err := s.withMemberClient(ctx, serviceName, member, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withMemberClient calls fn with an authenticated client for a member of a service, closing it afterwards
+func (s *RedisService) withMemberClient(ctx context.Context, serviceName string, member string, fn func(*RedisClient) error) error {
+	client, err := s.memberClient(ctx, serviceName, member)
+	if err != nil {
+		return err
+	}
+	defer client.Close() //nolint:errcheck
+
+	return fn(client)
+}
@@ -0,0 +1,427 @@
+package datastores
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// redisSentinelPort is the port sentinels listen on
+const redisSentinelPort = 26379
+
+// redisSentinelContainerName returns the name of a sentinel container of a service
+func redisSentinelContainerName(s Datastore, serviceName string, index int) string {
+	return fmt.Sprintf("%s.sentinel.%d", ContainerName(s, serviceName), index)
+}
+
+// redisSentinelHostname returns the network alias of a sentinel container of a service
+func redisSentinelHostname(s Datastore, serviceName string, index int) string {
+	return fmt.Sprintf("%s-sentinel-%d", DNSHostname(s, serviceName), index)
+}
+
+// redisSentinelFolder returns the config folder of a sentinel, which sentinel rewrites as it learns about failovers
+func redisSentinelFolder(s Datastore, serviceName string, index int) string {
+	return filepath.Join(Folders(s, serviceName).Config, fmt.Sprintf("sentinel-%d", index))
+}
+
+// redisSentinelPassword returns the password clients authenticate to the sentinels of a service with, which is
+// empty for sentinels configured before they required one
+func redisSentinelPassword(s Datastore, serviceName string) string {
+	return common.PropertyGet(s.Properties().CommandPrefix, serviceName, "sentinel-password")
+}
+
+// MonitorContainerNames returns the names of the sentinel containers of a service
+func (s *RedisService) MonitorContainerNames(serviceName string) []string {
+	names := []string{}
+	for index := 1; index <= HAMonitorCount; index++ {
+		names = append(names, redisSentinelContainerName(s, serviceName, index))
+	}
+	return names
+}
+
+// ConfigureHA shares the password of a service with its replica services and sentinels
+//
+// Every member gets the password as masterauth, as any of them may be demoted to a replica by a failover,
+// and announces its hostname so sentinels hand out addresses that survive container restarts
+func (s *RedisService) ConfigureHA(ctx context.Context, serviceName string) error {
+	password := common.ReadFirstLine(Files(s, serviceName).Password)
+	for _, member := range HAServices(s, serviceName) {
+		directives := map[string]string{
+			"masterauth":          password,
+			"replica-announce-ip": DNSHostname(s, member),
+		}
+		// the password of the service itself is managed by RotatePassword, which may keep a previous one valid
+		if member != serviceName {
+			directives["requirepass"] = password
+		}
+
+		err := s.withRunningClient(ctx, member, func(client *RedisClient) error {
+			for _, name := range []string{"requirepass", "masterauth", "replica-announce-ip"} {
+				value, ok := directives[name]
+				if !ok {
+					continue
+				}
+				if _, err := client.Do("CONFIG", "SET", name, value); err != nil {
+					return fmt.Errorf("failed to set %s: %w", name, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to configure member %s: %w", member, err)
+		}
+
+		if err := setRedisConfigDirectives(s, member, directives); err != nil {
+			return err
+		}
+
+		if member == serviceName {
+			continue
+		}
+		err = common.WriteStringToFile(common.WriteStringToFileInput{
+			Content:   password,
+			Filename:  Files(s, member).Password,
+			GroupName: SystemGroup(),
+			Mode:      0640,
+			Username:  SystemUser(),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to write password of member %s: %w", member, err)
+		}
+	}
+
+	// members using an acl file only accept the password listed for its default user
+	if err := syncRedisDefaultACLUser(s, serviceName, password); err != nil {
+		return err
+	}
+
+	// sentinels join the networks of linked apps, so they require a password of their own
+	prefix := s.Properties().CommandPrefix
+	sentinelPassword := redisSentinelPassword(s, serviceName)
+	if sentinelPassword == "" {
+		var err error
+		sentinelPassword, err = GenerateRandomHexString(64)
+		if err != nil {
+			return fmt.Errorf("unable to generate random hex string: %w", err)
+		}
+		if err := common.PropertyWrite(prefix, serviceName, "sentinel-password", sentinelPassword); err != nil {
+			return fmt.Errorf("failed to write sentinel-password property: %w", err)
+		}
+	}
+
+	for index := 1; index <= HAMonitorCount; index++ {
+		passwordChanged, err := writeRedisSentinelConfig(s, serviceName, index, password, sentinelPassword)
+		if err != nil {
+			return err
+		}
+
+		containerName := redisSentinelContainerName(s, serviceName, index)
+		if !common.ContainerIsRunning(containerName) {
+			continue
+		}
+
+		// sentinel only reads requirepass on startup, and reads the auth-pass along with it
+		if passwordChanged {
+			_, err := CallExecCommandWithContext(ctx, common.ExecCommandInput{
+				Command: common.DockerBin(),
+				Args:    []string{"container", "restart", containerName},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to restart sentinel %s: %w", containerName, err)
+			}
+			continue
+		}
+
+		client, err := s.sentinelClient(ctx, serviceName, containerName)
+		if err != nil {
+			return err
+		}
+		_, err = client.Do("SENTINEL", "SET", serviceName, "auth-pass", password)
+		client.Close() //nolint:errcheck
+		if err != nil {
+			return fmt.Errorf("failed to update password of sentinel %s: %w", containerName, err)
+		}
+	}
+
+	return nil
+}
+
+// writeRedisSentinelConfig writes the sentinel.conf of a sentinel, only updating the passwords of an existing one
+// so the state sentinel rewrote into it is kept, and returns whether the sentinel password of an existing one changed
+func writeRedisSentinelConfig(s Datastore, serviceName string, index int, password string, sentinelPassword string) (bool, error) {
+	configFile := filepath.Join(redisSentinelFolder(s, serviceName, index), "sentinel.conf")
+	authPass := fmt.Sprintf("sentinel auth-pass %s %s", serviceName, password)
+	requirePass := "requirepass " + sentinelPassword
+
+	lines := []string{
+		fmt.Sprintf("port %d", redisSentinelPort),
+		"dir /tmp",
+		requirePass,
+		"sentinel resolve-hostnames yes",
+		"sentinel announce-hostnames yes",
+		"sentinel announce-ip " + redisSentinelHostname(s, serviceName, index),
+		fmt.Sprintf("sentinel monitor %s %s %d %d", serviceName, DNSHostname(s, RecordedHAPrimary(s, serviceName)), s.Properties().Ports[0], HAMonitorCount/2+1),
+		authPass,
+		fmt.Sprintf("sentinel down-after-milliseconds %s 5000", serviceName),
+		fmt.Sprintf("sentinel failover-timeout %s 60000", serviceName),
+		fmt.Sprintf("sentinel parallel-syncs %s 1", serviceName),
+	}
+	passwordChanged := false
+	if common.FileExists(configFile) {
+		existing, err := common.FileToSlice(configFile)
+		if err != nil {
+			return false, fmt.Errorf("unable to read %s: %w", configFile, err)
+		}
+		lines = make([]string, 0, len(existing)+1)
+		passwordChanged = true
+		for _, line := range existing {
+			if strings.HasPrefix(line, fmt.Sprintf("sentinel auth-pass %s ", serviceName)) {
+				line = authPass
+			}
+			// sentinel also authenticates to the other sentinels with its requirepass
+			if strings.HasPrefix(line, "requirepass ") {
+				passwordChanged = line != requirePass
+				line = requirePass
+			}
+			lines = append(lines, line)
+		}
+		if !slices.Contains(lines, requirePass) {
+			lines = append(lines, requirePass)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(configFile), 0755); err != nil {
+		return false, fmt.Errorf("unable to create %s: %w", filepath.Dir(configFile), err)
+	}
+	err := common.WriteSliceToFile(common.WriteSliceToFileInput{
+		Filename:  configFile,
+		GroupName: SystemGroup(),
+		Lines:     lines,
+		Mode:      0640,
+		Username:  SystemUser(),
+	})
+	if err != nil {
+		return false, fmt.Errorf("unable to write to %s: %w", configFile, err)
+	}
+	return passwordChanged, nil
+}
+
+// StartMonitors runs the sentinel containers of a service on its dedicated network, starting existing ones
+//
+// Sentinels also join the networks of the service, so linked apps can reach them
+func (s *RedisService) StartMonitors(ctx context.Context, serviceName string) error {
+	taggedImage, err := ImageForService(ImageForServiceInput{
+		Datastore:   s,
+		ServiceName: serviceName,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get image for service: %w", err)
+	}
+
+	networks := []string{}
+	for _, property := range []string{PostCreateNetwork(s, serviceName), PostStartNetwork(s, serviceName)} {
+		if property != "" {
+			networks = append(networks, strings.Split(property, ",")...)
+		}
+	}
+
+	for index := 1; index <= HAMonitorCount; index++ {
+		containerName := redisSentinelContainerName(s, serviceName, index)
+		if common.ContainerIsRunning(containerName) {
+			continue
+		}
+
+		if ContainerExists(ctx, containerName) {
+			_, err := CallExecCommandWithContext(ctx, common.ExecCommandInput{
+				Command: common.DockerBin(),
+				Args:    []string{"container", "start", containerName},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to start container %s: %w", containerName, err)
+			}
+			continue
+		}
+
+		hostname := redisSentinelHostname(s, serviceName, index)
+		hostFolder := filepath.Join(Folders(s, serviceName).HostConfig, fmt.Sprintf("sentinel-%d", index))
+		_, err := CallExecCommandWithContext(ctx, common.ExecCommandInput{
+			Command: common.DockerBin(),
+			Args: []string{
+				"container", "run", "-d",
+				"--name=" + containerName,
+				"--hostname=" + hostname,
+				"--restart=always",
+				"--label=dokku=sentinel",
+				"--label=dokku.sentinel=" + s.Properties().CommandPrefix,
+				"--network=" + HANetwork(s, serviceName),
+				"--network-alias=" + hostname,
+				"--volume=" + hostFolder + ":" + redisConfigMount,
+				taggedImage,
+				"redis-sentinel", redisConfigMount + "/sentinel.conf",
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to run container %s: %w", containerName, err)
+		}
+
+		err = AttachNetworksToContainer(ctx, AttachNetworksToContainerInput{
+			ContainerID:  containerName,
+			Networks:     networks,
+			NetworkAlias: hostname,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sentinelClient returns a client for a running sentinel container of a service
+func (s *RedisService) sentinelClient(ctx context.Context, serviceName string, containerName string) (*RedisClient, error) {
+	containerIP := ContainerIP(ctx, ContainerIPInput{ContainerID: containerName})
+	if containerIP == "" {
+		return nil, fmt.Errorf("unable to determine ip address for sentinel %s", containerName)
+	}
+
+	return NewRedisClient(ctx, NewRedisClientInput{
+		Address:  net.JoinHostPort(containerIP, strconv.Itoa(redisSentinelPort)),
+		Password: redisSentinelPassword(s, serviceName),
+	})
+}
+
+// CurrentPrimary asks the first reachable sentinel which member of a service is the primary
+func (s *RedisService) CurrentPrimary(ctx context.Context, serviceName string) (string, error) {
+	var lastErr error
+	for _, containerName := range s.MonitorContainerNames(serviceName) {
+		if !common.ContainerIsRunning(containerName) {
+			continue
+		}
+
+		client, err := s.sentinelClient(ctx, serviceName, containerName)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		reply, err := client.Do("SENTINEL", "GET-MASTER-ADDR-BY-NAME", serviceName)
+		client.Close() //nolint:errcheck
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		address, ok := reply.([]interface{})
+		if !ok || len(address) == 0 {
+			lastErr = fmt.Errorf("sentinel %s does not monitor service %s", containerName, serviceName)
+			continue
+		}
+		host, _ := address[0].(string)
+		return s.haMemberForHost(ctx, serviceName, host)
+	}
+
+	if lastErr != nil {
+		return "", lastErr
+	}
+	return "", fmt.Errorf("no sentinel of service %s is running", serviceName)
+}
+
+// haMemberForHost maps an address handed out by sentinel back to a member of a service
+func (s *RedisService) haMemberForHost(ctx context.Context, serviceName string, host string) (string, error) {
+	network := HANetwork(s, serviceName)
+	for _, member := range HAServices(s, serviceName) {
+		if host == DNSHostname(s, member) {
+			return member, nil
+		}
+
+		// sentinels that learned about a member before it announced its hostname still know it by ip
+		memberIP, _ := common.DockerInspect(ContainerName(s, member), fmt.Sprintf("{{ with index .NetworkSettings.Networks %q }}{{ .IPAddress }}{{ end }}", network))
+		if memberIP != "" && host == memberIP {
+			return member, nil
+		}
+	}
+	return "", fmt.Errorf("primary address %s of service %s does not belong to any member", host, serviceName)
+}
+
+// WatchFailovers subscribes to the +switch-master events of the sentinels of a service
+//
+// When the sentinel being watched goes away the next one is used, so the watch survives the loss of a sentinel
+func (s *RedisService) WatchFailovers(ctx context.Context, serviceName string, fn func(primary string) error) error {
+	for {
+		for _, containerName := range s.MonitorContainerNames(serviceName) {
+			if !common.ContainerIsRunning(containerName) {
+				continue
+			}
+
+			err := s.watchSentinel(ctx, serviceName, containerName, fn)
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// watchSentinel passes failovers seen by a single sentinel to fn, returning nil when the connection is lost
+func (s *RedisService) watchSentinel(ctx context.Context, serviceName string, containerName string, fn func(primary string) error) error {
+	client, err := s.sentinelClient(ctx, serviceName, containerName)
+	if err != nil {
+		return nil
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			client.Close() //nolint:errcheck
+		case <-done:
+			client.Close() //nolint:errcheck
+		}
+	}()
+
+	if _, err := client.Do("SUBSCRIBE", "+switch-master"); err != nil {
+		return nil
+	}
+
+	for {
+		reply, err := client.Receive()
+		if err != nil {
+			return nil
+		}
+
+		// messages look like "message", "+switch-master", "<master> <old host> <old port> <new host> <new port>"
+		message, ok := reply.([]interface{})
+		if !ok || len(message) != 3 || message[0] != "message" {
+			continue
+		}
+		payload, _ := message[2].(string)
+		fields := strings.Fields(payload)
+		if len(fields) != 5 || fields[0] != serviceName {
+			continue
+		}
+
+		primary, err := s.haMemberForHost(ctx, serviceName, fields[3])
+		if err != nil {
+			return err
+		}
+		if err := fn(primary); err != nil {
+			return err
+		}
+	}
+}
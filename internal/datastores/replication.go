@@ -0,0 +1,170 @@
+package datastores
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// Replicator is implemented by datastores that can run a service as a read-only replica of another
+type Replicator interface {
+	// ConfigureReplica rewrites the service configuration to replicate from a primary, applying it live when running
+	ConfigureReplica(ctx context.Context, serviceName string, primaryName string) error
+
+	// PromoteReplica stops replicating and makes the service accept writes, applying it live when running
+	PromoteReplica(ctx context.Context, serviceName string) error
+
+	// ReplicationStatus returns the replication state of a running service
+	ReplicationStatus(ctx context.Context, serviceName string) (ReplicationStatus, error)
+}
+
+// ReplicationStatus is the replication state of a service
+type ReplicationStatus struct {
+	// Type is the datastore type of the service
+	Type string `json:"type"`
+
+	// Service is the name of the service
+	Service string `json:"service"`
+
+	// Role is the role reported by the datastore, one of primary or replica
+	Role string `json:"role"`
+
+	// Primary is the service replicated from, for replicas
+	Primary string `json:"primary,omitempty"`
+
+	// LinkStatus is the state of the connection to the primary, for replicas
+	LinkStatus string `json:"link-status,omitempty"`
+
+	// LastIOSeconds is the seconds since the last interaction with the primary, for replicas
+	LastIOSeconds int `json:"last-io-seconds,omitempty"`
+
+	// Replicas is the replica services of the service
+	Replicas []string `json:"replicas"`
+
+	// ConnectedReplicas is the number of replicas currently connected, for primaries
+	ConnectedReplicas int `json:"connected-replicas"`
+
+	// Offset is the replication offset of the service
+	Offset int64 `json:"offset"`
+}
+
+// ReplicaOf returns the primary a service replicates from, or empty when it is not a replica
+func ReplicaOf(s Datastore, serviceName string) string {
+	return common.PropertyGet(s.Properties().CommandPrefix, serviceName, "replica-of")
+}
+
+// Replicas returns the services replicating from a primary
+func Replicas(s Datastore, primaryName string) []string {
+	replicas := []string{}
+	entries, err := os.ReadDir(filepath.Join(PluginDataRoot, s.Properties().CommandPrefix))
+	if err != nil {
+		return replicas
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() && ReplicaOf(s, entry.Name()) == primaryName {
+			replicas = append(replicas, entry.Name())
+		}
+	}
+	sort.Strings(replicas)
+	return replicas
+}
+
+// ConfigureReplicaInput is the input for the ConfigureReplica function
+type ConfigureReplicaInput struct {
+	// Datastore is the service to configure as a replica
+	Datastore Datastore
+
+	// PrimaryName is the name of the service to replicate from
+	PrimaryName string
+
+	// ServiceName is the name of the service to configure as a replica
+	ServiceName string
+}
+
+// ConfigureReplica makes a service replicate from a primary of the same datastore
+func ConfigureReplica(ctx context.Context, input ConfigureReplicaInput) error {
+	replicator, ok := input.Datastore.(Replicator)
+	if !ok {
+		return fmt.Errorf("%s services do not support replication", input.Datastore.ServiceType())
+	}
+	if input.PrimaryName == input.ServiceName {
+		return fmt.Errorf("service %s cannot replicate from itself", input.ServiceName)
+	}
+	if !Exists(ctx, input.Datastore, input.PrimaryName) {
+		return fmt.Errorf("primary service %s does not exist", input.PrimaryName)
+	}
+	if primary := ReplicaOf(input.Datastore, input.PrimaryName); primary != "" {
+		return fmt.Errorf("service %s is itself a replica of %s", input.PrimaryName, primary)
+	}
+
+	if err := replicator.ConfigureReplica(ctx, input.ServiceName, input.PrimaryName); err != nil {
+		return fmt.Errorf("failed to configure replica: %w", err)
+	}
+
+	if err := common.PropertyWrite(input.Datastore.Properties().CommandPrefix, input.ServiceName, "replica-of", input.PrimaryName); err != nil {
+		return fmt.Errorf("failed to write replica-of property: %w", err)
+	}
+	return nil
+}
+
+// PromoteReplicaInput is the input for the PromoteReplica function
+type PromoteReplicaInput struct {
+	// Datastore is the replica to promote
+	Datastore Datastore
+
+	// ServiceName is the name of the replica to promote
+	ServiceName string
+}
+
+// PromoteReplica makes a replica a standalone primary
+func PromoteReplica(ctx context.Context, input PromoteReplicaInput) error {
+	replicator, ok := input.Datastore.(Replicator)
+	if !ok {
+		return fmt.Errorf("%s services do not support replication", input.Datastore.ServiceType())
+	}
+	if ReplicaOf(input.Datastore, input.ServiceName) == "" {
+		return fmt.Errorf("service %s is not a replica", input.ServiceName)
+	}
+
+	if err := replicator.PromoteReplica(ctx, input.ServiceName); err != nil {
+		return fmt.Errorf("failed to promote replica: %w", err)
+	}
+
+	if err := common.PropertyDelete(input.Datastore.Properties().CommandPrefix, input.ServiceName, "replica-of"); err != nil {
+		return fmt.Errorf("failed to remove replica-of property: %w", err)
+	}
+	return nil
+}
+
+// ServiceReplicationStatusInput is the input for the ServiceReplicationStatus function
+type ServiceReplicationStatusInput struct {
+	// Datastore is the service to get the replication status of
+	Datastore Datastore
+
+	// ServiceName is the name of the service to get the replication status of
+	ServiceName string
+}
+
+// ServiceReplicationStatus returns the replication state of a service along with its replica services
+func ServiceReplicationStatus(ctx context.Context, input ServiceReplicationStatusInput) (ReplicationStatus, error) {
+	replicator, ok := input.Datastore.(Replicator)
+	if !ok {
+		return ReplicationStatus{}, fmt.Errorf("%s services do not support replication", input.Datastore.ServiceType())
+	}
+
+	status, err := replicator.ReplicationStatus(ctx, input.ServiceName)
+	if err != nil {
+		return ReplicationStatus{}, err
+	}
+
+	status.Type = input.Datastore.ServiceType()
+	status.Service = input.ServiceName
+	status.Primary = ReplicaOf(input.Datastore, input.ServiceName)
+	status.Replicas = Replicas(input.Datastore, input.ServiceName)
+	return status, nil
+}
@@ -0,0 +1,293 @@
+package datastores
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// ResourceLimits is the container resource controls of a service beyond MEMORY and SHM_SIZE
+type ResourceLimits struct {
+	// BlkioWeight is the relative block io weight, between 10 and 1000
+	BlkioWeight int `json:"blkio-weight,omitempty" yaml:"blkio-weight" toml:"blkio-weight"`
+
+	// CPUs is the number of cpus the container may use, such as 1.5
+	CPUs string `json:"cpus,omitempty" yaml:"cpus" toml:"cpus"`
+
+	// CPUShares is the relative cpu weight
+	CPUShares int `json:"cpu-shares,omitempty" yaml:"cpu-shares" toml:"cpu-shares"`
+
+	// CPUSetCPUs is the cpus the container may run on, such as 0-3 or 0,1
+	CPUSetCPUs string `json:"cpuset-cpus,omitempty" yaml:"cpuset-cpus" toml:"cpuset-cpus"`
+
+	// MemoryReservation is the soft memory limit, such as 256m
+	MemoryReservation string `json:"memory-reservation,omitempty" yaml:"memory-reservation" toml:"memory-reservation"`
+
+	// MemorySwap is the memory plus swap limit, such as 1g or -1 for unlimited swap
+	MemorySwap string `json:"memory-swap,omitempty" yaml:"memory-swap" toml:"memory-swap"`
+
+	// PidsLimit is the maximum number of processes
+	PidsLimit int `json:"pids-limit,omitempty" yaml:"pids-limit" toml:"pids-limit"`
+
+	// Ulimits is the ulimits in docker format, such as nofile=10032:10032
+	Ulimits []string `json:"ulimits,omitempty" yaml:"ulimits" toml:"ulimits"`
+}
+
+// ReadResourceLimits reads the resource limits of a service from its service files
+func ReadResourceLimits(s Datastore, serviceName string) ResourceLimits {
+	serviceFiles := Files(s, serviceName)
+	limits := ResourceLimits{
+		CPUs:              common.ReadFirstLine(serviceFiles.CPUs),
+		CPUSetCPUs:        common.ReadFirstLine(serviceFiles.CPUSetCPUs),
+		MemoryReservation: common.ReadFirstLine(serviceFiles.MemoryReservation),
+		MemorySwap:        common.ReadFirstLine(serviceFiles.MemorySwap),
+	}
+	limits.BlkioWeight, _ = strconv.Atoi(common.ReadFirstLine(serviceFiles.BlkioWeight))
+	limits.CPUShares, _ = strconv.Atoi(common.ReadFirstLine(serviceFiles.CPUShares))
+	limits.PidsLimit, _ = strconv.Atoi(common.ReadFirstLine(serviceFiles.PidsLimit))
+	if ulimits, err := common.FileToSlice(serviceFiles.Ulimits); err == nil {
+		for _, ulimit := range ulimits {
+			if ulimit = strings.TrimSpace(ulimit); ulimit != "" {
+				limits.Ulimits = append(limits.Ulimits, ulimit)
+			}
+		}
+	}
+	return limits
+}
+
+// Validate checks the resource limits are well formed
+func (r ResourceLimits) Validate() error {
+	if r.BlkioWeight != 0 && (r.BlkioWeight < 10 || r.BlkioWeight > 1000) {
+		return fmt.Errorf("invalid blkio weight %d, must be between 10 and 1000", r.BlkioWeight)
+	}
+	if r.CPUs != "" {
+		if cpus, err := strconv.ParseFloat(r.CPUs, 64); err != nil || cpus <= 0 {
+			return fmt.Errorf("invalid cpus %s, must be a positive number", r.CPUs)
+		}
+	}
+	if r.CPUShares < 0 {
+		return fmt.Errorf("invalid cpu shares %d, must be positive", r.CPUShares)
+	}
+	if r.PidsLimit < 0 {
+		return fmt.Errorf("invalid pids limit %d, must be positive", r.PidsLimit)
+	}
+	for _, ulimit := range r.Ulimits {
+		name, value, ok := strings.Cut(ulimit, "=")
+		if !ok || name == "" || value == "" {
+			return fmt.Errorf("invalid ulimit %s, expected <name>=<soft>[:<hard>]", ulimit)
+		}
+	}
+	return nil
+}
+
+// String returns the resource limits as space separated docker flag values, for display
+func (r ResourceLimits) String() string {
+	values := []string{}
+	for _, arg := range r.UpdateArgs() {
+		values = append(values, strings.TrimPrefix(arg, "--"))
+	}
+	for _, ulimit := range r.Ulimits {
+		values = append(values, "ulimit="+ulimit)
+	}
+	return strings.Join(values, " ")
+}
+
+// CreateArgs returns the docker container create flags for the resource limits
+func (r ResourceLimits) CreateArgs() []string {
+	args := r.UpdateArgs()
+	for _, ulimit := range r.Ulimits {
+		args = append(args, "--ulimit="+ulimit)
+	}
+	return args
+}
+
+// UpdateArgs returns the flags for the resource limits that docker container update can change in place
+func (r ResourceLimits) UpdateArgs() []string {
+	args := []string{}
+	if r.BlkioWeight != 0 {
+		args = append(args, fmt.Sprintf("--blkio-weight=%d", r.BlkioWeight))
+	}
+	if r.CPUs != "" {
+		args = append(args, "--cpus="+r.CPUs)
+	}
+	if r.CPUShares != 0 {
+		args = append(args, fmt.Sprintf("--cpu-shares=%d", r.CPUShares))
+	}
+	if r.CPUSetCPUs != "" {
+		args = append(args, "--cpuset-cpus="+r.CPUSetCPUs)
+	}
+	if r.MemoryReservation != "" {
+		args = append(args, "--memory-reservation="+r.MemoryReservation)
+	}
+	if r.MemorySwap != "" {
+		args = append(args, "--memory-swap="+r.MemorySwap)
+	}
+	if r.PidsLimit != 0 {
+		args = append(args, fmt.Sprintf("--pids-limit=%d", r.PidsLimit))
+	}
+	return args
+}
+
+// WriteResourceLimitsInput is the input for the WriteResourceLimits function
+type WriteResourceLimitsInput struct {
+	// Datastore is the service to write the resource limits for
+	Datastore Datastore
+
+	// Limits is the resource limits to write
+	Limits ResourceLimits
+
+	// ServiceName is the name of the service to write the resource limits for
+	ServiceName string
+}
+
+// WriteResourceLimits persists resource limits as service files, removing the files of unset limits
+func WriteResourceLimits(input WriteResourceLimitsInput) error {
+	if err := input.Limits.Validate(); err != nil {
+		return err
+	}
+
+	serviceFiles := Files(input.Datastore, input.ServiceName)
+	files := map[string]string{
+		serviceFiles.CPUs:              input.Limits.CPUs,
+		serviceFiles.CPUSetCPUs:        input.Limits.CPUSetCPUs,
+		serviceFiles.MemoryReservation: input.Limits.MemoryReservation,
+		serviceFiles.MemorySwap:        input.Limits.MemorySwap,
+		serviceFiles.Ulimits:           strings.Join(input.Limits.Ulimits, "\n"),
+	}
+	if input.Limits.BlkioWeight != 0 {
+		files[serviceFiles.BlkioWeight] = strconv.Itoa(input.Limits.BlkioWeight)
+	} else {
+		files[serviceFiles.BlkioWeight] = ""
+	}
+	if input.Limits.CPUShares != 0 {
+		files[serviceFiles.CPUShares] = strconv.Itoa(input.Limits.CPUShares)
+	} else {
+		files[serviceFiles.CPUShares] = ""
+	}
+	if input.Limits.PidsLimit != 0 {
+		files[serviceFiles.PidsLimit] = strconv.Itoa(input.Limits.PidsLimit)
+	} else {
+		files[serviceFiles.PidsLimit] = ""
+	}
+
+	for filename, content := range files {
+		if content == "" {
+			if err := os.RemoveAll(filename); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", filename, err)
+			}
+			continue
+		}
+
+		err := common.WriteStringToFile(common.WriteStringToFileInput{
+			Content:   content,
+			Filename:  filename,
+			GroupName: SystemGroup(),
+			Mode:      0644,
+			Username:  SystemUser(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", filename, err)
+		}
+	}
+
+	return nil
+}
+
+// UpdateResourceLimitsInput is the input for the UpdateResourceLimits function
+type UpdateResourceLimitsInput struct {
+	// Datastore is the service to update the resource limits for
+	Datastore Datastore
+
+	// Limits is the new resource limits
+	Limits ResourceLimits
+
+	// Memory is the new memory limit in megabytes, where 0 is unlimited
+	Memory int
+
+	// ServiceName is the name of the service to update the resource limits for
+	ServiceName string
+}
+
+// UpdateResourceLimits persists new resource limits and applies them to the service container
+//
+// Limits are changed in place with docker container update, and the container is only recreated
+// when a limit that cannot be updated, such as a ulimit, or a removed limit changes. Datastores that
+// cap their own memory use are reconfigured for the new MEMORY limit first.
+func UpdateResourceLimits(ctx context.Context, input UpdateResourceLimitsInput) error {
+	current := ReadResourceLimits(input.Datastore, input.ServiceName)
+	currentMemory, _ := strconv.Atoi(common.ReadFirstLine(Files(input.Datastore, input.ServiceName).Memory))
+
+	err := WriteResourceLimits(WriteResourceLimitsInput{
+		Datastore:   input.Datastore,
+		Limits:      input.Limits,
+		ServiceName: input.ServiceName,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = common.WriteStringToFile(common.WriteStringToFileInput{
+		Content:   strconv.Itoa(input.Memory),
+		Filename:  Files(input.Datastore, input.ServiceName).Memory,
+		GroupName: SystemGroup(),
+		Mode:      0644,
+		Username:  SystemUser(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write memory: %w", err)
+	}
+
+	// the datastore is capped below a lowered limit before the container is, so it evicts rather than being killed
+	if err := ConfigureMaxmemory(ctx, input.Datastore, input.ServiceName); err != nil {
+		return err
+	}
+
+	containerIDs := sortedContainerIDs(LiveContainerIDs(ctx, LiveContainerIDsInput{
+		Datastore:   input.Datastore,
+		ServiceName: input.ServiceName,
+	}))
+	if len(containerIDs) == 0 {
+		return nil
+	}
+
+	// docker container update cannot change ulimits, nor reset a limit to unlimited
+	recreate := !slices.Equal(current.Ulimits, input.Limits.Ulimits) || (currentMemory != 0 && input.Memory == 0)
+	for _, arg := range current.UpdateArgs() {
+		name, _, _ := strings.Cut(arg, "=")
+		if !slices.ContainsFunc(input.Limits.UpdateArgs(), func(updated string) bool {
+			return strings.HasPrefix(updated, name+"=")
+		}) {
+			recreate = true
+		}
+	}
+	if recreate {
+		return RecreateServiceContainer(ctx, RecreateServiceContainerInput{
+			Datastore:   input.Datastore,
+			ServiceName: input.ServiceName,
+		})
+	}
+
+	args := []string{"container", "update"}
+	if input.Memory != 0 {
+		args = append(args, fmt.Sprintf("--memory=%dm", input.Memory))
+	}
+	args = append(args, input.Limits.UpdateArgs()...)
+	if len(args) == 2 {
+		return nil
+	}
+
+	_, err = CallExecCommandWithContext(ctx, common.ExecCommandInput{
+		Command: common.DockerBin(),
+		Args:    append(args, containerIDs...),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update container resource limits: %w", err)
+	}
+
+	return nil
+}
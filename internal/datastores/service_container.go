@@ -0,0 +1,288 @@
+package datastores
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dokku/dokku/plugins/common"
+	"mvdan.cc/sh/v3/shell"
+)
+
+// ServiceContainerSpec describes the datastore specific parts of a service container
+type ServiceContainerSpec struct {
+	// Args is the command and arguments to run in the container, before any config options
+	Args []string
+
+	// Env is the environment variables to set on the container in addition to the service env file
+	Env map[string]string
+
+	// Hardening is what the datastore needs to run in a hardened container
+	Hardening ServiceHardeningSpec
+
+	// Healthcheck is the docker healthcheck reporting when the datastore is ready to serve requests
+	Healthcheck ServiceHealthcheckSpec
+
+	// Volumes is the volume mounts for the container in host:container form
+	Volumes []string
+}
+
+// ServiceHardeningSpec is what a datastore needs to run in a hardened container
+type ServiceHardeningSpec struct {
+	// CapAdd is the capabilities to add back after dropping all of them
+	CapAdd []string `yaml:"cap-add"`
+
+	// Tmpfs is the paths that must stay writable on a read-only root filesystem, defaulting to /tmp
+	Tmpfs []string `yaml:"tmpfs"`
+
+	// User is the non-root user to run as, preferably as a numeric uid:gid
+	User string `yaml:"user"`
+}
+
+// ServiceHealthcheckSpec is a docker healthcheck run inside a service container
+type ServiceHealthcheckSpec struct {
+	// Command is the shell command to run, where a zero exit code means the datastore is healthy
+	Command string `yaml:"command"`
+
+	// Interval is the time between checks, defaulting to 10s
+	Interval string `yaml:"interval"`
+
+	// Timeout is the time a single check may take, defaulting to 5s
+	Timeout string `yaml:"timeout"`
+
+	// StartPeriod is the time failing checks are ignored for after the container starts
+	StartPeriod string `yaml:"start-period"`
+
+	// Retries is the number of consecutive failures before the container is unhealthy, defaulting to 3
+	Retries int `yaml:"retries"`
+}
+
+// CreateArgs returns the docker container create flags for the healthcheck
+func (h ServiceHealthcheckSpec) CreateArgs() []string {
+	if h.Command == "" {
+		return []string{}
+	}
+
+	interval := h.Interval
+	if interval == "" {
+		interval = "10s"
+	}
+	timeout := h.Timeout
+	if timeout == "" {
+		timeout = "5s"
+	}
+	retries := h.Retries
+	if retries == 0 {
+		retries = 3
+	}
+
+	args := []string{
+		"--health-cmd=" + h.Command,
+		"--health-interval=" + interval,
+		"--health-timeout=" + timeout,
+		fmt.Sprintf("--health-retries=%d", retries),
+	}
+	if h.StartPeriod != "" {
+		args = append(args, "--health-start-period="+h.StartPeriod)
+	}
+	return args
+}
+
+// RunServiceContainerInput is the input for the RunServiceContainer function
+type RunServiceContainerInput struct {
+	// Datastore is the service to create the container for
+	Datastore Datastore
+
+	// Member is the cluster member to create the container for, where empty is the service container
+	Member string
+
+	// ServiceName is the name of the service to create the container for
+	ServiceName string
+
+	// Spec is the datastore specific container configuration
+	Spec ServiceContainerSpec
+
+	// TaggedImage is the tagged image to use for the container
+	TaggedImage string
+}
+
+// RunServiceContainer creates and starts a service container, attaching networks and reconciling exposed ports
+//
+// Cluster members share the networks of the service container, but only the service container publishes ports
+func RunServiceContainer(ctx context.Context, input RunServiceContainerInput) error {
+	serviceProperties := input.Datastore.Properties()
+	serviceFiles := Files(input.Datastore, input.ServiceName)
+	containerName := MemberContainerName(input.Datastore, input.ServiceName, input.Member)
+	cidFilename := MemberIDFile(input.Datastore, input.ServiceName, input.Member)
+
+	lines, err := common.FileToSlice(serviceFiles.ConfigOptions)
+	if err != nil {
+		return fmt.Errorf("unable to read config options from %s: %w", serviceFiles.ConfigOptions, err)
+	}
+	startArgsToAppend, err := shell.Fields(strings.Join(lines, "\n"), func(name string) string {
+		return ""
+	})
+	if err != nil {
+		return fmt.Errorf("unable to parse config options: %w", err)
+	}
+
+	// remove the ID file if it exists
+	if err := os.RemoveAll(cidFilename); err != nil {
+		return fmt.Errorf("unable to remove ID file from %s: %w", cidFilename, err)
+	}
+
+	dockerCreateArgs := []string{
+		"container",
+		"create",
+		"--cidfile=" + cidFilename,
+		"--env-file=" + serviceFiles.Env,
+		"--hostname=" + containerName,
+		"--label=dokku.service=" + serviceProperties.CommandPrefix,
+		"--label=dokku=service",
+		"--name=" + containerName,
+		"--restart=always",
+	}
+
+	if input.Member == "" {
+		dockerCreateArgs = append(dockerCreateArgs, PublishArgs(input.Datastore, input.ServiceName)...)
+	} else {
+		dockerCreateArgs = append(dockerCreateArgs, "--label=dokku.member="+input.Member)
+	}
+
+	for _, volume := range input.Spec.Volumes {
+		dockerCreateArgs = append(dockerCreateArgs, "--volume="+volume)
+	}
+
+	envKeys := make([]string, 0, len(input.Spec.Env))
+	for key := range input.Spec.Env {
+		envKeys = append(envKeys, key)
+	}
+	sort.Strings(envKeys)
+	for _, key := range envKeys {
+		dockerCreateArgs = append(dockerCreateArgs, fmt.Sprintf("--env=%s=%s", key, input.Spec.Env[key]))
+	}
+
+	memory := common.ReadFirstLine(serviceFiles.Memory)
+	if memory != "" && memory != "0" {
+		dockerCreateArgs = append(dockerCreateArgs, "--memory="+memory+"m")
+	}
+
+	dockerCreateArgs = append(dockerCreateArgs, ReadResourceLimits(input.Datastore, input.ServiceName).CreateArgs()...)
+	hardening := Hardening(input.Datastore, input.ServiceName)
+	if err := hardening.ValidateUser(input.Spec.Hardening); err != nil {
+		return err
+	}
+	dockerCreateArgs = append(dockerCreateArgs, hardening.CreateArgs(input.Spec.Hardening)...)
+	dockerCreateArgs = append(dockerCreateArgs, input.Spec.Healthcheck.CreateArgs()...)
+
+	shmSize := common.ReadFirstLine(serviceFiles.ShmSize)
+	if shmSize != "" {
+		dockerCreateArgs = append(dockerCreateArgs, "--shm-size="+shmSize)
+	}
+
+	networkAlias := MemberDNSHostname(input.Datastore, input.ServiceName, input.Member)
+	initialNetwork := InitialNetwork(input.Datastore, input.ServiceName)
+	if initialNetwork != "" {
+		dockerCreateArgs = append(dockerCreateArgs, "--network="+initialNetwork)
+		dockerCreateArgs = append(dockerCreateArgs, "--network-alias="+networkAlias)
+	}
+
+	taggedImage := input.TaggedImage
+	if taggedImage == "" {
+		image := common.ReadFirstLine(serviceFiles.Image)
+		if image == "" {
+			image = serviceProperties.DefaultImage
+		}
+
+		imageVersion := common.ReadFirstLine(serviceFiles.ImageVersion)
+		if imageVersion == "" {
+			imageVersion = serviceProperties.DefaultImageVersion
+		}
+		taggedImage = fmt.Sprintf("%s:%s", image, imageVersion)
+	}
+
+	dockerCreateArgs = append(dockerCreateArgs, taggedImage)
+	dockerCreateArgs = append(dockerCreateArgs, input.Spec.Args...)
+	for _, arg := range startArgsToAppend {
+		if arg == "" {
+			continue
+		}
+
+		dockerCreateArgs = append(dockerCreateArgs, arg)
+	}
+
+	// the data folders of cluster members are inside the data folder of the service container
+	if input.Member == "" {
+		if err := prepareHardenedDataFolder(ctx, input.Datastore, input.ServiceName, input.Spec.Hardening); err != nil {
+			return err
+		}
+
+		// the key follows the container user, which changes with the hardening settings
+		if TLSEnabled(input.Datastore, input.ServiceName) {
+			if err := prepareServiceTLSKey(ctx, input.Datastore, input.ServiceName); err != nil {
+				return err
+			}
+		}
+	}
+
+	// create the container
+	_, err = CallExecCommandWithContext(ctx, common.ExecCommandInput{
+		Command: common.DockerBin(),
+		Args:    dockerCreateArgs,
+	})
+	if err != nil {
+		return err
+	}
+
+	postCreateNetworks := common.PropertyGet(serviceProperties.CommandPrefix, input.ServiceName, "post-create-network")
+	if postCreateNetworks != "" {
+		err := AttachNetworksToContainer(ctx, AttachNetworksToContainerInput{
+			ContainerID:  common.ReadFirstLine(cidFilename),
+			Networks:     strings.Split(postCreateNetworks, ","),
+			NetworkAlias: networkAlias,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	containerID := common.ReadFirstLine(cidFilename)
+	if containerID == "" {
+		return fmt.Errorf("failed to read container ID from %s", cidFilename)
+	}
+
+	// start the container
+	_, err = CallExecCommandWithContext(ctx, common.ExecCommandInput{
+		Command: common.DockerBin(),
+		Args:    []string{"container", "start", containerID},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start container: %w", err)
+	}
+
+	if input.Member == "" {
+		err = ServicePortReconcileStatus(ctx, ServicePortReconcileStatusInput{
+			Datastore:   input.Datastore,
+			ServiceName: input.ServiceName,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to reconcile port status: %w", err)
+		}
+	}
+
+	postStartNetworks := common.PropertyGet(serviceProperties.CommandPrefix, input.ServiceName, "post-start-network")
+	if postStartNetworks != "" {
+		err := AttachNetworksToContainer(ctx, AttachNetworksToContainerInput{
+			ContainerID:  common.ReadFirstLine(cidFilename),
+			Networks:     strings.Split(postStartNetworks, ","),
+			NetworkAlias: networkAlias,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
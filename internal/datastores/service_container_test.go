@@ -0,0 +1,46 @@
+package datastores
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestServiceHealthcheckCreateArgs(t *testing.T) {
+	tests := []struct {
+		name        string
+		healthcheck ServiceHealthcheckSpec
+		want        []string
+	}{
+		{name: "no command", healthcheck: ServiceHealthcheckSpec{Interval: "30s", Retries: 5}, want: []string{}},
+		{
+			name:        "defaults",
+			healthcheck: ServiceHealthcheckSpec{Command: "redis-cli ping"},
+			want:        []string{"--health-cmd=redis-cli ping", "--health-interval=10s", "--health-timeout=5s", "--health-retries=3"},
+		},
+		{
+			name: "every setting",
+			healthcheck: ServiceHealthcheckSpec{
+				Command:     "redis-cli ping | grep PONG",
+				Interval:    "30s",
+				Timeout:     "2s",
+				StartPeriod: "1m",
+				Retries:     5,
+			},
+			want: []string{
+				"--health-cmd=redis-cli ping | grep PONG",
+				"--health-interval=30s",
+				"--health-timeout=2s",
+				"--health-retries=5",
+				"--health-start-period=1m",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.healthcheck.CreateArgs(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CreateArgs(%v) = %v, want %v", tt.healthcheck, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,427 @@
+package datastores
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// TLSCAValidity is how long the host certificate authority is valid for
+var TLSCAValidity = 10 * 365 * 24 * time.Hour
+
+// TLSCertificateValidity is how long a service certificate is valid for before it must be rotated
+var TLSCertificateValidity = 365 * 24 * time.Hour
+
+// TLSConfigurer is implemented by datastores that can serve encrypted connections
+type TLSConfigurer interface {
+	// ConfigureTLS rewrites the service configuration to require or stop using tls
+	ConfigureTLS(ctx context.Context, serviceName string, enabled bool) error
+
+	// ReloadTLS makes a running service pick up a rotated certificate without restarting
+	ReloadTLS(ctx context.Context, serviceName string) error
+
+	// TLSKeyUser returns the numeric uid:gid the datastore reads its private key as when not hardened
+	TLSKeyUser() string
+}
+
+// TLSFiles is the tls files of a service
+type TLSFiles struct {
+	// Folder is the folder holding the tls files, inside the service config folder
+	Folder string
+
+	// CACert is the certificate of the host certificate authority
+	CACert string
+
+	// Cert is the server certificate of the service
+	Cert string
+
+	// Key is the private key of the server certificate
+	Key string
+}
+
+// ServiceTLSFiles returns the tls files of a service
+func ServiceTLSFiles(s Datastore, serviceName string) TLSFiles {
+	folder := filepath.Join(Folders(s, serviceName).Config, "tls")
+	return TLSFiles{
+		Folder: folder,
+		CACert: filepath.Join(folder, "ca.crt"),
+		Cert:   filepath.Join(folder, "server.crt"),
+		Key:    filepath.Join(folder, "server.key"),
+	}
+}
+
+// TLSCAFolder returns the folder holding the host certificate authority that signs service certificates
+func TLSCAFolder() string {
+	return filepath.Join(PluginDataRoot, "TLS_CA")
+}
+
+// TLSEnabled returns whether a service requires tls connections
+func TLSEnabled(s Datastore, serviceName string) bool {
+	return common.PropertyGet(s.Properties().CommandPrefix, serviceName, "tls") == "true"
+}
+
+// TLSStatus returns the tls state of a service for display, including when its certificate expires
+func TLSStatus(s Datastore, serviceName string) string {
+	if !TLSEnabled(s, serviceName) {
+		return "disabled"
+	}
+
+	certificate, err := readCertificate(ServiceTLSFiles(s, serviceName).Cert)
+	if err != nil {
+		return "enabled"
+	}
+	return fmt.Sprintf("enabled (expires %s)", certificate.NotAfter.UTC().Format(time.RFC3339))
+}
+
+// ConfigureServiceTLSInput is the input for the ConfigureServiceTLS function
+type ConfigureServiceTLSInput struct {
+	// Datastore is the service to configure tls for
+	Datastore Datastore
+
+	// Enabled is whether the service should require tls
+	Enabled bool
+
+	// ServiceName is the name of the service to configure tls for
+	ServiceName string
+}
+
+// ConfigureServiceTLS issues or removes the certificate of a service and rewrites its configuration
+//
+// The service container must be recreated for the change to take effect
+func ConfigureServiceTLS(ctx context.Context, input ConfigureServiceTLSInput) error {
+	configurer, ok := input.Datastore.(TLSConfigurer)
+	if !ok {
+		return fmt.Errorf("%s services do not support tls", input.Datastore.ServiceType())
+	}
+
+	if input.Enabled && (HAEnabled(input.Datastore, input.ServiceName) || HAMemberOf(input.Datastore, input.ServiceName) != "") {
+		return fmt.Errorf("high availability services do not support tls yet")
+	}
+
+	if input.Enabled && ClusterEnabled(input.Datastore, input.ServiceName) {
+		return fmt.Errorf("cluster services do not support tls yet")
+	}
+
+	prefix := input.Datastore.Properties().CommandPrefix
+	if input.Enabled {
+		if err := IssueServiceCertificate(ctx, input.Datastore, input.ServiceName); err != nil {
+			return err
+		}
+		if err := configurer.ConfigureTLS(ctx, input.ServiceName, true); err != nil {
+			return fmt.Errorf("failed to configure tls: %w", err)
+		}
+		if err := common.PropertyWrite(prefix, input.ServiceName, "tls", "true"); err != nil {
+			return fmt.Errorf("failed to write tls property: %w", err)
+		}
+		return nil
+	}
+
+	if err := configurer.ConfigureTLS(ctx, input.ServiceName, false); err != nil {
+		return fmt.Errorf("failed to configure tls: %w", err)
+	}
+	if common.PropertyExists(prefix, input.ServiceName, "tls") {
+		if err := common.PropertyDelete(prefix, input.ServiceName, "tls"); err != nil {
+			return fmt.Errorf("failed to remove tls property: %w", err)
+		}
+	}
+	if err := os.RemoveAll(ServiceTLSFiles(input.Datastore, input.ServiceName).Folder); err != nil {
+		return fmt.Errorf("failed to remove tls files: %w", err)
+	}
+	return nil
+}
+
+// RotateServiceCertificateInput is the input for the RotateServiceCertificate function
+type RotateServiceCertificateInput struct {
+	// Datastore is the service to rotate the certificate of
+	Datastore Datastore
+
+	// ServiceName is the name of the service to rotate the certificate of
+	ServiceName string
+}
+
+// RotateServiceCertificate issues a new certificate for a service, reloading it in place when the service is running
+func RotateServiceCertificate(ctx context.Context, input RotateServiceCertificateInput) error {
+	configurer, ok := input.Datastore.(TLSConfigurer)
+	if !ok {
+		return fmt.Errorf("%s services do not support tls", input.Datastore.ServiceType())
+	}
+	if !TLSEnabled(input.Datastore, input.ServiceName) {
+		return fmt.Errorf("tls is not enabled for service %s", input.ServiceName)
+	}
+
+	if err := IssueServiceCertificate(ctx, input.Datastore, input.ServiceName); err != nil {
+		return err
+	}
+
+	runningContainerID := LiveContainerID(ctx, LiveContainerIDInput{
+		Datastore:   input.Datastore,
+		ServiceName: input.ServiceName,
+		Filter:      "status=running",
+	})
+	if runningContainerID == "" {
+		return nil
+	}
+
+	if err := configurer.ReloadTLS(ctx, input.ServiceName); err != nil {
+		return fmt.Errorf("failed to reload tls certificate: %w", err)
+	}
+	return nil
+}
+
+// IssueServiceCertificate signs a new server certificate for a service, writing it along with the
+// certificate authority into the service config folder
+func IssueServiceCertificate(ctx context.Context, s Datastore, serviceName string) error {
+	if _, err := tlsKeyUser(s, serviceName); err != nil {
+		return err
+	}
+
+	caCertificate, caKey, caPEM, err := loadOrCreateTLSCA()
+	if err != nil {
+		return err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate service key: %w", err)
+	}
+
+	serial, err := randomSerialNumber()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: DNSHostname(s, serviceName)},
+		DNSNames:     []string{DNSHostname(s, serviceName), ContainerName(s, serviceName), "localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    now.Add(-5 * time.Minute),
+		NotAfter:     now.Add(TLSCertificateValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCertificate, &key.PublicKey, caKey)
+	if err != nil {
+		return fmt.Errorf("failed to sign service certificate: %w", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return fmt.Errorf("failed to encode service key: %w", err)
+	}
+
+	tlsFiles := ServiceTLSFiles(s, serviceName)
+	if err := os.MkdirAll(tlsFiles.Folder, 0755); err != nil {
+		return fmt.Errorf("failed to create tls folder: %w", err)
+	}
+
+	// the certificates are public, while the key is only readable by the datastore user inside the container
+	files := []struct {
+		filename string
+		content  string
+		mode     os.FileMode
+	}{
+		{tlsFiles.CACert, caPEM, 0644},
+		{tlsFiles.Key, string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})), 0600},
+		{tlsFiles.Cert, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), 0644},
+	}
+	for _, file := range files {
+		// a previous key belongs to the container user, so it is replaced rather than rewritten
+		if err := os.Remove(file.filename); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", file.filename, err)
+		}
+
+		err := common.WriteStringToFile(common.WriteStringToFileInput{
+			Content:   file.content,
+			Filename:  file.filename,
+			GroupName: SystemGroup(),
+			Mode:      file.mode,
+			Username:  SystemUser(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.filename, err)
+		}
+	}
+
+	return prepareServiceTLSKey(ctx, s, serviceName)
+}
+
+// prepareServiceTLSKey hands the private key of a service to the user its container reads it as
+func prepareServiceTLSKey(ctx context.Context, s Datastore, serviceName string) error {
+	user, err := tlsKeyUser(s, serviceName)
+	if err != nil {
+		return err
+	}
+
+	uid, gid, ok := strings.Cut(user, ":")
+	if !ok {
+		gid = uid
+	}
+	_, err = CallExecCommandWithContext(ctx, common.ExecCommandInput{
+		Command: common.DockerBin(),
+		Args:    []string{"container", "run", "--rm", "-v", Folders(s, serviceName).HostConfig + ":/config", PluginBusyboxImage, "chown", uid + ":" + gid, "/config/tls/" + filepath.Base(ServiceTLSFiles(s, serviceName).Key)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to change the owner of the tls key: %w", err)
+	}
+	return nil
+}
+
+// tlsKeyUser returns the numeric user the container of a service reads its private key as
+//
+// Only numeric users can be applied from outside the image, so a named hardening user cannot be given the key
+func tlsKeyUser(s Datastore, serviceName string) (string, error) {
+	configurer, ok := s.(TLSConfigurer)
+	if !ok {
+		return "", fmt.Errorf("%s services do not support tls", s.ServiceType())
+	}
+
+	user := configurer.TLSKeyUser()
+	if hardening := Hardening(s, serviceName); hardening.Enabled {
+		user = hardening.containerUser(ServiceHardeningSpec{User: user})
+	}
+
+	if !hardeningNumericUser.MatchString(user) {
+		return "", fmt.Errorf("tls requires a numeric hardening user to own the private key, got %s", user)
+	}
+	return user, nil
+}
+
+// loadOrCreateTLSCA returns the host certificate authority, creating it on first use
+func loadOrCreateTLSCA() (*x509.Certificate, *ecdsa.PrivateKey, string, error) {
+	certFile := filepath.Join(TLSCAFolder(), "ca.crt")
+	keyFile := filepath.Join(TLSCAFolder(), "ca.key")
+
+	if common.FileExists(certFile) && common.FileExists(keyFile) {
+		certificate, err := readCertificate(certFile)
+		if err != nil {
+			return nil, nil, "", err
+		}
+
+		content, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, nil, "", fmt.Errorf("failed to read tls certificate authority key: %w", err)
+		}
+		block, _ := pem.Decode(content)
+		if block == nil {
+			return nil, nil, "", fmt.Errorf("invalid tls certificate authority key %s", keyFile)
+		}
+		key, err := x509.ParseECPrivateKey(block.Bytes)
+		if err != nil {
+			return nil, nil, "", fmt.Errorf("failed to parse tls certificate authority key: %w", err)
+		}
+
+		certPEM, err := os.ReadFile(certFile)
+		if err != nil {
+			return nil, nil, "", fmt.Errorf("failed to read tls certificate authority: %w", err)
+		}
+		return certificate, key, string(certPEM), nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to generate tls certificate authority key: %w", err)
+	}
+
+	serial, err := randomSerialNumber()
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	hostname, _ := os.Hostname()
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: fmt.Sprintf("dokku datastore ca %s", hostname)},
+		NotBefore:             now.Add(-5 * time.Minute),
+		NotAfter:              now.Add(TLSCAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to create tls certificate authority: %w", err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to parse tls certificate authority: %w", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to encode tls certificate authority key: %w", err)
+	}
+
+	if err := os.MkdirAll(TLSCAFolder(), 0700); err != nil {
+		return nil, nil, "", fmt.Errorf("failed to create tls certificate authority folder: %w", err)
+	}
+
+	err = common.WriteStringToFile(common.WriteStringToFileInput{
+		Content:   string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+		Filename:  keyFile,
+		GroupName: SystemGroup(),
+		Mode:      0600,
+		Username:  SystemUser(),
+	})
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to write tls certificate authority key: %w", err)
+	}
+
+	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	err = common.WriteStringToFile(common.WriteStringToFileInput{
+		Content:   certPEM,
+		Filename:  certFile,
+		GroupName: SystemGroup(),
+		Mode:      0644,
+		Username:  SystemUser(),
+	})
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to write tls certificate authority: %w", err)
+	}
+
+	return certificate, key, certPEM, nil
+}
+
+// readCertificate parses the first pem encoded certificate in a file
+func readCertificate(filename string) (*x509.Certificate, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate %s: %w", filename, err)
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("invalid certificate %s", filename)
+	}
+
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate %s: %w", filename, err)
+	}
+	return certificate, nil
+}
+
+// randomSerialNumber returns a random certificate serial number
+func randomSerialNumber() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate certificate serial number: %w", err)
+	}
+	return serial, nil
+}
@@ -2,8 +2,11 @@ package internal
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 
 	"github.com/dokku/dokku-datastore/internal/datastores"
 	"github.com/dokku/dokku/plugins/common"
@@ -18,8 +21,53 @@ type DestroyServiceInput struct {
 	ServiceName string
 }
 
+// CheckDestroyService returns why a service cannot be destroyed, or nil when nothing stands in the way
+//
+// High availability services are checked along with their members, whose replication of the service does not count
+func CheckDestroyService(ctx context.Context, input DestroyServiceInput) error {
+	if owner := datastores.HAMemberOf(input.Datastore, input.ServiceName); owner != "" {
+		return fmt.Errorf("service %s is a member of high availability service %s, destroy that instead", input.ServiceName, owner)
+	}
+
+	services := []string{input.ServiceName}
+	if datastores.HAEnabled(input.Datastore, input.ServiceName) {
+		services = datastores.HAServices(input.Datastore, input.ServiceName)
+	}
+
+	replicas := []string{}
+	for _, serviceName := range services {
+		for _, replica := range datastores.Replicas(input.Datastore, serviceName) {
+			if !slices.Contains(services, replica) {
+				replicas = append(replicas, replica)
+			}
+		}
+	}
+	if len(replicas) > 0 {
+		return fmt.Errorf("cannot destroy service %s, it has replicas: %s", input.ServiceName, strings.Join(replicas, ", "))
+	}
+
+	for _, serviceName := range services {
+		linkedApps := datastores.LinkedApps(ctx, datastores.LinkedAppsInput{
+			Datastore:   input.Datastore,
+			ServiceName: serviceName,
+		})
+		if len(linkedApps) > 0 {
+			return errors.New("cannot delete linked service")
+		}
+	}
+	return nil
+}
+
 // DestroyService destroys a service
 func DestroyService(ctx context.Context, input DestroyServiceInput) error {
+	// every check runs before high availability members are torn down, so a refusal leaves the service whole
+	if err := CheckDestroyService(ctx, input); err != nil {
+		return err
+	}
+
+	highAvailability := datastores.HAEnabled(input.Datastore, input.ServiceName)
+	clustered := datastores.ClusterEnabled(input.Datastore, input.ServiceName)
+
 	_, err := datastores.CallPlugnTriggerWithContext(ctx, common.PlugnTriggerInput{
 		Trigger:      "service-action",
 		Args:         []string{"pre-delete", input.Datastore.ServiceType(), input.ServiceName},
@@ -30,6 +78,12 @@ func DestroyService(ctx context.Context, input DestroyServiceInput) error {
 		return fmt.Errorf("failed to call service-action pre-delete trigger: %w", err)
 	}
 
+	if highAvailability {
+		if err := destroyHAMembers(ctx, input); err != nil {
+			return err
+		}
+	}
+
 	err = datastores.RemoveBackupSchedule(ctx, datastores.RemoveBackupScheduleInput{
 		Datastore:   input.Datastore,
 		ServiceName: input.ServiceName,
@@ -46,6 +100,24 @@ func DestroyService(ctx context.Context, input DestroyServiceInput) error {
 		return fmt.Errorf("failed to remove container: %w", err)
 	}
 
+	if len(datastores.ExposeAllowList(input.Datastore, input.ServiceName)) > 0 {
+		err = datastores.RemoveExposeFirewall(ctx, datastores.ExposeFirewallInput{
+			Datastore:   input.Datastore,
+			ServiceName: input.ServiceName,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to remove firewall rules: %w", err)
+		}
+	}
+
+	err = datastores.ReleasePorts(datastores.ReleasePortsInput{
+		Datastore:   input.Datastore,
+		ServiceName: input.ServiceName,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release ports: %w", err)
+	}
+
 	serviceFolders := datastores.Folders(input.Datastore, input.ServiceName)
 	_, err = datastores.CallExecCommandWithContext(ctx, common.ExecCommandInput{
 		Command: common.DockerBin(),
@@ -64,6 +136,18 @@ func DestroyService(ctx context.Context, input DestroyServiceInput) error {
 		return fmt.Errorf("failed to destroy properties: %w", err)
 	}
 
+	if highAvailability {
+		if err := datastores.RemoveHANetwork(ctx, input.Datastore, input.ServiceName); err != nil {
+			return err
+		}
+	}
+
+	if clustered {
+		if err := datastores.RemoveClusterNetwork(ctx, input.Datastore, input.ServiceName); err != nil {
+			return err
+		}
+	}
+
 	_, err = datastores.CallPlugnTriggerWithContext(ctx, common.PlugnTriggerInput{
 		Trigger:      "service-action",
 		Args:         []string{"post-delete", input.Datastore.ServiceType(), input.ServiceName},
@@ -0,0 +1,233 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dokku/dokku-datastore/internal/datastores"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// ServiceEventActions is the list of docker container actions reported as service events
+var ServiceEventActions = []string{"die", "oom", "restart", "health_status"}
+
+// ServiceEventRoles is the list of container roles, from the dokku label, whose events are reported
+var ServiceEventRoles = []string{"service", "ambassador", "proxy", "sentinel"}
+
+// ServiceEvent is a lifecycle event of a container belonging to a service
+type ServiceEvent struct {
+	// Time is when the event happened
+	Time time.Time `json:"time"`
+
+	// Type is the datastore type of the service
+	Type string `json:"type"`
+
+	// Service is the name of the service
+	Service string `json:"service"`
+
+	// Role is the role of the container, one of service, ambassador, proxy or sentinel
+	Role string `json:"role"`
+
+	// Container is the name of the container
+	Container string `json:"container"`
+
+	// Member is the cluster member the container belongs to, and empty for the service container
+	Member string `json:"member,omitempty"`
+
+	// Action is the docker event action, one of die, oom, restart or health_status
+	Action string `json:"action"`
+
+	// Health is the health status reported by a health_status event
+	Health string `json:"health,omitempty"`
+
+	// ExitCode is the exit code reported by a die event
+	ExitCode *int `json:"exit-code,omitempty"`
+}
+
+// StreamEventsInput is the input for the StreamEvents function
+type StreamEventsInput struct {
+	// Datastore is the datastore to stream events for, where nil streams every datastore
+	Datastore datastores.Datastore
+
+	// Handler is called with every event in order
+	Handler func(ServiceEvent) error
+
+	// ServiceName is the name of the service to stream events for, where empty streams every service
+	ServiceName string
+
+	// Since is the docker timestamp or duration to replay events from
+	Since string
+
+	// Trace is whether to enable trace output
+	Trace bool
+}
+
+// dockerEvent is a line of docker events json output
+type dockerEvent struct {
+	// Action is the event action
+	Action string `json:"Action"`
+
+	// Actor is the container the event is about
+	Actor struct {
+		// Attributes is the container name and labels
+		Attributes map[string]string `json:"Attributes"`
+	} `json:"Actor"`
+
+	// TimeNano is when the event happened in nanoseconds
+	TimeNano int64 `json:"timeNano"`
+}
+
+// StreamEvents streams lifecycle events of service, ambassador, proxy and sentinel containers until the context is cancelled
+func StreamEvents(ctx context.Context, input StreamEventsInput) error {
+	// docker requires every label filter to match, so containers are filtered on the key and their role checked when read
+	args := []string{"events", "--format", "{{ json . }}", "--filter", "type=container", "--filter", "label=dokku"}
+	for _, action := range ServiceEventActions {
+		args = append(args, "--filter", "event="+action)
+	}
+	if input.Since != "" {
+		args = append(args, "--since", input.Since)
+	}
+
+	// a failing handler stops the docker events process through the context
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	reader, writer := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		err := readEvents(ctx, reader, input)
+		if err != nil {
+			cancel()
+		}
+		done <- err
+	}()
+
+	_, err := datastores.CallExecCommandWithContext(ctx, common.ExecCommandInput{
+		Command:      common.DockerBin(),
+		Args:         args,
+		StdoutWriter: writer,
+	})
+	writer.Close()
+	readErr := <-done
+
+	if ctx.Err() != nil {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return readErr
+		}
+		return ctx.Err()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stream docker events: %w", err)
+	}
+
+	return readErr
+}
+
+// readEvents parses docker events, maps them to services and passes the allowed ones to the handler
+func readEvents(ctx context.Context, reader io.Reader, input StreamEventsInput) error {
+	// the user-auth-service trigger is only consulted once per service
+	allowed := map[string]bool{}
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		entry := dockerEvent{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			continue
+		}
+
+		datastore, event, ok := serviceEventFromDocker(entry)
+		if !ok {
+			continue
+		}
+		if input.Datastore != nil && datastore.ServiceType() != input.Datastore.ServiceType() {
+			continue
+		}
+		if input.ServiceName != "" && event.Service != input.ServiceName {
+			continue
+		}
+
+		key := event.Type + "/" + event.Service
+		if _, ok := allowed[key]; !ok && input.ServiceName == "" {
+			services, err := datastores.FilterServices(ctx, datastores.FilterServicesInput{
+				Datastore: datastore,
+				Services:  []string{event.Service},
+				Trace:     input.Trace,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to filter services: %w", err)
+			}
+			allowed[key] = slices.Contains(services, event.Service)
+		}
+		if input.ServiceName == "" && !allowed[key] {
+			continue
+		}
+
+		if err := input.Handler(event); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// serviceEventFromDocker maps a docker event back to the service owning the container
+func serviceEventFromDocker(entry dockerEvent) (datastores.Datastore, ServiceEvent, bool) {
+	attributes := entry.Actor.Attributes
+	role := attributes["dokku"]
+	if !slices.Contains(ServiceEventRoles, role) {
+		return nil, ServiceEvent{}, false
+	}
+	datastore := datastoreForCommandPrefix(attributes["dokku."+role])
+	if datastore == nil {
+		return nil, ServiceEvent{}, false
+	}
+
+	containerName := attributes["name"]
+	serviceName := strings.TrimPrefix(containerName, datastores.ContainerName(datastore, ""))
+	// other roles are named after the service, followed by their role and, for sentinels, their index
+	if index := strings.LastIndex(serviceName, "."+role); role != "service" && index != -1 {
+		serviceName = serviceName[:index]
+	}
+	member := attributes["dokku.member"]
+	if member != "" {
+		serviceName = strings.TrimSuffix(serviceName, "."+member)
+	}
+	if serviceName == containerName || serviceName == "" {
+		return nil, ServiceEvent{}, false
+	}
+
+	event := ServiceEvent{
+		Time:      time.Unix(0, entry.TimeNano).UTC(),
+		Type:      datastore.ServiceType(),
+		Service:   serviceName,
+		Role:      role,
+		Container: containerName,
+		Member:    member,
+		Action:    entry.Action,
+	}
+
+	// health events carry the new status in the action, as in "health_status: unhealthy"
+	if action, health, ok := strings.Cut(entry.Action, ":"); ok {
+		event.Action = strings.TrimSpace(action)
+		event.Health = strings.TrimSpace(health)
+	}
+
+	if exitCode, err := strconv.Atoi(attributes["exitCode"]); err == nil && event.Action == "die" {
+		event.ExitCode = &exitCode
+	}
+
+	return datastore, event, true
+}
@@ -3,6 +3,10 @@ package internal
 import (
 	"context"
 	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/dokku/dokku-datastore/internal/datastores"
@@ -18,6 +22,12 @@ func IsExposed(s datastores.Datastore, serviceName string) bool {
 
 // ExposeServiceInput is the input for the ExposeService function
 type ExposeServiceInput struct {
+	// Allow is the networks allowed to connect to the exposed ports, where empty allows all
+	Allow []string
+
+	// Bind is the host address to bind the exposed ports to, where empty binds all interfaces
+	Bind string
+
 	// Datastore is the service to expose
 	Datastore datastores.Datastore
 
@@ -30,10 +40,20 @@ type ExposeServiceInput struct {
 
 // ExposeService exposes a service
 func ExposeService(ctx context.Context, input ExposeServiceInput) error {
+	// expose containers forward to the service container alone, which is only one member of a cluster
+	// and stops being the primary of a high availability service after a failover
+	if datastores.ClusterEnabled(input.Datastore, input.ServiceName) {
+		return fmt.Errorf("cluster services cannot be exposed")
+	}
+	if datastores.HAEnabled(input.Datastore, input.ServiceName) || datastores.HAMemberOf(input.Datastore, input.ServiceName) != "" {
+		return fmt.Errorf("high availability services cannot be exposed")
+	}
+
 	serviceFiles := datastores.Files(input.Datastore, input.ServiceName)
 	portFile := serviceFiles.Port
 
-	if len(input.Ports) == 0 {
+	userSupplied := len(input.Ports) > 0
+	if !userSupplied {
 		ports, err := datastores.GenerateRandomPorts(len(input.Datastore.Properties().Ports))
 		if err != nil {
 			return fmt.Errorf("failed to generate random ports: %w", err)
@@ -52,7 +72,48 @@ func ExposeService(ctx context.Context, input ExposeServiceInput) error {
 		return fmt.Errorf("%d ports to be exposed need to be provided in the following order: %s", len(input.Ports), strings.Join(ports, ","))
 	}
 
-	err := common.WriteStringToFile(common.WriteStringToFileInput{
+	if input.Bind != "" && net.ParseIP(input.Bind) == nil {
+		return fmt.Errorf("invalid bind address %s", input.Bind)
+	}
+
+	allowList := []string{}
+	for _, network := range input.Allow {
+		network, err := datastores.NormalizeAllowedNetwork(network)
+		if err != nil {
+			return err
+		}
+		allowList = append(allowList, network)
+	}
+
+	// the allowlist is enforced with iptables only, which never sees ipv6 connections
+	if ip := net.ParseIP(input.Bind); len(allowList) > 0 && ip != nil && ip.To4() == nil {
+		return fmt.Errorf("allowed networks only apply to ipv4 traffic, so the bind address must be an ipv4 address")
+	}
+
+	ports := []int{}
+	for _, value := range input.Ports {
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid port %s", value)
+		}
+		ports = append(ports, port)
+	}
+
+	err := datastores.ReservePorts(datastores.ReservePortsInput{
+		CheckListening: userSupplied,
+		Datastore:      input.Datastore,
+		Ports:          ports,
+		ServiceName:    input.ServiceName,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reserve ports: %w", err)
+	}
+
+	if err := writeExposeSettings(input.Datastore, input.ServiceName, input.Bind, allowList); err != nil {
+		return err
+	}
+
+	err = common.WriteStringToFile(common.WriteStringToFileInput{
 		Content:   strings.Join(input.Ports, " "),
 		Filename:  portFile,
 		GroupName: datastores.SystemGroup(),
@@ -63,6 +124,17 @@ func ExposeService(ctx context.Context, input ExposeServiceInput) error {
 		return fmt.Errorf("failed to write ports to %s: %w", portFile, err)
 	}
 
+	if datastores.ExposeMode(input.Datastore, input.ServiceName) == datastores.ExposeModePublish {
+		// published ports are part of the service container, so it is recreated to apply them
+		err := datastores.RemoveServiceContainer(ctx, datastores.RemoveServiceContainerInput{
+			Datastore:   input.Datastore,
+			ServiceName: input.ServiceName,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to remove service container: %w", err)
+		}
+	}
+
 	err = datastores.Start(ctx, datastores.StartInput{
 		Datastore:   input.Datastore,
 		ServiceName: input.ServiceName,
@@ -82,22 +154,143 @@ func ExposeService(ctx context.Context, input ExposeServiceInput) error {
 	return nil
 }
 
-// RemoveAmbassadorContainer removes the ambassador container for a service
-func RemoveAmbassadorContainer(ctx context.Context, s datastores.Datastore, serviceName string) error {
-	ambassadorName := datastores.AmbassadorContainerName(s, serviceName)
-	_, err := common.CallExecCommandWithContext(ctx, common.ExecCommandInput{
-		Command: common.DockerBin(),
-		Args:    []string{"container", "stop", ambassadorName},
+// writeExposeSettings persists the bind address and allowlist next to the port file
+func writeExposeSettings(s datastores.Datastore, serviceName string, bind string, allowList []string) error {
+	serviceFiles := datastores.Files(s, serviceName)
+	if bind == "" {
+		if err := os.RemoveAll(serviceFiles.ExposeBind); err != nil {
+			return fmt.Errorf("failed to remove bind file: %w", err)
+		}
+	} else {
+		err := common.WriteStringToFile(common.WriteStringToFileInput{
+			Content:   bind,
+			Filename:  serviceFiles.ExposeBind,
+			GroupName: datastores.SystemGroup(),
+			Mode:      0644,
+			Username:  datastores.SystemUser(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to write bind address to %s: %w", serviceFiles.ExposeBind, err)
+		}
+	}
+
+	if len(allowList) == 0 {
+		if err := os.RemoveAll(serviceFiles.ExposeAllow); err != nil {
+			return fmt.Errorf("failed to remove allow file: %w", err)
+		}
+		return nil
+	}
+
+	err := common.WriteSliceToFile(common.WriteSliceToFileInput{
+		Filename:  serviceFiles.ExposeAllow,
+		GroupName: datastores.SystemGroup(),
+		Lines:     allowList,
+		Mode:      0644,
+		Username:  datastores.SystemUser(),
 	})
 	if err != nil {
-		return fmt.Errorf("failed to stop container %s: %w", ambassadorName, err)
+		return fmt.Errorf("failed to write allowed networks to %s: %w", serviceFiles.ExposeAllow, err)
+	}
+
+	return nil
+}
+
+// HasExposeContainer checks if any ambassador or proxy container exists for a service
+func HasExposeContainer(ctx context.Context, s datastores.Datastore, serviceName string) bool {
+	for _, containerName := range datastores.ExposeContainerNames(s, serviceName) {
+		if datastores.ContainerExists(ctx, containerName) {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveExposeContainers removes the ambassador and proxy containers for a service
+func RemoveExposeContainers(ctx context.Context, s datastores.Datastore, serviceName string) error {
+	for _, containerName := range datastores.ExposeContainerNames(s, serviceName) {
+		if !datastores.ContainerExists(ctx, containerName) {
+			continue
+		}
+
+		_, err := common.CallExecCommandWithContext(ctx, common.ExecCommandInput{
+			Command: common.DockerBin(),
+			Args:    []string{"container", "stop", containerName},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to stop container %s: %w", containerName, err)
+		}
+		_, err = common.CallExecCommandWithContext(ctx, common.ExecCommandInput{
+			Command: common.DockerBin(),
+			Args:    []string{"container", "rm", containerName},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to remove container %s: %w", containerName, err)
+		}
+	}
+
+	return nil
+}
+
+// SetExposeModeInput is the input for the SetExposeMode function
+type SetExposeModeInput struct {
+	// Datastore is the service to set the expose mode of
+	Datastore datastores.Datastore
+
+	// Mode is the expose mode to switch to
+	Mode string
+
+	// ServiceName is the name of the service to set the expose mode of
+	ServiceName string
+}
+
+// SetExposeMode switches the expose mode of a service, migrating any existing exposure to the new mode
+func SetExposeMode(ctx context.Context, input SetExposeModeInput) error {
+	if !slices.Contains(datastores.ExposeModes, input.Mode) {
+		return fmt.Errorf("invalid expose mode %s, must be one of: %s", input.Mode, strings.Join(datastores.ExposeModes, ", "))
+	}
+
+	previousMode := datastores.ExposeMode(input.Datastore, input.ServiceName)
+	err := common.PropertyWrite(input.Datastore.Properties().CommandPrefix, input.ServiceName, "expose-mode", input.Mode)
+	if err != nil {
+		return fmt.Errorf("failed to write expose mode: %w", err)
+	}
+
+	if previousMode == input.Mode || !IsExposed(input.Datastore, input.ServiceName) {
+		return nil
+	}
+
+	if err := RemoveExposeContainers(ctx, input.Datastore, input.ServiceName); err != nil {
+		return fmt.Errorf("failed to remove expose container: %w", err)
+	}
+
+	// published ports are part of the service container, so it is recreated when switching to or from publishing,
+	// and starting it again brings up the expose container for the new mode
+	if previousMode == datastores.ExposeModePublish || input.Mode == datastores.ExposeModePublish {
+		err := datastores.RecreateServiceContainer(ctx, datastores.RecreateServiceContainerInput{
+			Datastore:   input.Datastore,
+			ServiceName: input.ServiceName,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to recreate service container: %w", err)
+		}
+		return nil
 	}
-	_, err = common.CallExecCommandWithContext(ctx, common.ExecCommandInput{
-		Command: common.DockerBin(),
-		Args:    []string{"container", "rm", ambassadorName},
+
+	runningContainerID := datastores.LiveContainerID(ctx, datastores.LiveContainerIDInput{
+		Datastore:   input.Datastore,
+		ServiceName: input.ServiceName,
+		Filter:      "status=running",
+	})
+	if runningContainerID == "" {
+		return nil
+	}
+
+	err = datastores.ServicePortReconcileStatus(ctx, datastores.ServicePortReconcileStatusInput{
+		Datastore:   input.Datastore,
+		ServiceName: input.ServiceName,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to remove container %s: %w", ambassadorName, err)
+		return fmt.Errorf("failed to reconcile port status: %w", err)
 	}
 
 	return nil
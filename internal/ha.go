@@ -0,0 +1,218 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dokku/dokku-datastore/internal/datastores"
+)
+
+// DefaultHAReplicas is the number of replica services of a high availability service when none is given
+var DefaultHAReplicas = 2
+
+// prepareHAService validates a high availability service and moves it onto its dedicated network
+//
+// A requested initial network is attached after creation instead, along with the other networks
+func prepareHAService(ctx context.Context, input *CreateServiceInput) error {
+	if _, ok := input.Datastore.(datastores.HAManager); !ok {
+		return fmt.Errorf("%s services do not support high availability", input.Datastore.ServiceType())
+	}
+
+	if input.ReplicaOf != "" {
+		return fmt.Errorf("high availability services cannot replicate from another service")
+	}
+
+	if input.TLS {
+		return fmt.Errorf("high availability services do not support tls yet")
+	}
+
+	if input.HAReplicas == 0 {
+		input.HAReplicas = DefaultHAReplicas
+	}
+	if input.HAReplicas < 1 {
+		return fmt.Errorf("high availability services need at least one replica")
+	}
+
+	for index := 1; index <= input.HAReplicas; index++ {
+		member := datastores.HAMemberName(input.ServiceName, index)
+		if err := datastores.ValidateServiceName(member); err != nil {
+			return err
+		}
+		if datastores.Exists(ctx, input.Datastore, member) {
+			return fmt.Errorf("service %s already exists", member)
+		}
+	}
+
+	if input.InitialNetwork != "" {
+		input.PostCreateNetworks = append([]string{input.InitialNetwork}, input.PostCreateNetworks...)
+	}
+	input.InitialNetwork = datastores.HANetwork(input.Datastore, input.ServiceName)
+	return nil
+}
+
+// createHAMembers creates the replica services and monitors of a high availability service once it is running
+func createHAMembers(ctx context.Context, input CreateServiceInput) error {
+	manager := input.Datastore.(datastores.HAManager)
+	err := WaitForService(ctx, WaitForServiceInput{
+		Datastore:   input.Datastore,
+		ServiceName: input.ServiceName,
+	})
+	if err != nil {
+		return err
+	}
+
+	members := []string{}
+	for index := 1; index <= input.HAReplicas; index++ {
+		member := datastores.HAMemberName(input.ServiceName, index)
+		err := CreateService(ctx, CreateServiceInput{
+			ConfigOptions:    input.ConfigOptions,
+			CustomEnv:        input.CustomEnv,
+			Datastore:        input.Datastore,
+			Image:            input.Image,
+			ImageVersion:     input.ImageVersion,
+			MaxmemoryPercent: input.MaxmemoryPercent,
+			MaxmemoryPolicy:  input.MaxmemoryPolicy,
+			Memory:           input.Memory,
+			Persistence:      input.Persistence,
+			ReplicaOf:        input.ServiceName,
+			Resources:        input.Resources,
+			ServiceName:      member,
+			ShmSize:          input.ShmSize,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create member %s: %w", member, err)
+		}
+
+		err = WaitForService(ctx, WaitForServiceInput{
+			Datastore:   input.Datastore,
+			ServiceName: member,
+		})
+		if err != nil {
+			return err
+		}
+		members = append(members, member)
+	}
+
+	err = datastores.WriteHAMembers(datastores.WriteHAMembersInput{
+		Datastore:   input.Datastore,
+		Members:     members,
+		ServiceName: input.ServiceName,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := manager.ConfigureHA(ctx, input.ServiceName); err != nil {
+		return fmt.Errorf("failed to configure high availability: %w", err)
+	}
+
+	if err := manager.StartMonitors(ctx, input.ServiceName); err != nil {
+		return fmt.Errorf("failed to start monitors: %w", err)
+	}
+
+	return datastores.StartHAWatcher(ctx, input.Datastore, input.ServiceName)
+}
+
+// destroyHAMembers removes the monitors and replica services of a high availability service
+func destroyHAMembers(ctx context.Context, input DestroyServiceInput) error {
+	members := datastores.HAMembers(input.Datastore, input.ServiceName)
+	if err := datastores.StopHAWatcher(ctx, input.Datastore, input.ServiceName); err != nil {
+		return err
+	}
+
+	if err := datastores.RemoveHAMonitors(ctx, input.Datastore, input.ServiceName); err != nil {
+		return err
+	}
+
+	if err := datastores.ForgetHAMembers(input.Datastore, input.ServiceName); err != nil {
+		return err
+	}
+
+	for _, member := range members {
+		err := DestroyService(ctx, DestroyServiceInput{
+			Datastore:   input.Datastore,
+			ServiceName: member,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to destroy member %s: %w", member, err)
+		}
+	}
+	return nil
+}
+
+// FailoverEvent is a change of the primary of a high availability service
+type FailoverEvent struct {
+	// Time is when the failover was seen
+	Time time.Time `json:"time"`
+
+	// Type is the datastore type of the service
+	Type string `json:"type"`
+
+	// Service is the name of the high availability service
+	Service string `json:"service"`
+
+	// Primary is the member that became the primary
+	Primary string `json:"primary"`
+
+	// PreviousPrimary is the member that was the primary before
+	PreviousPrimary string `json:"previous-primary"`
+}
+
+// WatchHAServiceInput is the input for the WatchHAService function
+type WatchHAServiceInput struct {
+	// Datastore is the high availability service to watch
+	Datastore datastores.Datastore
+
+	// Handler is called with every failover in order
+	Handler func(FailoverEvent) error
+
+	// ServiceName is the name of the high availability service to watch
+	ServiceName string
+}
+
+// WatchHAService records failovers of a high availability service until the context is cancelled
+//
+// A failover that happened while nothing was watching is recorded first. Each failover calls the
+// service-action trigger, see datastores.ReconcileHAPrimary.
+func WatchHAService(ctx context.Context, input WatchHAServiceInput) error {
+	manager, ok := input.Datastore.(datastores.HAManager)
+	if !ok {
+		return fmt.Errorf("%s services do not support high availability", input.Datastore.ServiceType())
+	}
+
+	if !datastores.HAEnabled(input.Datastore, input.ServiceName) {
+		return fmt.Errorf("service %s is not a high availability service", input.ServiceName)
+	}
+
+	record := func(primary string) error {
+		previous, err := datastores.ReconcileHAPrimary(ctx, datastores.ReconcileHAPrimaryInput{
+			Datastore:   input.Datastore,
+			Primary:     primary,
+			ServiceName: input.ServiceName,
+		})
+		if err != nil {
+			return err
+		}
+		if primary == previous {
+			return nil
+		}
+
+		return input.Handler(FailoverEvent{
+			Time:            time.Now().UTC(),
+			Type:            input.Datastore.ServiceType(),
+			Service:         input.ServiceName,
+			Primary:         primary,
+			PreviousPrimary: previous,
+		})
+	}
+
+	// monitors that cannot be reached yet are retried by the watch itself
+	if primary, err := manager.CurrentPrimary(ctx, input.ServiceName); err == nil {
+		if err := record(primary); err != nil {
+			return err
+		}
+	}
+
+	return manager.WatchFailovers(ctx, input.ServiceName, record)
+}
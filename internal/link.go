@@ -0,0 +1,230 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/dokku/dokku-datastore/internal/datastores"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// LinkServiceInput is the input for the LinkService function
+type LinkServiceInput struct {
+	// AppName is the name of the app to link
+	AppName string
+
+	// Datastore is the service to link
+	Datastore datastores.Datastore
+
+	// NoRestart is whether to skip restarting the app
+	NoRestart bool
+
+	// ServiceName is the name of the service to link
+	ServiceName string
+}
+
+// LinkService links a service to an app, exposing the service dsn to the app
+func LinkService(ctx context.Context, input LinkServiceInput) error {
+	if err := common.VerifyAppName(input.AppName); err != nil {
+		return err
+	}
+
+	linkedApps := datastores.LinkedApps(ctx, datastores.LinkedAppsInput{
+		Datastore:   input.Datastore,
+		ServiceName: input.ServiceName,
+	})
+	if slices.Contains(linkedApps, input.AppName) {
+		return fmt.Errorf("service %s is already linked to app %s", input.ServiceName, input.AppName)
+	}
+
+	if err := writeLinkedApps(input.Datastore, input.ServiceName, append(linkedApps, input.AppName)); err != nil {
+		return err
+	}
+
+	if manager, ok := input.Datastore.(datastores.AppUserManager); ok {
+		if err := manager.SetAppUser(ctx, input.ServiceName, input.AppName, ""); err != nil {
+			return fmt.Errorf("failed to create user for app %s: %w", input.AppName, err)
+		}
+	}
+
+	return datastores.SetAppConfig(ctx, datastores.SetAppConfigInput{
+		AppName:   input.AppName,
+		NoRestart: input.NoRestart,
+		Values: map[string]string{
+			datastores.DSNEnvVariable(input.Datastore): datastores.AppURL(input.Datastore, input.ServiceName, input.AppName),
+		},
+	})
+}
+
+// UnlinkServiceInput is the input for the UnlinkService function
+type UnlinkServiceInput struct {
+	// AppName is the name of the app to unlink
+	AppName string
+
+	// Datastore is the service to unlink
+	Datastore datastores.Datastore
+
+	// NoRestart is whether to skip restarting the app
+	NoRestart bool
+
+	// ServiceName is the name of the service to unlink
+	ServiceName string
+}
+
+// UnlinkService unlinks a service from an app
+func UnlinkService(ctx context.Context, input UnlinkServiceInput) error {
+	linkedApps := datastores.LinkedApps(ctx, datastores.LinkedAppsInput{
+		Datastore:   input.Datastore,
+		ServiceName: input.ServiceName,
+	})
+	if !slices.Contains(linkedApps, input.AppName) {
+		return fmt.Errorf("service %s is not linked to app %s", input.ServiceName, input.AppName)
+	}
+
+	remainingApps := slices.DeleteFunc(linkedApps, func(appName string) bool {
+		return appName == input.AppName
+	})
+	if err := writeLinkedApps(input.Datastore, input.ServiceName, remainingApps); err != nil {
+		return err
+	}
+
+	if manager, ok := input.Datastore.(datastores.AppUserManager); ok {
+		if err := manager.RemoveAppUser(ctx, input.ServiceName, input.AppName); err != nil {
+			return fmt.Errorf("failed to remove user for app %s: %w", input.AppName, err)
+		}
+	}
+
+	return datastores.UnsetAppConfig(ctx, datastores.UnsetAppConfigInput{
+		AppName:   input.AppName,
+		Keys:      []string{datastores.DSNEnvVariable(input.Datastore)},
+		NoRestart: input.NoRestart,
+	})
+}
+
+// RefreshLinkedAppsInput is the input for the RefreshLinkedApps function
+type RefreshLinkedAppsInput struct {
+	// Datastore is the service whose dsn changed
+	Datastore datastores.Datastore
+
+	// NoRestart is whether to skip restarting the apps
+	NoRestart bool
+
+	// ServiceName is the name of the service whose dsn changed
+	ServiceName string
+}
+
+// RefreshLinkedApps sets the current service dsn on every linked app
+func RefreshLinkedApps(ctx context.Context, input RefreshLinkedAppsInput) error {
+	linkedApps := datastores.LinkedApps(ctx, datastores.LinkedAppsInput{
+		Datastore:   input.Datastore,
+		ServiceName: input.ServiceName,
+	})
+	for _, appName := range linkedApps {
+		err := datastores.SetAppConfig(ctx, datastores.SetAppConfigInput{
+			AppName:   appName,
+			NoRestart: input.NoRestart,
+			Values: map[string]string{
+				datastores.DSNEnvVariable(input.Datastore): datastores.AppURL(input.Datastore, input.ServiceName, appName),
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update dsn for app %s: %w", appName, err)
+		}
+	}
+	return nil
+}
+
+// writeLinkedApps writes the links file for a service
+func writeLinkedApps(s datastores.Datastore, serviceName string, linkedApps []string) error {
+	linksFile := datastores.Files(s, serviceName).Links
+	err := common.WriteSliceToFile(common.WriteSliceToFileInput{
+		Filename:  linksFile,
+		GroupName: datastores.SystemGroup(),
+		Lines:     linkedApps,
+		Mode:      0644,
+		Username:  datastores.SystemUser(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write links file %s: %w", linksFile, err)
+	}
+	return nil
+}
+
+// SetAppUserInput is the input for the SetAppUser function
+type SetAppUserInput struct {
+	// AppName is the name of the linked app to set the user of
+	AppName string
+
+	// Datastore is the service to set the app user on
+	Datastore datastores.Datastore
+
+	// NoRestart is whether to skip restarting the app after its dsn changes
+	NoRestart bool
+
+	// Rules is the permissions of the user, where empty keeps the current permissions
+	Rules string
+
+	// ServiceName is the name of the service to set the app user on
+	ServiceName string
+}
+
+// SetAppUser gives a linked app its own credential with the given permissions and updates its dsn
+func SetAppUser(ctx context.Context, input SetAppUserInput) error {
+	manager, ok := input.Datastore.(datastores.AppUserManager)
+	if !ok {
+		return fmt.Errorf("%s services do not support per-app users", input.Datastore.ServiceType())
+	}
+
+	linkedApps := datastores.LinkedApps(ctx, datastores.LinkedAppsInput{
+		Datastore:   input.Datastore,
+		ServiceName: input.ServiceName,
+	})
+	if !slices.Contains(linkedApps, input.AppName) {
+		return fmt.Errorf("service %s is not linked to app %s", input.ServiceName, input.AppName)
+	}
+
+	if err := manager.SetAppUser(ctx, input.ServiceName, input.AppName, input.Rules); err != nil {
+		return err
+	}
+
+	return datastores.SetAppConfig(ctx, datastores.SetAppConfigInput{
+		AppName:   input.AppName,
+		NoRestart: input.NoRestart,
+		Values: map[string]string{
+			datastores.DSNEnvVariable(input.Datastore): datastores.AppURL(input.Datastore, input.ServiceName, input.AppName),
+		},
+	})
+}
+
+// RevokeAppUserInput is the input for the RevokeAppUser function
+type RevokeAppUserInput struct {
+	// AppName is the name of the app to revoke the user of
+	AppName string
+
+	// Datastore is the service to revoke the app user on
+	Datastore datastores.Datastore
+
+	// ServiceName is the name of the service to revoke the app user on
+	ServiceName string
+}
+
+// RevokeAppUser disables the credential of an app without affecting other apps linked to the service
+func RevokeAppUser(ctx context.Context, input RevokeAppUserInput) error {
+	manager, ok := input.Datastore.(datastores.AppUserManager)
+	if !ok {
+		return fmt.Errorf("%s services do not support per-app users", input.Datastore.ServiceType())
+	}
+
+	return manager.RevokeAppUser(ctx, input.ServiceName, input.AppName)
+}
+
+// ListAppUsers returns the per-app users of a service
+func ListAppUsers(s datastores.Datastore, serviceName string) ([]datastores.AppUser, error) {
+	manager, ok := s.(datastores.AppUserManager)
+	if !ok {
+		return nil, fmt.Errorf("%s services do not support per-app users", s.ServiceType())
+	}
+
+	return manager.AppUsers(serviceName), nil
+}
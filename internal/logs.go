@@ -1,65 +1,276 @@
 package internal
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/dokku/dokku-datastore/internal/datastores"
 	"github.com/dokku/dokku/plugins/common"
 )
 
+// LogLine is a single line of container output
+type LogLine struct {
+	// Member is the cluster member that wrote the line, and empty for services backed by a single container
+	Member string `json:"member,omitempty"`
+
+	// Stream is the stream the line was written to, one of stdout or stderr
+	Stream string `json:"stream"`
+
+	// Timestamp is when docker received the line, in RFC3339 format with nanoseconds
+	Timestamp string `json:"timestamp"`
+
+	// Message is the line without the timestamp
+	Message string `json:"message"`
+}
+
 // LogsInput is the input for the Logs function
 type LogsInput struct {
+	// Ambassador is whether to get the logs of the expose container instead of the service container
+	Ambassador bool
+
 	// Datastore is the datastore to get the logs for
 	Datastore datastores.Datastore
 
+	// Grep is a regular expression lines must match to be displayed
+	Grep *regexp.Regexp
+
+	// Handler is called with every displayed line, overriding the default output to Stdout and Stderr
+	Handler func(LogLine) error
+
+	// Member is the cluster member to get the logs of, where empty gets the logs of every member
+	Member string
+
 	// ServiceName is the name of the service to get the logs for
 	ServiceName string
 
 	// Num is the number of lines to display
 	Num int
 
+	// Since is the timestamp or relative duration to show logs since
+	Since string
+
 	// Tail is whether to tail the logs
 	Tail bool
+
+	// Timestamps is whether to prefix displayed lines with their timestamp
+	Timestamps bool
+
+	// Until is the timestamp or relative duration to show logs until
+	Until string
+
+	// Stdout is the writer for the log stdout, defaulting to os.Stdout
+	Stdout io.Writer
+
+	// Stderr is the writer for the log stderr, defaulting to os.Stderr
+	Stderr io.Writer
+}
+
+// logsContainer is a container to get the logs of
+type logsContainer struct {
+	// containerID is the id or name of the container
+	containerID string
+
+	// member is the cluster member the container belongs to, and empty for services backed by a single container
+	member string
 }
 
 // Logs gets the logs for a service
+//
+// The logs of every member of a cluster are interleaved as they arrive, with each line tagged with its member
 func Logs(ctx context.Context, input LogsInput) error {
-	containerID := datastores.LiveContainerID(ctx, datastores.LiveContainerIDInput{
-		Datastore:   input.Datastore,
-		ServiceName: input.ServiceName,
-	})
-	if containerID == "" {
-		return fmt.Errorf("container %s does not exist", input.ServiceName)
+	containers, err := logsContainers(ctx, input)
+	if err != nil {
+		return err
 	}
 
-	args := []string{"container", "logs", containerID}
+	// timestamps are always requested so they can be split from the message for filtering and json output
+	args := []string{"container", "logs", "--timestamps"}
 	if input.Num > 0 {
 		args = append(args, "--tail", strconv.Itoa(input.Num))
 	}
+	if input.Since != "" {
+		args = append(args, "--since", input.Since)
+	}
+	if input.Until != "" {
+		args = append(args, "--until", input.Until)
+	}
 	if input.Tail {
 		args = append(args, "--follow")
 	}
 
+	if input.Stdout == nil {
+		input.Stdout = os.Stdout
+	}
+	if input.Stderr == nil {
+		input.Stderr = os.Stderr
+	}
+	if input.Handler == nil {
+		input.Handler = func(line LogLine) error {
+			writer := input.Stdout
+			if line.Stream == "stderr" {
+				writer = input.Stderr
+			}
+			prefix := ""
+			if input.Timestamps {
+				prefix = line.Timestamp + " "
+			}
+			if line.Member != "" {
+				prefix += line.Member + " | "
+			}
+			_, err := fmt.Fprintln(writer, prefix+line.Message)
+			return err
+		}
+	}
+
+	// a failing handler stops every docker logs process through the context
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	handler := func(line LogLine) error {
+		mu.Lock()
+		defer mu.Unlock()
+		return input.Handler(line)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(containers))
+	for i, container := range containers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = streamContainerLogs(ctx, cancel, slices.Concat(args, []string{container.containerID}), input.Grep, func(line LogLine) error {
+				line.Member = container.member
+				return handler(line)
+			})
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	if ctx.Err() != nil && !errors.Is(ctx.Err(), context.Canceled) {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// streamContainerLogs runs docker logs for a single container, passing its lines to the handler
+func streamContainerLogs(ctx context.Context, cancel context.CancelFunc, args []string, grep *regexp.Regexp, handler func(LogLine) error) error {
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	stdoutReader, stdoutWriter := io.Pipe()
+	stderrReader, stderrWriter := io.Pipe()
+	for i, stream := range []struct {
+		name   string
+		reader io.Reader
+	}{{"stdout", stdoutReader}, {"stderr", stderrReader}} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = readLogLines(stream.reader, stream.name, grep, handler)
+			if errs[i] != nil {
+				cancel()
+				io.Copy(io.Discard, stream.reader) //nolint:errcheck
+			}
+		}()
+	}
+
 	_, err := datastores.CallExecCommandWithContext(ctx, common.ExecCommandInput{
 		Command:      common.DockerBin(),
 		Args:         args,
-		StdoutWriter: os.Stdout,
-		StderrWriter: os.Stderr,
+		StdoutWriter: stdoutWriter,
+		StderrWriter: stderrWriter,
 	})
+	stdoutWriter.Close()
+	stderrWriter.Close()
+	wg.Wait()
 
-	if err != nil {
-		if ctx.Err() != nil {
-			if errors.Is(ctx.Err(), context.Canceled) {
-				return nil
+	if readErr := errors.Join(errs...); readErr != nil {
+		return readErr
+	}
+
+	// errors caused by cancelling the context are reported by the caller
+	if err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+// logsContainers returns the containers to get the logs of
+func logsContainers(ctx context.Context, input LogsInput) ([]logsContainer, error) {
+	if input.Ambassador {
+		for _, containerName := range datastores.ExposeContainerNames(input.Datastore, input.ServiceName) {
+			if datastores.ContainerExists(ctx, containerName) {
+				return []logsContainer{{containerID: containerName}}, nil
 			}
-			return ctx.Err()
 		}
-		return err
+		return nil, fmt.Errorf("service %s has no ambassador or proxy container", input.ServiceName)
 	}
 
-	return nil
+	members := datastores.ServiceMembers(input.Datastore, input.ServiceName)
+	if input.Member != "" {
+		member, err := datastores.ParseMember(input.Datastore, input.ServiceName, input.Member)
+		if err != nil {
+			return nil, err
+		}
+		members = []string{member}
+	}
+
+	clustered := datastores.ClusterEnabled(input.Datastore, input.ServiceName)
+	containers := []logsContainer{}
+	for _, member := range members {
+		containerID := datastores.LiveContainerID(ctx, datastores.LiveContainerIDInput{
+			Datastore:   input.Datastore,
+			Member:      member,
+			ServiceName: input.ServiceName,
+		})
+		if containerID == "" {
+			continue
+		}
+
+		container := logsContainer{containerID: containerID}
+		if clustered {
+			container.member = datastores.MemberDisplayName(member)
+		}
+		containers = append(containers, container)
+	}
+
+	if len(containers) == 0 {
+		return nil, fmt.Errorf("container %s does not exist", input.ServiceName)
+	}
+	return containers, nil
+}
+
+// readLogLines splits timestamped docker log output into lines, passing those matching grep to the handler
+func readLogLines(reader io.Reader, stream string, grep *regexp.Regexp, handler func(LogLine) error) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		timestamp, message, _ := strings.Cut(scanner.Text(), " ")
+		if grep != nil && !grep.MatchString(message) {
+			continue
+		}
+
+		err := handler(LogLine{
+			Stream:    stream,
+			Timestamp: timestamp,
+			Message:   message,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
 }
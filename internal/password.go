@@ -0,0 +1,44 @@
+package internal
+
+import (
+	"context"
+
+	"github.com/dokku/dokku-datastore/internal/datastores"
+)
+
+// RotateServicePasswordInput is the input for the RotateServicePassword function
+type RotateServicePasswordInput struct {
+	// Datastore is the service to rotate the password of
+	Datastore datastores.Datastore
+
+	// KeepPrevious is whether the current password stays valid until it is revoked
+	KeepPrevious bool
+
+	// NoRestart is whether to skip restarting linked apps after their dsn changes
+	NoRestart bool
+
+	// Password is the new password, where empty generates one
+	Password string
+
+	// ServiceName is the name of the service to rotate the password of
+	ServiceName string
+}
+
+// RotateServicePassword changes the password of a service and updates the dsn of linked apps
+func RotateServicePassword(ctx context.Context, input RotateServicePasswordInput) error {
+	err := datastores.RotatePassword(ctx, datastores.RotatePasswordInput{
+		Datastore:    input.Datastore,
+		KeepPrevious: input.KeepPrevious,
+		Password:     input.Password,
+		ServiceName:  input.ServiceName,
+	})
+	if err != nil {
+		return err
+	}
+
+	return RefreshLinkedApps(ctx, RefreshLinkedAppsInput{
+		Datastore:   input.Datastore,
+		NoRestart:   input.NoRestart,
+		ServiceName: input.ServiceName,
+	})
+}
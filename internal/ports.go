@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/dokku/dokku-datastore/internal/datastores"
+)
+
+// ListPortReservationsInput is the input for the ListPortReservations function
+type ListPortReservationsInput struct {
+	// Trace is whether to enable trace output
+	Trace bool
+}
+
+// ListPortReservations lists the host port reservations of every service the user has access to
+func ListPortReservations(ctx context.Context, input ListPortReservationsInput) ([]datastores.PortReservation, error) {
+	reservations, err := datastores.PortReservations()
+	if err != nil {
+		return nil, err
+	}
+
+	servicesByType := map[string][]string{}
+	for _, reservation := range reservations {
+		if !slices.Contains(servicesByType[reservation.Type], reservation.Service) {
+			servicesByType[reservation.Type] = append(servicesByType[reservation.Type], reservation.Service)
+		}
+	}
+
+	allowedByType := map[string][]string{}
+	for serviceType, services := range servicesByType {
+		datastore, ok := datastores.Datastores[serviceType]
+		if !ok {
+			// reservations of datastores that are no longer defined are still shown, as their ports remain claimed
+			allowedByType[serviceType] = services
+			continue
+		}
+
+		allowed, err := datastores.FilterServices(ctx, datastores.FilterServicesInput{
+			Datastore: datastore,
+			Services:  services,
+			Trace:     input.Trace,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to filter services: %w", err)
+		}
+		allowedByType[serviceType] = allowed
+	}
+
+	filtered := []datastores.PortReservation{}
+	for _, reservation := range reservations {
+		if slices.Contains(allowedByType[reservation.Type], reservation.Service) {
+			filtered = append(filtered, reservation)
+		}
+	}
+
+	return filtered, nil
+}
@@ -0,0 +1,126 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ProxyForward is a single port forwarded by the proxy
+type ProxyForward struct {
+	// ListenPort is the port to listen on
+	ListenPort int
+
+	// Target is the host:port address to forward connections to
+	Target string
+}
+
+// ParseProxyForward parses a forward in the format <listen-port>=<host>:<port>
+func ParseProxyForward(value string) (ProxyForward, error) {
+	listenPort, target, ok := strings.Cut(value, "=")
+	if !ok {
+		return ProxyForward{}, fmt.Errorf("invalid forward %s, expected <listen-port>=<host>:<port>", value)
+	}
+
+	port, err := strconv.Atoi(listenPort)
+	if err != nil || port < 1 || port > 65535 {
+		return ProxyForward{}, fmt.Errorf("invalid listen port %s", listenPort)
+	}
+
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		return ProxyForward{}, fmt.Errorf("invalid forward target %s: %w", target, err)
+	}
+
+	return ProxyForward{ListenPort: port, Target: target}, nil
+}
+
+// ProxyInput is the input for the ServeProxy function
+type ProxyInput struct {
+	// Forwards is the ports to forward
+	Forwards []ProxyForward
+}
+
+// ServeProxy forwards tcp connections for every forward until the context is cancelled
+func ServeProxy(ctx context.Context, input ProxyInput) error {
+	if len(input.Forwards) == 0 {
+		return fmt.Errorf("at least one forward is required")
+	}
+
+	listeners := []net.Listener{}
+	for _, forward := range input.Forwards {
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", forward.ListenPort))
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return fmt.Errorf("failed to listen on port %d: %w", forward.ListenPort, err)
+		}
+		listeners = append(listeners, listener)
+	}
+
+	go func() {
+		<-ctx.Done()
+		for _, listener := range listeners {
+			listener.Close()
+		}
+	}()
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(listeners))
+	for i, listener := range listeners {
+		wg.Add(1)
+		go func(i int, listener net.Listener, target string) {
+			defer wg.Done()
+			errs[i] = acceptProxyConnections(ctx, listener, target)
+		}(i, listener, input.Forwards[i].Target)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// acceptProxyConnections accepts connections on a listener and forwards each to the target
+func acceptProxyConnections(ctx context.Context, listener net.Listener, target string) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+
+		go proxyConnection(ctx, conn, target)
+	}
+}
+
+// proxyConnection copies data in both directions between a client and the target
+func proxyConnection(ctx context.Context, client net.Conn, target string) {
+	defer client.Close()
+
+	var dialer net.Dialer
+	upstream, err := dialer.DialContext(ctx, "tcp", target)
+	if err != nil {
+		return
+	}
+	defer upstream.Close()
+
+	done := make(chan struct{}, 2)
+	copyHalf := func(dst net.Conn, src net.Conn) {
+		io.Copy(dst, src) //nolint:errcheck
+		if tcpConn, ok := dst.(*net.TCPConn); ok {
+			tcpConn.CloseWrite() //nolint:errcheck
+		}
+		done <- struct{}{}
+	}
+	go copyHalf(upstream, client)
+	go copyHalf(client, upstream)
+
+	<-done
+	<-done
+}
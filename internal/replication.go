@@ -0,0 +1,161 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/dokku/dokku-datastore/internal/datastores"
+)
+
+// ReplicateServiceInput is the input for the ReplicateService function
+type ReplicateServiceInput struct {
+	// Datastore is the service to turn into a replica
+	Datastore datastores.Datastore
+
+	// PrimaryName is the name of the service to replicate from
+	PrimaryName string
+
+	// ServiceName is the name of the service to turn into a replica
+	ServiceName string
+}
+
+// ReplicateService turns an existing service into a replica of a primary, discarding its own data
+func ReplicateService(ctx context.Context, input ReplicateServiceInput) error {
+	for _, serviceName := range []string{input.ServiceName, input.PrimaryName} {
+		if err := checkManualReplication(input.Datastore, serviceName); err != nil {
+			return err
+		}
+	}
+
+	if replicas := datastores.Replicas(input.Datastore, input.ServiceName); len(replicas) > 0 {
+		return fmt.Errorf("service %s has replicas and cannot become a replica itself", input.ServiceName)
+	}
+
+	if datastores.TLSEnabled(input.Datastore, input.PrimaryName) && !datastores.TLSEnabled(input.Datastore, input.ServiceName) {
+		return fmt.Errorf("primary service %s requires tls, enable tls on service %s first", input.PrimaryName, input.ServiceName)
+	}
+
+	return datastores.ConfigureReplica(ctx, datastores.ConfigureReplicaInput{
+		Datastore:   input.Datastore,
+		PrimaryName: input.PrimaryName,
+		ServiceName: input.ServiceName,
+	})
+}
+
+// PromoteReplicaInput is the input for the PromoteReplica function
+type PromoteReplicaInput struct {
+	// Datastore is the replica to promote
+	Datastore datastores.Datastore
+
+	// NoRestart is whether to skip restarting apps after their dsn changes
+	NoRestart bool
+
+	// ServiceName is the name of the replica to promote
+	ServiceName string
+}
+
+// PromoteReplica makes a replica the primary, re-pointing the other replicas and the apps linked to the old primary at it
+//
+// The old primary is left as a standalone service, so it can be destroyed or turned into a replica once it recovers
+func PromoteReplica(ctx context.Context, input PromoteReplicaInput) error {
+	if err := checkManualReplication(input.Datastore, input.ServiceName); err != nil {
+		return err
+	}
+
+	primaryName := datastores.ReplicaOf(input.Datastore, input.ServiceName)
+	err := datastores.PromoteReplica(ctx, datastores.PromoteReplicaInput{
+		Datastore:   input.Datastore,
+		ServiceName: input.ServiceName,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, replicaName := range datastores.Replicas(input.Datastore, primaryName) {
+		err := datastores.ConfigureReplica(ctx, datastores.ConfigureReplicaInput{
+			Datastore:   input.Datastore,
+			PrimaryName: input.ServiceName,
+			ServiceName: replicaName,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to re-point replica %s: %w", replicaName, err)
+		}
+	}
+
+	primaryApps := datastores.LinkedApps(ctx, datastores.LinkedAppsInput{
+		Datastore:   input.Datastore,
+		ServiceName: primaryName,
+	})
+	for _, appName := range primaryApps {
+		if err := moveLink(ctx, input, primaryName, appName); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// moveLink links an app of the old primary to the promoted replica, then drops the link to the old primary
+func moveLink(ctx context.Context, input PromoteReplicaInput, primaryName string, appName string) error {
+	linkedApps := datastores.LinkedApps(ctx, datastores.LinkedAppsInput{
+		Datastore:   input.Datastore,
+		ServiceName: input.ServiceName,
+	})
+	if !slices.Contains(linkedApps, appName) {
+		if err := writeLinkedApps(input.Datastore, input.ServiceName, append(linkedApps, appName)); err != nil {
+			return err
+		}
+	}
+
+	manager, hasAppUsers := input.Datastore.(datastores.AppUserManager)
+	if hasAppUsers {
+		if err := manager.SetAppUser(ctx, input.ServiceName, appName, ""); err != nil {
+			return fmt.Errorf("failed to create user for app %s: %w", appName, err)
+		}
+	}
+
+	err := datastores.SetAppConfig(ctx, datastores.SetAppConfigInput{
+		AppName:   appName,
+		NoRestart: input.NoRestart,
+		Values: map[string]string{
+			datastores.DSNEnvVariable(input.Datastore): datastores.AppURL(input.Datastore, input.ServiceName, appName),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	primaryApps := datastores.LinkedApps(ctx, datastores.LinkedAppsInput{
+		Datastore:   input.Datastore,
+		ServiceName: primaryName,
+	})
+	remainingApps := slices.DeleteFunc(primaryApps, func(linkedApp string) bool {
+		return linkedApp == appName
+	})
+	if err := writeLinkedApps(input.Datastore, primaryName, remainingApps); err != nil {
+		return err
+	}
+
+	// the old primary is usually unreachable during a failover, and its users stop mattering once no app uses them
+	if hasAppUsers {
+		manager.RemoveAppUser(ctx, primaryName, appName) //nolint:errcheck
+	}
+
+	return nil
+}
+
+// checkManualReplication refuses to change the replication of a high availability or cluster service,
+// which its monitors or the cluster manage
+func checkManualReplication(s datastores.Datastore, serviceName string) error {
+	if datastores.ClusterEnabled(s, serviceName) {
+		return fmt.Errorf("replication of cluster service %s is managed by the cluster", serviceName)
+	}
+	if owner := datastores.HAMemberOf(s, serviceName); owner != "" {
+		return fmt.Errorf("replication of service %s is managed by high availability service %s", serviceName, owner)
+	}
+	if datastores.HAEnabled(s, serviceName) {
+		return fmt.Errorf("replication of high availability service %s is managed by its monitors", serviceName)
+	}
+	return nil
+}
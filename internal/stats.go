@@ -0,0 +1,256 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/dokku/dokku-datastore/internal/datastores"
+	"github.com/dokku/dokku/plugins/common"
+)
+
+// ServiceStats is a snapshot of the resource usage of a service container
+type ServiceStats struct {
+	// Type is the datastore type of the service
+	Type string `json:"type"`
+
+	// Service is the name of the service
+	Service string `json:"service"`
+
+	// Member is the cluster member the container belongs to, and empty for the service container
+	Member string `json:"member,omitempty"`
+
+	// CPUPercent is the share of host cpu used by the container
+	CPUPercent string `json:"cpu-percent"`
+
+	// MemoryUsage is the memory used by the container
+	MemoryUsage string `json:"memory-usage"`
+
+	// MemoryLimit is the MEMORY limit of the service, or unlimited when unset
+	MemoryLimit string `json:"memory-limit"`
+
+	// MemoryPercent is the share of the container memory limit in use
+	MemoryPercent string `json:"memory-percent"`
+
+	// NetIO is the network bytes received and sent
+	NetIO string `json:"net-io"`
+
+	// BlockIO is the block device bytes read and written
+	BlockIO string `json:"block-io"`
+
+	// PIDs is the number of processes in the container
+	PIDs string `json:"pids"`
+}
+
+// ServiceStatsInput is the input for the CollectServiceStats function
+type ServiceStatsInput struct {
+	// Datastore is the datastore to collect stats for, where nil collects every datastore
+	Datastore datastores.Datastore
+
+	// ServiceName is the name of the service to collect stats for, where empty collects every service
+	ServiceName string
+
+	// Trace is whether to enable trace output
+	Trace bool
+}
+
+// dockerStats is a line of docker container stats json output
+type dockerStats struct {
+	// BlockIO is the block device bytes read and written
+	BlockIO string `json:"BlockIO"`
+
+	// CPUPerc is the cpu percentage
+	CPUPerc string `json:"CPUPerc"`
+
+	// MemPerc is the memory percentage
+	MemPerc string `json:"MemPerc"`
+
+	// MemUsage is the memory usage and limit
+	MemUsage string `json:"MemUsage"`
+
+	// Name is the container name
+	Name string `json:"Name"`
+
+	// NetIO is the network bytes received and sent
+	NetIO string `json:"NetIO"`
+
+	// PIDs is the number of processes
+	PIDs string `json:"PIDs"`
+}
+
+// CollectServiceStats takes a single snapshot of the resource usage of running service containers
+func CollectServiceStats(ctx context.Context, input ServiceStatsInput) ([]ServiceStats, error) {
+	args := []string{"container", "ls", "--filter", "label=dokku=service", "--format", `{{ .Names }} {{ .Label "dokku.service" }} {{ .Label "dokku.member" }}`}
+	if input.Datastore != nil {
+		args = append(args, "--filter", "label=dokku.service="+input.Datastore.Properties().CommandPrefix)
+	}
+	if input.ServiceName != "" {
+		for _, member := range datastores.ServiceMembers(input.Datastore, input.ServiceName) {
+			args = append(args, "--filter", fmt.Sprintf("name=^/%s$", datastores.MemberContainerName(input.Datastore, input.ServiceName, member)))
+		}
+	}
+
+	result, err := datastores.CallExecCommandWithContext(ctx, common.ExecCommandInput{
+		Command: common.DockerBin(),
+		Args:    args,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list service containers: %w", err)
+	}
+
+	containers := map[string]ServiceStats{}
+	servicesByType := map[string][]string{}
+	for line := range strings.SplitSeq(result.StdoutContents(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 && len(fields) != 3 {
+			continue
+		}
+
+		datastore := datastoreForCommandPrefix(fields[1])
+		if datastore == nil {
+			continue
+		}
+
+		member := ""
+		serviceName := strings.TrimPrefix(fields[0], datastores.ContainerName(datastore, ""))
+		if len(fields) == 3 {
+			member = fields[2]
+			serviceName = strings.TrimSuffix(serviceName, "."+member)
+		}
+		if serviceName == fields[0] || serviceName == "" {
+			continue
+		}
+
+		containers[fields[0]] = ServiceStats{
+			Type:        datastore.ServiceType(),
+			Service:     serviceName,
+			Member:      member,
+			MemoryLimit: serviceMemoryLimit(datastore, serviceName),
+		}
+		if !slices.Contains(servicesByType[datastore.ServiceType()], serviceName) {
+			servicesByType[datastore.ServiceType()] = append(servicesByType[datastore.ServiceType()], serviceName)
+		}
+	}
+
+	// a single requested service has already been authorized by the caller
+	if input.ServiceName == "" {
+		for serviceType, services := range servicesByType {
+			allowed, err := datastores.FilterServices(ctx, datastores.FilterServicesInput{
+				Datastore: datastores.Datastores[serviceType],
+				Services:  services,
+				Trace:     input.Trace,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to filter services: %w", err)
+			}
+
+			for name, stats := range containers {
+				if stats.Type == serviceType && !slices.Contains(allowed, stats.Service) {
+					delete(containers, name)
+				}
+			}
+		}
+	}
+
+	stats := []ServiceStats{}
+	if len(containers) == 0 {
+		return stats, nil
+	}
+
+	names := []string{}
+	for name := range containers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result, err = datastores.CallExecCommandWithContext(ctx, common.ExecCommandInput{
+		Command: common.DockerBin(),
+		Args:    append([]string{"container", "stats", "--no-stream", "--format", "{{ json . }}"}, names...),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container stats: %w", err)
+	}
+
+	for line := range strings.SplitSeq(result.StdoutContents(), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		entry := dockerStats{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse container stats: %w", err)
+		}
+
+		serviceStats, ok := containers[entry.Name]
+		if !ok {
+			continue
+		}
+
+		memoryUsage, _, _ := strings.Cut(entry.MemUsage, "/")
+		serviceStats.CPUPercent = entry.CPUPerc
+		serviceStats.MemoryUsage = strings.TrimSpace(memoryUsage)
+		serviceStats.MemoryPercent = entry.MemPerc
+		serviceStats.NetIO = entry.NetIO
+		serviceStats.BlockIO = entry.BlockIO
+		serviceStats.PIDs = entry.PIDs
+		stats = append(stats, serviceStats)
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Type != stats[j].Type {
+			return stats[i].Type < stats[j].Type
+		}
+		if stats[i].Service != stats[j].Service {
+			return stats[i].Service < stats[j].Service
+		}
+		return stats[i].Member < stats[j].Member
+	})
+
+	return stats, nil
+}
+
+// FormatServiceStats renders stats as table rows, with a column header as the first row
+func FormatServiceStats(stats []ServiceStats) []string {
+	format := "%-30s  %-8s  %-22s  %-7s  %-22s  %-22s  %s"
+	rows := []string{fmt.Sprintf(format, "SERVICE", "CPU %", "MEM USAGE / LIMIT", "MEM %", "NET I/O", "BLOCK I/O", "PIDS")}
+	for _, s := range stats {
+		name := s.Type + "/" + s.Service
+		if s.Member != "" {
+			name += "/" + s.Member
+		}
+		rows = append(rows, fmt.Sprintf(format,
+			name,
+			s.CPUPercent,
+			s.MemoryUsage+" / "+s.MemoryLimit,
+			s.MemoryPercent,
+			s.NetIO,
+			s.BlockIO,
+			s.PIDs,
+		))
+	}
+	return rows
+}
+
+// datastoreForCommandPrefix returns the datastore using a command prefix, if any
+func datastoreForCommandPrefix(commandPrefix string) datastores.Datastore {
+	for _, datastore := range datastores.Datastores {
+		if datastore.Properties().CommandPrefix == commandPrefix {
+			return datastore
+		}
+	}
+	return nil
+}
+
+// serviceMemoryLimit returns the MEMORY limit of a service in the units docker uses
+func serviceMemoryLimit(s datastores.Datastore, serviceName string) string {
+	// services created without a limit have 0 written to their MEMORY file
+	memory := common.ReadFirstLine(datastores.Files(s, serviceName).Memory)
+	if memory == "" || memory == "0" {
+		return "unlimited"
+	}
+	return memory + "MiB"
+}
@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dokku/dokku-datastore/internal/datastores"
+)
+
+// TLSCAResult is the json result of the tls-ca command
+type TLSCAResult struct {
+	// Type is the datastore type of the service
+	Type string `json:"type"`
+	// Service is the name of the service
+	Service string `json:"service"`
+	// CABundle is the pem encoded certificate authority bundle
+	CABundle string `json:"ca-bundle"`
+}
+
+// SetServiceTLSInput is the input for the SetServiceTLS function
+type SetServiceTLSInput struct {
+	// Datastore is the service to enable or disable tls for
+	Datastore datastores.Datastore
+
+	// Enabled is whether the service should require tls
+	Enabled bool
+
+	// NoRestart is whether to skip restarting linked apps after their dsn changes
+	NoRestart bool
+
+	// ServiceName is the name of the service to enable or disable tls for
+	ServiceName string
+}
+
+// SetServiceTLS enables or disables tls for a service, recreating its container and updating the dsn of linked apps
+func SetServiceTLS(ctx context.Context, input SetServiceTLSInput) error {
+	if datastores.TLSEnabled(input.Datastore, input.ServiceName) == input.Enabled {
+		return nil
+	}
+
+	err := datastores.ConfigureServiceTLS(ctx, datastores.ConfigureServiceTLSInput{
+		Datastore:   input.Datastore,
+		Enabled:     input.Enabled,
+		ServiceName: input.ServiceName,
+	})
+	if err != nil {
+		return err
+	}
+
+	// the listening port and healthcheck change, so the container is recreated to apply them
+	err = datastores.RecreateServiceContainer(ctx, datastores.RecreateServiceContainerInput{
+		Datastore:   input.Datastore,
+		ServiceName: input.ServiceName,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to recreate service container: %w", err)
+	}
+
+	return RefreshLinkedApps(ctx, RefreshLinkedAppsInput{
+		Datastore:   input.Datastore,
+		NoRestart:   input.NoRestart,
+		ServiceName: input.ServiceName,
+	})
+}
@@ -10,16 +10,68 @@ import (
 	"github.com/mitchellh/cli"
 )
 
+// EnvelopeSchemaVersion is the version of the json output envelope
+const EnvelopeSchemaVersion = 1
+
+// Envelope is the single json document emitted by every command when the format is json
+type Envelope struct {
+	// Schema is the version of the envelope schema
+	Schema int `json:"schema"`
+	// Command is the name of the command that was run
+	Command string `json:"command"`
+	// Result is the command specific result
+	Result interface{} `json:"result"`
+	// Errors is the list of errors encountered while running the command
+	Errors []EnvelopeError `json:"errors"`
+	// Warnings is the list of warnings encountered while running the command
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// EnvelopeError is an error in the json output envelope
+type EnvelopeError struct {
+	// Message is the error message
+	Message string `json:"message"`
+	// Detail is additional context for the error, such as usage text
+	Detail string `json:"detail,omitempty"`
+}
+
+// ServiceResult is the json result for commands that act on a single service
+type ServiceResult struct {
+	// Type is the datastore type of the service
+	Type string `json:"type"`
+	// Service is the name of the service
+	Service string `json:"service"`
+	// Status is the status of the service after the command ran
+	Status string `json:"status,omitempty"`
+	// App is the app the command was run against
+	App string `json:"app,omitempty"`
+	// ExposedPorts is the exposed ports of the service
+	ExposedPorts string `json:"exposed-ports,omitempty"`
+}
+
 // Ui is the UI wrapper for the CLI
 type Ui struct {
 	// Ui is the underlying UI implementation
 	Ui cli.Ui
+	// Command is the name of the command being run
+	Command string
 	// Format is the format to output the data in
 	Format string
 	// Quiet is whether to suppress output
 	Quiet bool
 	// Trace is whether to enable trace output
 	Trace bool
+
+	// result is the result to emit in the json envelope
+	result interface{}
+	// errors is the errors to emit in the json envelope
+	errors []EnvelopeError
+	// warnings is the warnings to emit in the json envelope
+	warnings []string
+	// flushed is whether the json envelope has been emitted
+	flushed bool
+	// streamed is whether results have been emitted as individual json lines
+	streamed bool
 }
 
 // ErrorInput is the input for the Error method
@@ -33,7 +85,11 @@ type ErrorInput struct {
 // Error outputs an error message
 func (u *Ui) Error(input ErrorInput) {
 	if u.Format == "json" {
-		json.NewEncoder(os.Stderr).Encode(input) //nolint:errcheck
+		u.errors = append(u.errors, EnvelopeError{
+			Message: input.Error.Error(),
+			Detail:  input.Message,
+		})
+		return
 	}
 
 	errorMessage := input.Error.Error()
@@ -47,10 +103,37 @@ func (u *Ui) Error(input ErrorInput) {
 	}
 }
 
+// Flush emits the json envelope, and is a no-op for other formats or if already emitted
+func (u *Ui) Flush() {
+	if u.Format != "json" || u.flushed {
+		return
+	}
+	u.flushed = true
+
+	// streamed results were already emitted, so a trailing envelope is only needed for errors
+	if u.streamed && len(u.errors) == 0 {
+		return
+	}
+
+	errors := u.errors
+	if errors == nil {
+		errors = []EnvelopeError{}
+	}
+
+	json.NewEncoder(os.Stdout).Encode(Envelope{ //nolint:errcheck
+		Schema:   EnvelopeSchemaVersion,
+		Command:  u.Command,
+		Result:   u.result,
+		Errors:   errors,
+		Warnings: u.warnings,
+	})
+}
+
 // Help outputs a help message
 func (u *Ui) Help(message string) error {
 	if u.Format == "json" {
-		return json.NewEncoder(os.Stdout).Encode(map[string]string{"help": message})
+		u.result = map[string]string{"help": message}
+		return nil
 	}
 
 	u.Ui.Output(message)
@@ -60,7 +143,7 @@ func (u *Ui) Help(message string) error {
 // Header1 outputs a header1 message
 func (u *Ui) Header1(message string) error {
 	if u.Format == "json" {
-		return json.NewEncoder(os.Stdout).Encode(map[string]string{"header1": message})
+		return nil
 	}
 
 	logger, ok := u.Ui.(*command.ZerologUi)
@@ -75,7 +158,7 @@ func (u *Ui) Header1(message string) error {
 // Header1 outputs a header1 message
 func (u *Ui) Header2(message string) error {
 	if u.Format == "json" {
-		return json.NewEncoder(os.Stdout).Encode(map[string]string{"header2": message})
+		return nil
 	}
 
 	logger, ok := u.Ui.(*command.ZerologUi)
@@ -90,16 +173,39 @@ func (u *Ui) Header2(message string) error {
 // Info outputs an info message
 func (u *Ui) Info(message string) {
 	if u.Format == "json" {
-		json.NewEncoder(os.Stdout).Encode(map[string]string{"message": message}) //nolint:errcheck
+		return
 	}
 
 	u.Ui.Output(message)
 }
 
+// Result sets the result emitted in the json envelope
+func (u *Ui) Result(result interface{}) {
+	u.result = result
+}
+
+// Stream emits a json envelope holding a single result immediately, for commands that produce json lines
+func (u *Ui) Stream(result interface{}) error {
+	u.streamed = true
+	warnings := u.warnings
+	u.warnings = nil
+	return json.NewEncoder(os.Stdout).Encode(Envelope{
+		Schema:   EnvelopeSchemaVersion,
+		Command:  u.Command,
+		Result:   result,
+		Errors:   []EnvelopeError{},
+		Warnings: warnings,
+	})
+}
+
 // Table outputs a table of data
 func (u *Ui) Table(header string, rows []string) error {
 	if u.Format == "json" {
-		return json.NewEncoder(os.Stdout).Encode(rows)
+		if rows == nil {
+			rows = []string{}
+		}
+		u.result = rows
+		return nil
 	}
 
 	logger, ok := u.Ui.(*command.ZerologUi)
@@ -127,7 +233,8 @@ type WarnInput struct {
 // Warn outputs a warning message
 func (u *Ui) Warn(input WarnInput) {
 	if u.Format == "json" {
-		json.NewEncoder(os.Stderr).Encode(input) //nolint:errcheck
+		u.warnings = append(u.warnings, input.Warning)
+		return
 	}
 
 	u.Ui.Warn(input.Warning)
@@ -19,17 +19,44 @@ type UnexposeServiceInput struct {
 
 // UnexposeService unexposes a service
 func UnexposeService(ctx context.Context, input UnexposeServiceInput) error {
-	ambassadorContainerName := datastores.AmbassadorContainerName(input.Datastore, input.ServiceName)
-	if datastores.ContainerExists(ctx, ambassadorContainerName) {
-		err := RemoveAmbassadorContainer(ctx, input.Datastore, input.ServiceName)
+	if err := RemoveExposeContainers(ctx, input.Datastore, input.ServiceName); err != nil {
+		return fmt.Errorf("failed to remove expose container: %w", err)
+	}
+
+	if len(datastores.ExposeAllowList(input.Datastore, input.ServiceName)) > 0 {
+		err := datastores.RemoveExposeFirewall(ctx, datastores.ExposeFirewallInput{
+			Datastore:   input.Datastore,
+			ServiceName: input.ServiceName,
+		})
 		if err != nil {
-			return fmt.Errorf("failed to remove ambassador container: %w", err)
+			return fmt.Errorf("failed to remove firewall rules: %w", err)
 		}
 	}
 
 	serviceFiles := datastores.Files(input.Datastore, input.ServiceName)
-	if err := os.RemoveAll(serviceFiles.Port); err != nil {
-		return fmt.Errorf("failed to remove port file: %w", err)
+	for _, file := range []string{serviceFiles.Port, serviceFiles.ExposeBind, serviceFiles.ExposeAllow} {
+		if err := os.RemoveAll(file); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", file, err)
+		}
+	}
+
+	err := datastores.ReleasePorts(datastores.ReleasePortsInput{
+		Datastore:   input.Datastore,
+		ServiceName: input.ServiceName,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release ports: %w", err)
+	}
+
+	if datastores.ExposeMode(input.Datastore, input.ServiceName) == datastores.ExposeModePublish {
+		// published ports are part of the service container, so it is recreated without them
+		err := datastores.RecreateServiceContainer(ctx, datastores.RecreateServiceContainerInput{
+			Datastore:   input.Datastore,
+			ServiceName: input.ServiceName,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to recreate service container: %w", err)
+		}
 	}
 
 	return nil
@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/dokku/dokku-datastore/commands"
+	"github.com/dokku/dokku-datastore/internal/datastores"
 
 	"github.com/josegonzalez/cli-skeleton/command"
 	"github.com/mitchellh/cli"
@@ -24,11 +25,16 @@ func main() {
 // Executes the specified subcommand
 func Run(args []string) int {
 	ctx := context.Background()
+	if err := datastores.RegisterDefinedDatastores(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading datastore definitions: %s\n", err.Error())
+	}
+
 	commandMeta := command.SetupRun(ctx, AppName, Version, args)
 	commandMeta.Ui = command.HumanZerologUiWithFields(commandMeta.Ui, make(map[string]interface{}, 0))
 	c := cli.NewCLI(AppName, Version)
 	c.Args = os.Args[1:]
 	c.Commands = command.Commands(ctx, commandMeta, Commands)
+	c.HiddenCommands = []string{"proxy"}
 	exitCode, err := c.Run()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error executing CLI: %s\n", err.Error())
@@ -41,9 +47,39 @@ func Run(args []string) int {
 // Returns a list of implemented commands
 func Commands(ctx context.Context, meta command.Meta) map[string]cli.CommandFactory {
 	return map[string]cli.CommandFactory{
+		"acl-list": func() (cli.Command, error) {
+			return &commands.ACLListCommand{Meta: meta}, nil
+		},
+		"acl-revoke": func() (cli.Command, error) {
+			return &commands.ACLRevokeCommand{Meta: meta}, nil
+		},
+		"acl-set": func() (cli.Command, error) {
+			return &commands.ACLSetCommand{Meta: meta}, nil
+		},
+		"api": func() (cli.Command, error) {
+			return &commands.ApiCommand{Meta: meta}, nil
+		},
+		"api serve": func() (cli.Command, error) {
+			return &commands.ApiServeCommand{Meta: meta}, nil
+		},
 		"app-links": func() (cli.Command, error) {
 			return &commands.AppLinksCommand{Meta: meta}, nil
 		},
+		"apply": func() (cli.Command, error) {
+			return &commands.ApplyCommand{Meta: meta}, nil
+		},
+		"config-get": func() (cli.Command, error) {
+			return &commands.ConfigGetCommand{Meta: meta}, nil
+		},
+		"config-set": func() (cli.Command, error) {
+			return &commands.ConfigSetCommand{Meta: meta}, nil
+		},
+		"config-show": func() (cli.Command, error) {
+			return &commands.ConfigShowCommand{Meta: meta}, nil
+		},
+		"config-unset": func() (cli.Command, error) {
+			return &commands.ConfigUnsetCommand{Meta: meta}, nil
+		},
 		"create": func() (cli.Command, error) {
 			return &commands.CreateCommand{Meta: meta}, nil
 		},
@@ -53,24 +89,66 @@ func Commands(ctx context.Context, meta command.Meta) map[string]cli.CommandFact
 		"enter": func() (cli.Command, error) {
 			return &commands.EnterCommand{Meta: meta}, nil
 		},
+		"events": func() (cli.Command, error) {
+			return &commands.EventsCommand{Meta: meta}, nil
+		},
 		"exists": func() (cli.Command, error) {
 			return &commands.ExistsCommand{Meta: meta}, nil
 		},
 		"expose": func() (cli.Command, error) {
 			return &commands.ExposeCommand{Meta: meta}, nil
 		},
+		"expose-mode": func() (cli.Command, error) {
+			return &commands.ExposeModeCommand{Meta: meta}, nil
+		},
+		"hardening": func() (cli.Command, error) {
+			return &commands.HardeningCommand{Meta: meta}, nil
+		},
+		"firewall-restore": func() (cli.Command, error) {
+			return &commands.FirewallRestoreCommand{Meta: meta}, nil
+		},
+		"ha-watch": func() (cli.Command, error) {
+			return &commands.HAWatchCommand{Meta: meta}, nil
+		},
+		"health": func() (cli.Command, error) {
+			return &commands.HealthCommand{Meta: meta}, nil
+		},
+		"history": func() (cli.Command, error) {
+			return &commands.HistoryCommand{Meta: meta}, nil
+		},
 		"info": func() (cli.Command, error) {
 			return &commands.InfoCommand{Meta: meta}, nil
 		},
 		"list": func() (cli.Command, error) {
 			return &commands.ListCommand{Meta: meta}, nil
 		},
+		"link": func() (cli.Command, error) {
+			return &commands.LinkCommand{Meta: meta}, nil
+		},
 		"linked": func() (cli.Command, error) {
 			return &commands.LinkedCommand{Meta: meta}, nil
 		},
 		"links": func() (cli.Command, error) {
 			return &commands.LinksCommand{Meta: meta}, nil
 		},
+		"ports": func() (cli.Command, error) {
+			return &commands.PortsCommand{Meta: meta}, nil
+		},
+		"promote-replica": func() (cli.Command, error) {
+			return &commands.PromoteReplicaCommand{Meta: meta}, nil
+		},
+		"proxy": func() (cli.Command, error) {
+			return &commands.ProxyCommand{Meta: meta}, nil
+		},
+		"replicate": func() (cli.Command, error) {
+			return &commands.ReplicateCommand{Meta: meta}, nil
+		},
+		"replication": func() (cli.Command, error) {
+			return &commands.ReplicationCommand{Meta: meta}, nil
+		},
+		"resources": func() (cli.Command, error) {
+			return &commands.ResourcesCommand{Meta: meta}, nil
+		},
 		"restart": func() (cli.Command, error) {
 			return &commands.RestartCommand{Meta: meta}, nil
 		},
@@ -80,15 +158,36 @@ func Commands(ctx context.Context, meta command.Meta) map[string]cli.CommandFact
 		"pause": func() (cli.Command, error) {
 			return &commands.PauseCommand{Meta: meta}, nil
 		},
+		"rotate-password": func() (cli.Command, error) {
+			return &commands.RotatePasswordCommand{Meta: meta}, nil
+		},
 		"start": func() (cli.Command, error) {
 			return &commands.StartCommand{Meta: meta}, nil
 		},
+		"stats": func() (cli.Command, error) {
+			return &commands.StatsCommand{Meta: meta}, nil
+		},
 		"stop": func() (cli.Command, error) {
 			return &commands.StopCommand{Meta: meta}, nil
 		},
+		"tls-ca": func() (cli.Command, error) {
+			return &commands.TLSCACommand{Meta: meta}, nil
+		},
+		"tls-disable": func() (cli.Command, error) {
+			return &commands.TLSDisableCommand{Meta: meta}, nil
+		},
+		"tls-enable": func() (cli.Command, error) {
+			return &commands.TLSEnableCommand{Meta: meta}, nil
+		},
+		"tls-rotate": func() (cli.Command, error) {
+			return &commands.TLSRotateCommand{Meta: meta}, nil
+		},
 		"unexpose": func() (cli.Command, error) {
 			return &commands.UnexposeCommand{Meta: meta}, nil
 		},
+		"unlink": func() (cli.Command, error) {
+			return &commands.UnlinkCommand{Meta: meta}, nil
+		},
 		"version": func() (cli.Command, error) {
 			return &command.VersionCommand{Meta: meta}, nil
 		},